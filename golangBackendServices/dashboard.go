@@ -0,0 +1,30 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// dashboardHTML is a dependency-free operator UI that drives the existing
+// JSON endpoints from the browser.
+//
+//go:embed dashboard/index.html
+var dashboardHTML []byte
+
+// dashboardHandler serves the embedded dashboard at exactly "/". Every other
+// unmatched path falls through to a 404.
+func dashboardHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(dashboardHTML)
+	})
+}
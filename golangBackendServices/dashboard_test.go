@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDashboardHandler(t *testing.T) {
+	// The page is public so it can prompt for the key, while the endpoints
+	// it calls still need one.
+	mux := http.NewServeMux()
+	mux.Handle("/", dashboardHandler())
+	mux.Handle("/scan-open-ports", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler := apiKeyMiddleware([]string{"secret"}, mux)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantHTML   bool
+	}{
+		{"dashboard", http.MethodGet, "/", http.StatusOK, true},
+		{"unknown path", http.MethodGet, "/nope", http.StatusUnauthorized, false},
+		{"wrong method", http.MethodPost, "/", http.StatusMethodNotAllowed, false},
+		{"api still needs key", http.MethodPost, "/scan-open-ports", http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			isHTML := strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html")
+			if isHTML != tt.wantHTML {
+				t.Fatalf("Content-Type = %q, want HTML: %v", rec.Header().Get("Content-Type"), tt.wantHTML)
+			}
+			if tt.wantHTML && rec.Body.String() != string(dashboardHTML) {
+				t.Error("body is not the embedded dashboard page")
+			}
+		})
+	}
+}
+
+func TestDashboardNotFoundWithoutAuth(t *testing.T) {
+	rec := httptest.NewRecorder()
+	dashboardHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDashboardHTMLEmbedded(t *testing.T) {
+	page := string(dashboardHTML)
+	if !strings.Contains(page, "<html") {
+		t.Fatal("embedded dashboard is not an HTML page")
+	}
+	if !strings.Contains(page, "X-API-Key") {
+		t.Error("dashboard does not send the API key")
+	}
+}
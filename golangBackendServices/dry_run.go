@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// dryRunOutputDir stands in for the per-scan output directory in dry runs,
+// since no directory is created.
+const dryRunOutputDir = "$OUTPUT_DIR"
+
+// nmapDryRunResponse shows the nmap command a scan request would run.
+type nmapDryRunResponse struct {
+	DryRun   bool     `json:"dry_run"`
+	Argv     []string `json:"argv"`
+	Command  string   `json:"command"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// shellSafePattern matches arguments that need no quoting in a POSIX shell.
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./\-]+$`)
+
+// shellQuote renders argv as a copy-pasteable POSIX shell command line.
+func shellQuote(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, a := range argv {
+		if shellSafePattern.MatchString(a) {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// writeNmapDryRun responds with the full nmap argv for req instead of
+// running it. Output files are shown under dryRunOutputDir.
+func writeNmapDryRun(w http.ResponseWriter, req scanRequest, cmdArgs []string, warnings []string) {
+	argv := append([]string{nmapPath}, outputFileArgs(dryRunOutputDir, req.OutputFormats)...)
+	argv = append(argv, outputFileArgs(dryRunOutputDir, workDirOutputFormats(req))...)
+	argv = append(argv, cmdArgs...)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(nmapDryRunResponse{
+		DryRun:   true,
+		Argv:     argv,
+		Command:  shellQuote(argv),
+		Warnings: warnings,
+	}); err != nil {
+		log.Printf("failed to encode dry run response: %v", err)
+	}
+}
+
+// ErrDryRun is returned by runGMP for read commands (get_*) instead of
+// contacting gvmd when the context carries a GMP dry run.
+var ErrDryRun = errors.New("dry run: GMP command not sent")
+
+// dryRunID stands in for the ID of a resource a dry run would have created,
+// so that later commands referencing it are still built and recorded.
+const dryRunID = "00000000-0000-0000-0000-000000000000"
+
+// dryRunGMPResponse is the reply runGMP gives during a dry run. Read commands
+// fail with ErrDryRun, since there is nothing real to return; every other
+// command succeeds with the status gvmd would send, and with dryRunID as the
+// new resource or report ID.
+func dryRunGMPResponse(xmlBody string) ([]byte, error) {
+	command := ""
+	dec := xml.NewDecoder(strings.NewReader(xmlBody))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			command = start.Name.Local
+			break
+		}
+	}
+
+	switch {
+	case command == "" || strings.HasPrefix(command, "get_"):
+		return nil, ErrDryRun
+	case strings.HasPrefix(command, "create_"):
+		return []byte(fmt.Sprintf(`<%s_response status="201" status_text="OK, resource created" id="%s"/>`, command, dryRunID)), nil
+	case command == "start_task" || command == "resume_task":
+		return []byte(fmt.Sprintf(`<%s_response status="202" status_text="OK, request submitted"><report_id>%s</report_id></%s_response>`, command, dryRunID, command)), nil
+	case command == "stop_task":
+		return []byte(`<stop_task_response status="202" status_text="OK, request submitted"/>`), nil
+	}
+	return []byte(fmt.Sprintf(`<%s_response status="200" status_text="OK"/>`, command)), nil
+}
+
+type gmpDryRunKey struct{}
+
+// gmpDryRun collects the GMP commands a request would have sent.
+type gmpDryRun struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (d *gmpDryRun) record(xmlBody string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.commands = append(d.commands, xmlBody)
+}
+
+func (d *gmpDryRun) list() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.commands...)
+}
+
+// withGMPDryRun returns a context under which runGMP records commands
+// instead of running gvm-cli.
+func withGMPDryRun(ctx context.Context) (context.Context, *gmpDryRun) {
+	d := &gmpDryRun{}
+	return context.WithValue(ctx, gmpDryRunKey{}, d), d
+}
+
+func gmpDryRunFromContext(ctx context.Context) *gmpDryRun {
+	d, _ := ctx.Value(gmpDryRunKey{}).(*gmpDryRun)
+	return d
+}
+
+// gmpDryRunResponse lists the GMP XML an OpenVAS request would have sent.
+// Commands that depend on an earlier lookup (e.g. create_task after a
+// get_tasks lookup) are shown as if the lookup found nothing, and commands
+// that use a created resource reference dryRunID. If the request can't go on
+// without a real answer from gvmd (waiting for a task, fetching a report),
+// the list ends at that read and Incomplete is set.
+type gmpDryRunResponse struct {
+	DryRun      bool     `json:"dry_run"`
+	GMPCommands []string `json:"gmp_commands"`
+	Incomplete  bool     `json:"incomplete,omitempty"`
+}
+
+// dryRunResponseWriter swallows the wrapped handler's response during a dry
+// run, keeping only the status and, for errors, the body so that a request
+// the handler rejects is reported as such rather than as an empty dry run.
+// Failures caused by ErrDryRun itself are not real errors and still get the
+// dry run response.
+type dryRunResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	// stopped is set by stopDryRun when the handler gave up on ErrDryRun.
+	stopped bool
+}
+
+func (d *dryRunResponseWriter) Header() http.Header { return d.header }
+
+func (d *dryRunResponseWriter) Write(b []byte) (int, error) {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+	if d.status >= 400 {
+		return d.body.Write(b)
+	}
+	return len(b), nil
+}
+
+func (d *dryRunResponseWriter) WriteHeader(status int) {
+	if d.status == 0 {
+		d.status = status
+	}
+}
+
+// stopDryRun reports whether err is a handler giving up on ErrDryRun during a
+// dry run, in which case the failure must not be written as an error.
+func stopDryRun(w http.ResponseWriter, err error) bool {
+	rec, ok := w.(*dryRunResponseWriter)
+	if !ok || !errors.Is(err, ErrDryRun) {
+		return false
+	}
+	rec.stopped = true
+	return true
+}
+
+// openVASDryRun lets any OpenVAS endpoint be called with ?dry_run=true. The
+// handler runs as usual, but no GMP command reaches gvmd; the XML it would
+// have sent is returned instead. If the handler rejects the request (bad ID,
+// target out of scope, ...), its error response is passed on unchanged.
+func openVASDryRun(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if dry, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); !dry {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, d := withGMPDryRun(r.Context())
+		rec := &dryRunResponseWriter{header: make(http.Header)}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		if rec.status >= 400 && !rec.stopped {
+			for k, v := range rec.header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(rec.status)
+			if _, err := w.Write(rec.body.Bytes()); err != nil {
+				log.Printf("failed to write GMP dry run error response: %v", err)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false) // keep the XML readable
+		if err := enc.Encode(gmpDryRunResponse{
+			DryRun:      true,
+			GMPCommands: d.list(),
+			Incomplete:  rec.stopped,
+		}); err != nil {
+			log.Printf("failed to encode GMP dry run response: %v", err)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envString reads a string environment variable, falling back to def when
+// the variable is unset or blank.
+func envString(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+// envBool reads a boolean environment variable, falling back to def when the
+// variable is unset or cannot be parsed.
+func envBool(key string, def bool) bool {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Printf("invalid boolean for %s=%q, using default %v", key, raw, def)
+		return def
+	}
+	return v
+}
+
+// envDuration reads a time.Duration environment variable (e.g. "30m"),
+// falling back to def when the variable is unset or cannot be parsed.
+func envDuration(key string, def time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return def
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil || v <= 0 {
+		log.Printf("invalid duration for %s=%q, using default %v", key, raw, def)
+		return def
+	}
+	return v
+}
+
+// envInt reads an integer environment variable, falling back to def when the
+// variable is unset, unparsable, or not positive.
+func envInt(key string, def int) int {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		log.Printf("invalid integer for %s=%q, using default %d", key, raw, def)
+		return def
+	}
+	return v
+}
+
+// envList reads a comma-separated environment variable, dropping blank
+// entries.
+func envList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
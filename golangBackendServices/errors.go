@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// verboseErrors controls whether internal error details (nmap stderr, gvmd
+// output, ...) are included in client-facing error responses. It is read
+// from VERBOSE_ERRORS at startup and defaults to false so that production
+// deployments never leak internals to callers.
+var verboseErrors = false
+
+// errorResponse is the JSON body of every error response. Code is a stable
+// snake_case name for the status (e.g. "bad_request", "not_found") so
+// clients can branch on it without parsing Error. Details carries the
+// underlying error when verbose errors are enabled, and Problems lists each
+// issue found in an invalid scan request.
+type errorResponse struct {
+	Error    string   `json:"error"`
+	Code     string   `json:"code"`
+	Details  string   `json:"details,omitempty"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// errorCode returns the Code used for status in an errorResponse.
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	text = strings.ToLower(strings.ReplaceAll(text, "'", ""))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(text)
+}
+
+// writeJSONError writes msg as an errorResponse with the given status.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeErrorResponse(w, status, errorResponse{Error: msg})
+}
+
+// writeErrorResponse writes resp with the given status, filling in Code.
+func writeErrorResponse(w http.ResponseWriter, status int, resp errorResponse) {
+	if resp.Code == "" {
+		resp.Code = errorCode(status)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("failed to encode error response: %v", err)
+	}
+}
+
+// errorStatus says how errors matching it are reported to clients, whichever
+// handler they reach.
+type errorStatus struct {
+	match  func(error) bool
+	status int
+	// message, if set, replaces the handler's message.
+	message string
+	// showError replaces the handler's message with the error text, for
+	// errors that are safe to show and name what the caller must fix.
+	showError bool
+}
+
+// errorIs matches errors wrapping target.
+func errorIs(target error) func(error) bool {
+	return func(err error) bool { return errors.Is(err, target) }
+}
+
+// isMaxBytesError matches a request body cut off by bodyLimitMiddleware.
+func isMaxBytesError(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// errorStatuses is the one place errors are mapped to HTTP statuses. It is
+// used by writeError, statusForError and openVASErrorStatus alike. The first
+// match wins.
+var errorStatuses = []errorStatus{
+	{match: isMaxBytesError, status: http.StatusRequestEntityTooLarge, message: "request body too large"},
+	{match: errorIs(ErrContainerUnavailable), status: http.StatusServiceUnavailable, message: "OpenVAS container not available; check that it is running"},
+	{match: isTargetScopeError, status: http.StatusForbidden, showError: true},
+	{match: errorIs(ErrScriptNotAllowed), status: http.StatusForbidden, showError: true},
+	{match: errorIs(ErrTaskRefNotFound), status: http.StatusBadRequest, showError: true},
+	{match: errorIs(ErrInvalidID), status: http.StatusBadRequest},
+	{match: errorIs(ErrInvalidSchedule), status: http.StatusBadRequest},
+	{match: errorIs(ErrUnknownConfig), status: http.StatusBadRequest},
+	{match: errorIs(ErrUnknownRole), status: http.StatusBadRequest},
+	{match: errorIs(ErrTargetNotFound), status: http.StatusNotFound},
+	{match: errorIs(ErrConfigNotFound), status: http.StatusNotFound},
+	{match: errorIs(ErrTargetPortsDiffer), status: http.StatusConflict},
+}
+
+// lookupErrorStatus returns the errorStatuses entry matching err, if any.
+func lookupErrorStatus(err error) (errorStatus, bool) {
+	if err == nil {
+		return errorStatus{}, false
+	}
+	for _, e := range errorStatuses {
+		if e.match(err) {
+			return e, true
+		}
+	}
+	return errorStatus{}, false
+}
+
+// statusForError returns the status errorStatuses gives err, or fallback if
+// it isn't listed.
+func statusForError(err error, fallback int) int {
+	if e, ok := lookupErrorStatus(err); ok {
+		return e.status
+	}
+	return fallback
+}
+
+// writeError logs the full error server-side and writes an error response to
+// the client. The client only sees msg unless verbose errors are enabled, in
+// which case the underlying error text is included as details for
+// debugging. Errors listed in errorStatuses get the status (and, for some,
+// the message) given there; status is used for everything else.
+func writeError(w http.ResponseWriter, status int, msg string, err error) {
+	if stopDryRun(w, err) {
+		return
+	}
+
+	if e, ok := lookupErrorStatus(err); ok {
+		status = e.status
+		switch {
+		case e.message != "":
+			msg = e.message
+		case e.showError:
+			msg = err.Error()
+		}
+	}
+
+	if err != nil {
+		log.Printf("%s: %v", msg, err)
+	}
+
+	resp := errorResponse{Error: msg}
+	if verboseErrors && err != nil {
+		resp.Details = err.Error()
+	}
+	writeErrorResponse(w, status, resp)
+}
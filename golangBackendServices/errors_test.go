@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setVerboseErrors sets verboseErrors for the rest of the test and silences
+// the server-side error log.
+func setVerboseErrors(t *testing.T, verbose bool) {
+	t.Helper()
+	old, out := verboseErrors, log.Writer()
+	verboseErrors = verbose
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() {
+		verboseErrors = old
+		log.SetOutput(out)
+	})
+}
+
+func TestWriteErrorVerbose(t *testing.T) {
+	internal := errors.New("exit status 1: nmap: /usr/share/nmap/nmap-services: permission denied")
+	unavailable := fmt.Errorf("%w: Error response from daemon: No such container: openvas", ErrContainerUnavailable)
+
+	tests := []struct {
+		name        string
+		verbose     bool
+		status      int
+		msg         string
+		err         error
+		wantStatus  int
+		wantError   string
+		wantDetails string
+	}{
+		{"quiet hides details", false, http.StatusInternalServerError, "nmap scan failed", internal, http.StatusInternalServerError, "nmap scan failed", ""},
+		{"verbose shows details", true, http.StatusInternalServerError, "nmap scan failed", internal, http.StatusInternalServerError, "nmap scan failed", internal.Error()},
+		{"verbose without error", true, http.StatusBadRequest, "invalid JSON body", nil, http.StatusBadRequest, "invalid JSON body", ""},
+		{"quiet mapped error", false, http.StatusInternalServerError, "failed to list targets", unavailable, http.StatusServiceUnavailable, "OpenVAS container not available; check that it is running", ""},
+		{"verbose mapped error", true, http.StatusInternalServerError, "failed to list targets", unavailable, http.StatusServiceUnavailable, "OpenVAS container not available; check that it is running", unavailable.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setVerboseErrors(t, tt.verbose)
+
+			rec := httptest.NewRecorder()
+			writeError(rec, tt.status, tt.msg, tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var raw map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+				t.Fatalf("body %q is not JSON: %v", rec.Body.String(), err)
+			}
+			if raw["error"] != tt.wantError {
+				t.Errorf("error = %v, want %q", raw["error"], tt.wantError)
+			}
+			if raw["code"] != errorCode(tt.wantStatus) {
+				t.Errorf("code = %v, want %q", raw["code"], errorCode(tt.wantStatus))
+			}
+			details, present := raw["details"]
+			if tt.wantDetails == "" && present {
+				t.Errorf("details = %v, want it left out", details)
+			}
+			if tt.wantDetails != "" && details != tt.wantDetails {
+				t.Errorf("details = %v, want %q", details, tt.wantDetails)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+// readinessCheck is the outcome of probing a single dependency.
+type readinessCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string                    `json:"status"`
+	Checks map[string]readinessCheck `json:"checks"`
+}
+
+// healthzHandler reports that the process is up and serving requests.
+func healthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(healthResponse{Status: "ok"}); err != nil {
+			log.Printf("failed to encode health response: %v", err)
+		}
+	})
+}
+
+// readyzHandler verifies that the service's dependencies are usable: nmap
+// must be on PATH and, when checkOpenVAS is set, gvmd must answer
+// <get_version/> within timeout. Any failure yields 503 with per-dependency
+// details.
+func readyzHandler(svc *OpenVASService, checkOpenVAS bool, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		resp := readinessResponse{
+			Status: "ok",
+			Checks: make(map[string]readinessCheck),
+		}
+
+		if _, err := exec.LookPath(nmapPath); err != nil {
+			log.Printf("readiness: nmap not available: %v", err)
+			resp.Checks["nmap"] = readinessCheck{Status: "fail", Error: nmapPath + " not found"}
+			resp.Status = "fail"
+		} else {
+			resp.Checks["nmap"] = readinessCheck{Status: "ok"}
+		}
+
+		if checkOpenVAS {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			if _, err := svc.GetVersion(ctx); err != nil {
+				log.Printf("readiness: OpenVAS not available: %v", err)
+				check := readinessCheck{Status: "fail", Error: "OpenVAS did not respond to get_version"}
+				if verboseErrors {
+					check.Error += ": " + err.Error()
+				}
+				resp.Checks["openvas"] = check
+				resp.Status = "fail"
+			} else {
+				resp.Checks["openvas"] = readinessCheck{Status: "ok"}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("failed to encode readiness response: %v", err)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// defaultDiscoveryTiming is used by /discover-hosts when no timing is given.
+// A ping sweep sends few probes, so it can run faster than full scans.
+const defaultDiscoveryTiming = "T4"
+
+// discoverHostsRequest is the JSON input for /discover-hosts.
+type discoverHostsRequest struct {
+	Target  string   `json:"target"`
+	Targets []string `json:"targets,omitempty"`
+	Timing  string   `json:"timing,omitempty"`
+	Exclude string   `json:"exclude,omitempty"`
+	IPv6    bool     `json:"ipv6,omitempty"`
+}
+
+// discoveredHost is a host that answered the ping sweep.
+type discoveredHost struct {
+	Address  string `json:"address"`
+	Hostname string `json:"hostname,omitempty"`
+	MAC      string `json:"mac,omitempty"`
+	Vendor   string `json:"vendor,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type discoverHostsResponse struct {
+	Target    string           `json:"target"`
+	Hosts     []discoveredHost `json:"hosts"`
+	ExitCode  int              `json:"exit_code"`
+	Error     string           `json:"error,omitempty"`
+	HistoryID string           `json:"history_id,omitempty"`
+	Warnings  []string         `json:"warnings,omitempty"`
+}
+
+// liveHosts returns the hosts nmap reported as up.
+func liveHosts(run nmapRun) []discoveredHost {
+	hosts := []discoveredHost{}
+	for _, h := range run.Hosts {
+		if h.Status.State != "up" {
+			continue
+		}
+		mac, vendor := h.macAddress()
+		hosts = append(hosts, discoveredHost{
+			Address:  h.ipAddress(),
+			Hostname: h.hostname(),
+			MAC:      mac,
+			Vendor:   vendor,
+			Reason:   h.Status.Reason,
+		})
+	}
+	return hosts
+}
+
+// scanRequest turns a discovery request into the ping scan that runs it.
+// Target is empty if no target was given.
+func (in discoverHostsRequest) scanRequest() scanRequest {
+	req := scanRequest{
+		Target:       in.Target,
+		Targets:      in.Targets,
+		Timing:       strings.TrimSpace(in.Timing),
+		ScanType:     "ping",
+		Exclude:      strings.TrimSpace(in.Exclude),
+		IPv6:         in.IPv6,
+		OutputFormat: "xml",
+	}
+	req.Target, req.Targets = strings.Join(req.targetList(), " "), nil
+	if req.Timing == "" {
+		req.Timing = defaultDiscoveryTiming
+	}
+	return req
+}
+
+// discover runs a ping scan built from discoverHostsRequest.scanRequest and
+// returns the live hosts parsed from its XML output alongside the scan
+// result. The error is that of nmapScanner.run.
+func (s *nmapScanner) discover(ctx context.Context, req scanRequest, cmdArgs []string) (scanResponse, []discoveredHost, error) {
+	scan, err := s.run(ctx, req, cmdArgs)
+	hosts := []discoveredHost{}
+	if out, ok := scan.OutputContents["xml"]; ok {
+		run, perr := parseNmapXML(out)
+		if perr != nil {
+			log.Printf("%v", perr)
+			addWarning(ctx, "nmap XML output could not be parsed")
+		} else {
+			hosts = liveHosts(run)
+		}
+	}
+	return scan, hosts, err
+}
+
+// discoverHostsHandler runs a ping sweep (nmap -sn) and returns only the
+// hosts that are up, parsed from nmap's XML output.
+func discoverHostsHandler(scanner *nmapScanner) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var in discoverHostsRequest
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req := in.scanRequest()
+		if req.Target == "" {
+			writeJSONError(w, http.StatusBadRequest, "target is required")
+			return
+		}
+
+		cmdArgs, err := buildNmapArgs(ctx, req)
+		var reqErr *scanRequestError
+		if errors.As(err, &reqErr) {
+			writeScanRequestError(w, reqErr)
+			return
+		}
+		if err != nil {
+			writeJSONError(w, statusForError(err, http.StatusBadRequest), err.Error())
+			return
+		}
+
+		if !scanner.limiter.tryAcquire() {
+			writeTooManyScans(w)
+			return
+		}
+		defer scanner.limiter.release()
+
+		scan, hosts, err := scanner.discover(ctx, req, cmdArgs)
+		status := http.StatusOK
+		if err != nil {
+			log.Printf("nmap error for target %s: %v", req.Target, err)
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				status = http.StatusInternalServerError
+			}
+		}
+
+		resp := discoverHostsResponse{
+			Target:    req.Target,
+			Hosts:     hosts,
+			ExitCode:  scan.ExitCode,
+			Error:     scan.Error,
+			HistoryID: scan.HistoryID,
+			Warnings:  warns.list(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("failed to encode discover hosts response: %v", err)
+		}
+	})
+}
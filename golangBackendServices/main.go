@@ -1,86 +1,232 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type scanRequest struct {
-	Target           string `json:"target"`
-	Timing           string `json:"timing,omitempty"`
-	ScanType         string `json:"scan_type,omitempty"`
-	Ports            string `json:"ports,omitempty"`
-	ServiceDetection bool   `json:"service_detection,omitempty"`
-	OSDetection      bool   `json:"os_detection,omitempty"`
-	Scripts          string `json:"scripts,omitempty"`
-	OutputFormat     string `json:"output_format,omitempty"`
-	Aggressive       bool   `json:"aggressive,omitempty"` // -A flag
-	Traceroute       bool   `json:"traceroute,omitempty"` // --traceroute
+	// Target is one or more hosts separated by spaces, commas or newlines.
+	// Targets lists further hosts; both are scanned in a single nmap run.
+	Target           string   `json:"target"`
+	Targets          []string `json:"targets,omitempty"`
+	Timing           string   `json:"timing,omitempty"`
+	ScanType         string   `json:"scan_type,omitempty"`
+	Ports            string   `json:"ports,omitempty"`
+	ServiceDetection bool     `json:"service_detection,omitempty"`
+	OSDetection      bool     `json:"os_detection,omitempty"`
+	Scripts          string   `json:"scripts,omitempty"`
+	OutputFormat     string   `json:"output_format,omitempty"`
+	Aggressive       bool     `json:"aggressive,omitempty"` // -A flag
+	Traceroute       bool     `json:"traceroute,omitempty"` // --traceroute
 	// Direct Nmap flags
-	FlagO          bool                   `json:"flag_o,omitempty"`          // -O (OS detection)
-	FlagSC         bool                   `json:"flag_sc,omitempty"`         // -sC (default scripts)
-	FlagSV         bool                   `json:"flag_sv,omitempty"`         // -sV (service detection)
-	FlagTraceroute bool                   `json:"flag_traceroute,omitempty"` // --traceroute
-	FlagA          bool                   `json:"flag_a,omitempty"`          // -A (aggressive)
-	StealthOptions map[string]interface{} `json:"stealth_options,omitempty"`
+	FlagO          bool `json:"flag_o,omitempty"`          // -O (OS detection)
+	FlagSC         bool `json:"flag_sc,omitempty"`         // -sC (default scripts)
+	FlagSV         bool `json:"flag_sv,omitempty"`         // -sV (service detection)
+	FlagTraceroute bool `json:"flag_traceroute,omitempty"` // --traceroute
+	FlagA          bool `json:"flag_a,omitempty"`          // -A (aggressive)
+	// StealthOptions enables fragmentation, decoys, padding and MAC
+	// spoofing. They all need root.
+	StealthOptions *stealthOptions `json:"stealth_options,omitempty"`
+	// OutputFormats writes the scan to disk in each listed format (xml,
+	// normal, greppable, all) and returns download links for the files.
+	OutputFormats []string `json:"output_formats,omitempty"`
+	// TopPorts scans the N most common ports (--top-ports N). It can't be
+	// combined with Ports.
+	TopPorts int `json:"top_ports,omitempty"`
+	// ScriptArgs is passed to NSE scripts as --script-args key=value,...
+	ScriptArgs map[string]string `json:"script_args,omitempty"`
+	// Exclude and ExcludeFile skip hosts (e.g. a gateway or a fragile
+	// device) via --exclude and --excludefile. Exclusions apply to the whole
+	// scan, not to an individual target.
+	Exclude     string `json:"exclude,omitempty"`
+	ExcludeFile string `json:"exclude_file,omitempty"`
+	// DryRun returns the nmap command line instead of running it.
+	DryRun bool `json:"dry_run,omitempty"`
+	// ResolveAll scans every address a hostname resolves to (--resolve-all)
+	// instead of only the first one.
+	ResolveAll bool `json:"resolve_all,omitempty"`
+	// IPv6 scans over IPv6 (-6). It is enabled automatically for IPv6
+	// address and CIDR targets; set it for hostnames with AAAA records.
+	IPv6 bool `json:"ipv6,omitempty"`
+	// MaxRetries caps probe retransmissions (--max-retries, 0-10); nil
+	// keeps nmap's default. HostTimeout gives up on hosts that take longer
+	// (--host-timeout), e.g. "30s" or "15m".
+	MaxRetries  *int   `json:"max_retries,omitempty"`
+	HostTimeout string `json:"host_timeout,omitempty"`
+	// MinRate and MaxRate bound the send rate in packets per second
+	// (--min-rate, --max-rate).
+	MinRate int `json:"min_rate,omitempty"`
+	MaxRate int `json:"max_rate,omitempty"`
+	// Interface sends probes from a specific network interface (-e) and
+	// SourceIP spoofs or pins their source address (-S), for multi-homed
+	// hosts where nmap would otherwise pick the wrong one.
+	Interface string `json:"interface,omitempty"`
+	SourceIP  string `json:"source_ip,omitempty"`
+	// Profile applies a named preset (quick, thorough, stealth, vuln) from
+	// scanProfiles; the other fields override it.
+	Profile string `json:"profile,omitempty"`
+	// OpenOnly shows only open ports (--open), which keeps hosts in the
+	// parsed result to what agents usually need. Unset, it is on unless the
+	// request asks for raw output files or a ping scan; see openOnly.
+	OpenOnly *bool `json:"open_only,omitempty"`
+	// VersionIntensity (0-9, --version-intensity) and VersionMode ("light"
+	// for --version-light, "all" for --version-all) trade service detection
+	// speed against accuracy. They only apply with service detection or
+	// aggressive.
+	VersionIntensity *int   `json:"version_intensity,omitempty"`
+	VersionMode      string `json:"version_mode,omitempty"`
 }
 
 type scanResponse struct {
-	Target    string `json:"target"`
-	RawOutput string `json:"raw_output"`
+	Target           string            `json:"target"`
+	ScannedAddresses []string          `json:"scanned_addresses,omitempty"`
+	RawOutput        string            `json:"raw_output"`
+	Stderr           string            `json:"stderr,omitempty"`
+	ExitCode         int               `json:"exit_code"`
+	Error            string            `json:"error,omitempty"`
+	OutputFiles      map[string]string `json:"output_files,omitempty"`
+	// OutputContents holds the files written for output_format, keyed by
+	// format.
+	OutputContents map[string]string `json:"output_contents,omitempty"`
+	// Hosts is the per-host breakdown of the scan.
+	Hosts     []HostResult `json:"hosts,omitempty"`
+	HistoryID string       `json:"history_id,omitempty"`
+	Warnings  []string     `json:"warnings,omitempty"`
 }
 
-func scanOpenPortsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
+// validTimings are nmap's timing templates, -T0 (paranoid) to -T5 (insane).
+var validTimings = map[string]bool{"T0": true, "T1": true, "T2": true, "T3": true, "T4": true, "T5": true}
+
+// defaultNmapTiming is the timing template used when a request doesn't set
+// one. It is read from DEFAULT_NMAP_TIMING at startup.
+var defaultNmapTiming = "T2"
+
+// validScanTypes maps scan_type values to their nmap flags.
+var validScanTypes = map[string]string{
+	"ping":        "-sn",
+	"tcp_syn":     "-sS",
+	"tcp_connect": "-sT",
+	"udp":         "-sU",
+	"tcp_ack":     "-sA",
+	"tcp_fin":     "-sF",
+	"tcp_null":    "-sN",
+	"tcp_xmas":    "-sX",
+}
+
+// defaultScanType is the scan_type used when a request doesn't set one. It
+// is read from DEFAULT_SCAN_TYPE at startup; empty leaves the choice to nmap
+// (SYN when privileged, connect otherwise).
+var defaultScanType = ""
+
+// nmapDurationPattern matches nmap's time specifications: a number with an
+// optional ms, s, m or h suffix (seconds when there is none).
+var nmapDurationPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(ms|s|m|h)?$`)
+
+// maxScanTargets caps how many targets a single request may list.
+const maxScanTargets = 256
+
+// targetList returns every target of the request, from Target and Targets,
+// in order and without duplicates.
+func (req scanRequest) targetList() []string {
+	seen := make(map[string]bool)
+	var targets []string
+	for _, raw := range append([]string{req.Target}, req.Targets...) {
+		for _, t := range splitTargetList(raw) {
+			if !seen[t] {
+				seen[t] = true
+				targets = append(targets, t)
+			}
+		}
 	}
+	return targets
+}
 
-	var req scanRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON body", http.StatusBadRequest)
-		return
+// openOnly reports whether --open should be passed. Without an explicit
+// open_only it defaults to true, except for raw output (output_format,
+// output_formats), which keeps nmap's full information, and for ping scans,
+// which have no ports.
+func (req scanRequest) openOnly() bool {
+	if req.OpenOnly != nil {
+		return *req.OpenOnly
 	}
-	req.Target = strings.TrimSpace(req.Target)
-	if req.Target == "" {
-		http.Error(w, "target is required", http.StatusBadRequest)
-		return
+	return req.OutputFormat == "" && len(req.OutputFormats) == 0 && req.ScanType != "ping"
+}
+
+// buildNmapArgs validates a scan request and turns it into the nmap argument
+// list. The target is always the last argument.
+func buildNmapArgs(ctx context.Context, req scanRequest) ([]string, error) {
+	req, err := validateScanRequest(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Build nmap command with all options
 	var cmdArgs []string
 
 	// Add timing template
 	timingTemplate := req.Timing
 	if timingTemplate == "" {
-		timingTemplate = "T2"
-	}
-	validTimings := map[string]bool{"T0": true, "T1": true, "T2": true, "T3": true, "T4": true, "T5": true}
-	if !validTimings[timingTemplate] {
-		http.Error(w, "invalid timing template. Must be one of: T0, T1, T2, T3, T4, T5", http.StatusBadRequest)
-		return
+		timingTemplate = defaultNmapTiming
 	}
 	cmdArgs = append(cmdArgs, "-"+timingTemplate)
 
+	// Add retry and timeout controls
+	if req.MaxRetries != nil {
+		cmdArgs = append(cmdArgs, "--max-retries", strconv.Itoa(*req.MaxRetries))
+	}
+	if hostTimeout := strings.TrimSpace(req.HostTimeout); hostTimeout != "" {
+		cmdArgs = append(cmdArgs, "--host-timeout", hostTimeout)
+	}
+
+	// Add packet rate limits
+	if req.MinRate > 0 {
+		cmdArgs = append(cmdArgs, "--min-rate", strconv.Itoa(req.MinRate))
+	}
+	if req.MaxRate > 0 {
+		cmdArgs = append(cmdArgs, "--max-rate", strconv.Itoa(req.MaxRate))
+	}
+
+	// Add interface and source address
+	if iface := strings.TrimSpace(req.Interface); iface != "" {
+		cmdArgs = append(cmdArgs, "-e", iface)
+	}
+	if sourceIP := strings.TrimSpace(req.SourceIP); sourceIP != "" {
+		cmdArgs = append(cmdArgs, "-S", sourceIP)
+	}
+
+	// Add stealth options
+	if req.StealthOptions != nil {
+		cmdArgs = append(cmdArgs, req.StealthOptions.args()...)
+	}
+
 	// Add scan type
 	if req.ScanType != "" {
-		validScanTypes := map[string]string{
-			"ping":        "-sn",
-			"tcp_syn":     "-sS",
-			"tcp_connect": "-sT",
-			"udp":         "-sU",
-			"tcp_ack":     "-sA",
-			"tcp_fin":     "-sF",
-			"tcp_null":    "-sN",
-			"tcp_xmas":    "-sX",
-		}
 		if scanType, exists := validScanTypes[req.ScanType]; exists {
 			cmdArgs = append(cmdArgs, scanType)
+		} else {
+			addWarning(ctx, "unknown scan_type %q ignored; nmap's default scan type was used", req.ScanType)
 		}
 	}
 
@@ -88,11 +234,26 @@ func scanOpenPortsHandler(w http.ResponseWriter, r *http.Request) {
 	if req.Ports != "" {
 		cmdArgs = append(cmdArgs, "-p", req.Ports)
 	}
+	if req.TopPorts != 0 {
+		cmdArgs = append(cmdArgs, "--top-ports", strconv.Itoa(req.TopPorts))
+	}
+	if req.openOnly() {
+		cmdArgs = append(cmdArgs, "--open")
+	}
 
 	// Add service detection
 	if req.ServiceDetection {
 		cmdArgs = append(cmdArgs, "-sV")
 	}
+	if req.VersionIntensity != nil {
+		cmdArgs = append(cmdArgs, "--version-intensity", strconv.Itoa(*req.VersionIntensity))
+	}
+	switch req.VersionMode {
+	case "light":
+		cmdArgs = append(cmdArgs, "--version-light")
+	case "all":
+		cmdArgs = append(cmdArgs, "--version-all")
+	}
 
 	// Add OS detection
 	if req.OSDetection {
@@ -101,92 +262,678 @@ func scanOpenPortsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Add script scanning
 	if req.Scripts != "" {
+		if err := scriptAllowlist.check(req.Scripts); err != nil {
+			return nil, err
+		}
 		cmdArgs = append(cmdArgs, "--script", req.Scripts)
 	}
+	// -sC and -A run the "default" category.
+	if req.FlagSC || req.Aggressive {
+		if err := scriptAllowlist.check("default"); err != nil {
+			return nil, err
+		}
+	}
 
-	// Add output format
-	if req.OutputFormat != "" {
-		validFormats := map[string]string{
-			"xml":       "-oX",
-			"json":      "-oJ",
-			"greppable": "-oG",
-			"all":       "-oA",
+	// Add NSE script arguments
+	if len(req.ScriptArgs) > 0 {
+		scriptArgs, err := formatScriptArgs(req.ScriptArgs)
+		if err != nil {
+			return nil, err
 		}
-		if format, exists := validFormats[req.OutputFormat]; exists {
-			cmdArgs = append(cmdArgs, format)
+		if req.Scripts == "" && !req.FlagSC && !req.Aggressive {
+			addWarning(ctx, "script_args given without scripts, flag_sc or aggressive; no script will use them")
 		}
+		cmdArgs = append(cmdArgs, "--script-args", scriptArgs)
 	}
 
-	// Add direct Nmap flags
-	if req.FlagO {
-		cmdArgs = append(cmdArgs, "-O")
+	// output_format is returned inline; the files themselves are added by
+	// nmapScanner.run, which also removes them again.
+	if req.OutputFormat != "" {
+		if strings.EqualFold(strings.TrimSpace(req.OutputFormat), "json") {
+			addWarning(ctx, "nmap has no JSON output; output_format %q ignored, use xml instead", req.OutputFormat)
+		} else if inlineOutputFormats(req.OutputFormat) == nil {
+			addWarning(ctx, "unknown output_format %q ignored", req.OutputFormat)
+		}
 	}
+
+	// Add default scripts, aggressive mode and traceroute. The flag_*
+	// aliases and anything -A implies were folded in by
+	// validateScanRequest.
 	if req.FlagSC {
 		cmdArgs = append(cmdArgs, "-sC")
 	}
-	if req.FlagSV {
-		cmdArgs = append(cmdArgs, "-sV")
+	if req.Aggressive {
+		cmdArgs = append(cmdArgs, "-A")
 	}
-	if req.FlagTraceroute {
+	if req.Traceroute {
 		cmdArgs = append(cmdArgs, "--traceroute")
 	}
-	if req.FlagA {
-		cmdArgs = append(cmdArgs, "-A")
+
+	// Scan every resolved address of a hostname. nmap only follows the
+	// address family it is scanning, so AAAA records need -6 as well.
+	if req.ResolveAll {
+		cmdArgs = append(cmdArgs, "--resolve-all")
 	}
 
-	// Add aggressive scan options (-A flag) - for backward compatibility
-	if req.Aggressive && !req.FlagA {
-		cmdArgs = append(cmdArgs, "-A")
+	// Add host exclusions. These apply to every target in the scan.
+	if req.Exclude != "" {
+		exclude, err := validateExcludeList(req.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		cmdArgs = append(cmdArgs, "--exclude", exclude)
+	}
+	if req.ExcludeFile != "" {
+		if err := validateExcludeFile(req.ExcludeFile); err != nil {
+			return nil, err
+		}
+		cmdArgs = append(cmdArgs, "--excludefile", req.ExcludeFile)
 	}
 
-	// Add traceroute separately (in case user wants it without -A)
-	if req.Traceroute && !req.FlagTraceroute && !req.Aggressive && !req.FlagA {
-		cmdArgs = append(cmdArgs, "--traceroute")
+	if err := checkNmapPrivileges(cmdArgs); err != nil {
+		return nil, err
+	}
+	if nmapPrivileged && os.Geteuid() != 0 {
+		cmdArgs = append(cmdArgs, "--privileged")
+	}
+
+	// Add targets. nmap only scans IPv6 with -6, so it is added for IPv6
+	// targets even when the caller didn't ask for it; one run can't mix
+	// IPv4 and IPv6 addresses.
+	targets := req.targetList()
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("target is required")
+	}
+	if len(targets) > maxScanTargets {
+		return nil, fmt.Errorf("too many targets: %d (max %d)", len(targets), maxScanTargets)
+	}
+	ipv6, ipv4 := "", ""
+	for _, t := range targets {
+		if err := validateTarget(t); err != nil {
+			return nil, err
+		}
+		if err := checkTargetScope(ctx, t); err != nil {
+			return nil, err
+		}
+		if isIPv6Target(t) && ipv6 == "" {
+			ipv6 = t
+		}
+		if isIPv4Target(t) && ipv4 == "" {
+			ipv4 = t
+		}
+	}
+	if req.IPv6 && ipv4 != "" {
+		return nil, fmt.Errorf("ipv6 cannot be used with IPv4 target %q", ipv4)
+	}
+	if ipv6 != "" && ipv4 != "" {
+		return nil, fmt.Errorf("IPv4 target %q and IPv6 target %q cannot be scanned together", ipv4, ipv6)
+	}
+	if req.IPv6 || ipv6 != "" {
+		cmdArgs = append(cmdArgs, "-6")
+	}
+	cmdArgs = dedupeNmapFlags(cmdArgs)
+	for _, t := range targets {
+		cmdArgs = append(cmdArgs, unbracketIPv6(t))
+	}
+
+	return cmdArgs, nil
+}
+
+// valuelessNmapFlags are the switches buildNmapArgs emits that take no
+// value, so a repeat of one is always redundant.
+var valuelessNmapFlags = map[string]bool{
+	"-sn": true, "-sS": true, "-sT": true, "-sU": true, "-sA": true, "-sF": true, "-sN": true, "-sX": true,
+	"-sV": true, "-O": true, "-sC": true, "-A": true,
+	"--traceroute": true, "--resolve-all": true, "--privileged": true, "-6": true, "-f": true, "--open": true,
+	"--version-light": true, "--version-all": true,
+}
+
+// dedupeNmapFlags drops repeated valueless flags, keeping the first
+// occurrence and the order of everything else. Flags that take a value,
+// and the values themselves, are never touched.
+func dedupeNmapFlags(args []string) []string {
+	seen := make(map[string]bool)
+	out := args[:0:0]
+	for _, a := range args {
+		if valuelessNmapFlags[a] {
+			if seen[a] {
+				continue
+			}
+			seen[a] = true
+		}
+		out = append(out, a)
 	}
+	return out
+}
+
+// nmapScanner holds the shared state every nmap endpoint needs: the
+// concurrency limiter, the store for on-disk output files and, optionally,
+// the persistent scan history and the audit log.
+type nmapScanner struct {
+	limiter *scanLimiter
+	outputs *scanOutputStore
+	history *scanHistory
+	audit   *auditLog
+}
+
+// run executes nmap with the given arguments. The process is killed if ctx is
+// cancelled. Whatever output nmap produced is always returned, even when it
+// exits with an error; a non-zero exit surfaces as an *exec.ExitError, any
+// other error means nmap never ran.
+func (s *nmapScanner) run(ctx context.Context, req scanRequest, cmdArgs []string) (scanResponse, error) {
+	return s.runWithLines(ctx, req, cmdArgs, nil)
+}
 
-	// Add target
-	cmdArgs = append(cmdArgs, req.Target)
+// runWithLines is run, additionally calling onLine with each line of stdout
+// as nmap prints it. onLine may be nil.
+func (s *nmapScanner) runWithLines(ctx context.Context, req scanRequest, cmdArgs []string, onLine func(string)) (scanResponse, error) {
+	resp := scanResponse{Target: req.Target}
 
-	// Build command
-	cmd := exec.Command("nmap", cmdArgs...)
-	out, err := cmd.CombinedOutput()
+	outputID := ""
+	if len(req.OutputFormats) > 0 {
+		id, outputArgs, err := s.outputs.prepare(req.OutputFormats)
+		if err != nil {
+			resp.ExitCode = -1
+			resp.Error = "failed to prepare scan output"
+			return resp, err
+		}
+		outputID = id
+		cmdArgs = append(outputArgs, cmdArgs...)
+	}
+
+	// Inline output files and the XML used for the structured host results
+	// go to a temporary directory.
+	inline := inlineOutputFormats(req.OutputFormat)
+	workDir, err := os.MkdirTemp(scanWorkDir, "nmap-inline-*")
 	if err != nil {
-		// Still return whatever output we got, plus the error text.
-		log.Printf("nmap error for target %s: %v", req.Target, err)
+		resp.ExitCode = -1
+		resp.Error = "failed to prepare scan output"
+		return resp, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	// The files are only needed until they have been read back into the
+	// response, whatever the outcome of the scan.
+	defer os.RemoveAll(workDir)
+	cmdArgs = append(outputFileArgs(workDir, workDirOutputFormats(req)), cmdArgs...)
+	xmlPath := filepath.Join(workDir, nmapFileFormats["xml"].fileName)
+	if outputID != "" {
+		if p, ok := s.outputs.path(outputID, "xml"); ok {
+			xmlPath = p
+		}
 	}
 
-	resp := scanResponse{
-		Target:    req.Target,
-		RawOutput: string(out),
+	if err := s.audit.record(ctx, auditEntry{
+		Action:  auditNmapScan,
+		Target:  strings.Join(req.targetList(), " "),
+		Command: append([]string{nmapPath}, cmdArgs...),
+	}); err != nil {
+		resp.ExitCode = -1
+		resp.Error = "failed to write audit log"
+		return resp, err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("failed to encode response: %v", err)
+	var stdout, stderr bytes.Buffer
+	startedAt := time.Now()
+	done := observeNmapScan(req.ScanType)
+	cmd := exec.CommandContext(ctx, nmapPath, cmdArgs...)
+	cmd.Stdout = &stdout
+	var lines *lineWriter
+	if onLine != nil {
+		lines = &lineWriter{onLine: onLine}
+		cmd.Stdout = io.MultiWriter(&stdout, lines)
 	}
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	done(err)
+	if lines != nil {
+		lines.flush()
+	}
+
+	resp.RawOutput = stdout.String()
+	resp.ScannedAddresses = parseScannedAddresses(resp.RawOutput)
+	resp.Hosts = readHostResults(xmlPath, resp.RawOutput)
+	if slices.Contains(cmdArgs, "--traceroute") || slices.Contains(cmdArgs, "-A") {
+		for _, h := range resp.Hosts {
+			if h.Status == "up" && len(h.Route) == 0 {
+				addWarning(ctx, "traceroute produced no hops for %s", h.Address)
+			}
+		}
+	}
+	// nmap prints most of its warnings on stderr, so scan both streams.
+	for _, msg := range parseNmapWarnings(resp.RawOutput + "\n" + stderr.String()) {
+		addWarning(ctx, "%s", msg)
+	}
+	// stderr can contain local paths and other internals, so it is only
+	// returned to callers when verbose errors are enabled.
+	if verboseErrors {
+		resp.Stderr = stderr.String()
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		resp.ExitCode = exitErr.ExitCode()
+		resp.Error = fmt.Sprintf("nmap exited with status %d", resp.ExitCode)
+		err = fmt.Errorf("%s: %w; stderr: %s", resp.Error, err, strings.TrimSpace(stderr.String()))
+	case err != nil:
+		resp.ExitCode = -1
+		resp.Error = "failed to run nmap"
+		if verboseErrors {
+			resp.Error += ": " + err.Error()
+		}
+		err = fmt.Errorf("failed to run nmap: %w", err)
+	}
+	if len(inline) > 0 {
+		resp.OutputContents = readOutputFiles(workDir, inline)
+		for _, f := range inline {
+			if _, ok := resp.OutputContents[f]; !ok {
+				addWarning(ctx, "nmap did not produce %s output", f)
+			}
+		}
+	}
+	if outputID != "" {
+		resp.OutputFiles = s.outputs.links(outputID)
+		for _, f := range req.OutputFormats {
+			if _, ok := resp.OutputFiles[f]; !ok {
+				addWarning(ctx, "nmap did not produce %s output", f)
+			}
+		}
+	}
+	if s.history != nil {
+		// Record the scan even if the client has gone away.
+		id, herr := s.history.record(context.WithoutCancel(ctx), cmdArgs, startedAt, resp)
+		if herr != nil {
+			log.Printf("%v", herr)
+			addWarning(ctx, "scan could not be saved to history")
+		}
+		resp.HistoryID = id
+	}
+	return resp, err
+}
+
+// decodeScanRequest reads and normalizes a scanRequest from the request body,
+// writing a 400 response and returning false when it is invalid.
+func decodeScanRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) (scanRequest, []string, bool) {
+	var req scanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+		return req, nil, false
+	}
+	targets := req.targetList()
+	if len(targets) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "target is required")
+		return req, nil, false
+	}
+	// Target is echoed in responses and history, so it names every host.
+	req.Target = strings.Join(targets, " ")
+	req.Targets = nil
+	req.Exclude = strings.TrimSpace(req.Exclude)
+	req.ExcludeFile = strings.TrimSpace(req.ExcludeFile)
+
+	formats, err := normalizeOutputFormats(req.OutputFormats)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return req, nil, false
+	}
+	req.OutputFormats = formats
+
+	cmdArgs, err := buildNmapArgs(ctx, req)
+	var reqErr *scanRequestError
+	if errors.As(err, &reqErr) {
+		writeScanRequestError(w, reqErr)
+		return req, nil, false
+	}
+	if err != nil {
+		writeJSONError(w, statusForError(err, http.StatusBadRequest), err.Error())
+		return req, nil, false
+	}
+	return req, cmdArgs, true
+}
+
+// scanOpenPortsHandler runs nmap synchronously and returns the raw output.
+// Scans are rejected with 429 when the concurrency limit is reached.
+func scanOpenPortsHandler(scanner *nmapScanner) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		req, cmdArgs, ok := decodeScanRequest(ctx, w, r)
+		if !ok {
+			return
+		}
+		if req.DryRun {
+			writeNmapDryRun(w, req, cmdArgs, warns.list())
+			return
+		}
+
+		if !scanner.limiter.tryAcquire() {
+			writeTooManyScans(w)
+			return
+		}
+		defer scanner.limiter.release()
+
+		resp, err := scanner.run(ctx, req, cmdArgs)
+		status := http.StatusOK
+		if err != nil {
+			// Still return whatever output we got, plus the error text. Only a
+			// failure to launch nmap is a server error; a non-zero exit is
+			// reported through exit_code and error.
+			log.Printf("nmap error for target %s: %v", req.Target, err)
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				status = http.StatusInternalServerError
+			}
+		}
+		resp.Warnings = warns.list()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("failed to encode response: %v", err)
+		}
+	})
 }
 
 func main() {
+	addrFlag := flag.String("addr", "", "listen address (overrides LISTEN_ADDR, default :8080)")
+	flag.Parse()
+
 	// Load environment variables from .env so OpenVAS auth/config
 	// is available without manually exporting each time.
 	_ = godotenv.Load(".env")
 
+	// Detailed error messages are useful in development but can leak internals
+	// (nmap stderr, gvmd output) in production, so they are opt-in.
+	verboseErrors = envBool("VERBOSE_ERRORS", false)
+
+	nmapPath = envString("NMAP_PATH", "nmap")
+
+	// Every scan writes nmap's output files under SCAN_WORK_DIR (the system
+	// temp directory by default), so it must be writable from the start.
+	scanWorkDir = envString("SCAN_WORK_DIR", "")
+	if err := prepareWorkDir(scanWorkDir); err != nil {
+		log.Fatalf("%v; set SCAN_WORK_DIR to a writable directory", err)
+	}
+	nmapPrivileged = envBool("NMAP_PRIVILEGED", nmapPrivileged)
+
+	// T2 is a safe default for production networks; labs usually want T4.
+	defaultNmapTiming = strings.ToUpper(envString("DEFAULT_NMAP_TIMING", defaultNmapTiming))
+	if !validTimings[defaultNmapTiming] {
+		log.Fatalf("invalid DEFAULT_NMAP_TIMING=%q: must be one of T0, T1, T2, T3, T4, T5", defaultNmapTiming)
+	}
+
+	// Pin the scan type so results don't depend on whether nmap happens to
+	// run privileged.
+	defaultScanType = strings.ToLower(envString("DEFAULT_SCAN_TYPE", ""))
+	if defaultScanType != "" {
+		scanFlag, ok := validScanTypes[defaultScanType]
+		if !ok {
+			log.Fatalf("invalid DEFAULT_SCAN_TYPE=%q: must be one of ping, tcp_syn, tcp_connect, udp, tcp_ack, tcp_fin, tcp_null, tcp_xmas", defaultScanType)
+		}
+		if _, needsRoot := privilegedNmapFlags[scanFlag]; needsRoot && !nmapPrivileged {
+			log.Printf("warning: DEFAULT_SCAN_TYPE=%s needs root privileges, which this server does not have; requests without a scan_type will be rejected", defaultScanType)
+		}
+	}
+
+	// Optionally restrict --script to known-safe scripts and categories.
+	allowlist, err := loadScriptAllowlist(envList("NSE_SCRIPT_ALLOWLIST"), envString("NSE_SCRIPT_ALLOWLIST_FILE", ""))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	scriptAllowlist = allowlist
+
+	// Optionally confine nmap and OpenVAS scans to ALLOWED_TARGETS
+	// (comma-separated CIDRs or addresses). Hostnames are rejected unless
+	// ALLOWED_TARGETS_RESOLVE is set, in which case every address they
+	// resolve to must be allowed.
+	scope, err := parseTargetAllowlist(envList("ALLOWED_TARGETS"), envBool("ALLOWED_TARGETS_RESOLVE", false))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	targetAllowlist = scope
+
+	// DENIED_TARGETS (comma-separated CIDRs, addresses or hostnames, e.g.
+	// 169.254.169.254,127.0.0.0/8) are never scanned. It is checked before
+	// ALLOWED_TARGETS, so it can carve holes out of an allowed network.
+	deny, err := parseTargetDenylist(envList("DENIED_TARGETS"))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	targetDenylist = deny
+
+	// baseCtx is the parent of every request and background scan. It is
+	// cancelled during shutdown so in-flight nmap processes are killed
+	// instead of being orphaned.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/scan-open-ports", scanOpenPortsHandler)
+	mux.Handle("/healthz", healthzHandler())
+	mux.Handle("/version", versionHandler())
+	mux.Handle("/metrics", promhttp.Handler())
+
+	// Cap simultaneous nmap processes so callers can't thrash the host, and
+	// expire on-disk output files after SCAN_OUTPUT_TTL.
+	scanner := &nmapScanner{
+		limiter: newScanLimiter(envInt("MAX_CONCURRENT_SCANS", 4)),
+		outputs: newScanOutputStore(envDuration("SCAN_OUTPUT_TTL", time.Hour)),
+	}
+	go scanner.outputs.runCleanup(baseCtx, time.Minute)
+
+	// Every scan and OpenVAS task start is appended to AUDIT_LOG as a JSON
+	// line before it is issued; if the entry can't be written, the scan is
+	// refused.
+	var audit *auditLog
+	if path := envString("AUDIT_LOG", ""); path != "" {
+		audit, err = openAuditLog(path)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer audit.close()
+		scanner.audit = audit
+	} else {
+		log.Printf("AUDIT_LOG is not set; scans are not audited")
+	}
+
+	// Every finished scan is persisted to SQLite unless SCAN_HISTORY_DB is
+	// set to "off"; entries older than SCAN_HISTORY_RETENTION are pruned.
+	if dbPath := envString("SCAN_HISTORY_DB", "scan_history.db"); dbPath != "off" {
+		history, err := openScanHistory(dbPath, envDuration("SCAN_HISTORY_RETENTION", 30*24*time.Hour))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer history.close()
+		scanner.history = history
+		go history.runPrune(baseCtx, time.Hour)
+		mux.Handle("/scan-history", scanHistoryListHandler(history))
+		mux.Handle("/scan-history/{id}", scanHistoryEntryHandler(history))
+		mux.Handle("/scan-history/diff", scanDiffHandler(history))
+	}
+	mux.Handle("/scan-open-ports", scanOpenPortsHandler(scanner))
+	mux.Handle("/scan-output", scanOutputHandler(scanner.outputs))
+	mux.Handle("/nmap/version", nmapVersionHandler(&nmapVersionCache{}))
+
+	// Async scans: jobs are persisted to SQLite so they survive a restart,
+	// or kept in memory when SCAN_JOB_DB is "off". Finished jobs expire
+	// after SCAN_JOB_TTL.
+	jobTTL := envDuration("SCAN_JOB_TTL", time.Hour)
+	var jobs JobStore = newMemoryJobStore(jobTTL)
+	if dbPath := envString("SCAN_JOB_DB", "scan_jobs.db"); dbPath != "off" {
+		store, err := openSQLiteJobStore(dbPath, jobTTL)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer store.close()
+		jobs = store
+	}
+	go runJobCleanup(baseCtx, jobs, time.Minute)
+	mux.Handle("/discover-hosts", discoverHostsHandler(scanner))
+	mux.Handle("/scan-open-ports/stream", scanStreamHandler(scanner))
+	mux.Handle("/scan-open-ports/async", scanOpenPortsAsyncHandler(baseCtx, jobs, scanner))
+	mux.Handle("/scan-open-ports/status", scanStatusHandler(jobs))
+	mux.Handle("/scan-open-ports/jobs", scanJobsHandler(jobs))
+
+	if envBool("DASHBOARD_ENABLED", false) {
+		mux.Handle("/", dashboardHandler())
+	}
 
 	// Modular OpenVAS APIs.
 	openVASService := NewOpenVASServiceFromEnv()
-	mux.Handle("/openvas/version", openVASVersionHandler(openVASService))
-	mux.Handle("/openvas/configs", openVASConfigsHandler(openVASService))
-	mux.Handle("/openvas/targets", openVASCreateTargetHandler(openVASService))
-	mux.Handle("/openvas/tasks", openVASCreateTaskHandler(openVASService))
-	mux.Handle("/openvas/tasks/start", openVASStartTaskHandler(openVASService))
-	mux.Handle("/openvas/tasks/status", openVASTaskStatusHandler(openVASService))
-	mux.Handle("/openvas/reports", openVASGetReportHandler(openVASService))
-
-	addr := ":8080"
-	log.Printf("Go backend listening on %s", addr)
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		log.Fatalf("server failed: %v", err)
+	openVASService.audit = audit
+
+	// Tasks started here keep running inside gvmd across restarts, so the
+	// registry of tracked tasks is reloaded on startup and saved on shutdown.
+	tasks := newTaskRegistry(envString("TASK_REGISTRY_FILE", "openvas_tasks.json"))
+	if err := tasks.load(); err != nil {
+		log.Printf("failed to load task registry: %v", err)
+	} else if n := len(tasks.list()); n > 0 {
+		log.Printf("resumed tracking of %d OpenVAS task(s)", n)
+	}
+
+	// Notify an external webhook when a tracked task finishes.
+	if webhookURL := envString("WEBHOOK_URL", ""); webhookURL != "" {
+		notifier := newWebhookNotifier(webhookURL, os.Getenv("WEBHOOK_SECRET"), envInt("WEBHOOK_MAX_ATTEMPTS", 5))
+		go watchTasks(baseCtx, openVASService, tasks, notifier, envDuration("WEBHOOK_POLL_INTERVAL", 30*time.Second))
+	}
+
+	mux.Handle("/readyz", readyzHandler(
+		openVASService,
+		envBool("READYZ_CHECK_OPENVAS", false),
+		envDuration("READYZ_OPENVAS_TIMEOUT", 5*time.Second),
+	))
+	// Requests that wait for a task to finish give up after OPENVAS_MAX_WAIT.
+	maxTaskWait := envDuration("OPENVAS_MAX_WAIT", 30*time.Minute)
+	mux.Handle("/openvas/version", instrumentOpenVAS("get_version", openVASDryRun(openVASVersionHandler(openVASService))))
+	// No dry-run wrapper: the recorded command would contain the password.
+	mux.Handle("/openvas/auth-check", instrumentOpenVAS("authenticate", openVASAuthCheckHandler(openVASService)))
+	mux.Handle("/openvas/configs", instrumentOpenVAS("get_configs", openVASDryRun(openVASConfigsHandler(openVASService))))
+	mux.Handle("/openvas/configs/get", instrumentOpenVAS("get_config", openVASDryRun(openVASGetConfigHandler(openVASService))))
+	mux.Handle("/openvas/scanners", instrumentOpenVAS("get_scanners", openVASDryRun(openVASScannersHandler(openVASService))))
+	mux.Handle("/openvas/feeds", instrumentOpenVAS("get_feeds", openVASDryRun(openVASFeedsHandler(openVASService, envDuration("OPENVAS_FEED_MAX_AGE", defaultFeedMaxAge)))))
+	mux.Handle("/openvas/nvt-families", instrumentOpenVAS("get_nvt_families", openVASDryRun(openVASNVTFamiliesHandler(openVASService))))
+	mux.Handle("/openvas/nvts", instrumentOpenVAS("get_nvts", openVASDryRun(openVASNVTsHandler(openVASService))))
+	mux.Handle("/openvas/dashboard", instrumentOpenVAS("get_dashboard", openVASDryRun(openVASDashboardHandler(openVASService))))
+	mux.Handle("/openvas/targets", instrumentOpenVAS("create_target", openVASDryRun(openVASCreateTargetHandler(openVASService))))
+	mux.Handle("/openvas/port-lists", instrumentOpenVAS("get_port_lists", openVASDryRun(openVASPortListsHandler(openVASService))))
+	mux.Handle("/openvas/port-lists/create", instrumentOpenVAS("create_port_list", openVASDryRun(openVASCreatePortListHandler(openVASService))))
+	mux.Handle("/openvas/targets/get", instrumentOpenVAS("get_target", openVASDryRun(openVASGetTargetHandler(openVASService))))
+	mux.Handle("/openvas/targets/delete", instrumentOpenVAS("delete_target", openVASDryRun(openVASDeleteTargetHandler(openVASService))))
+	mux.Handle("/openvas/schedules", instrumentOpenVAS("create_schedule", openVASDryRun(openVASCreateScheduleHandler(openVASService))))
+	mux.Handle("/openvas/tasks", instrumentOpenVAS("create_task", openVASDryRun(openVASCreateTaskHandler(openVASService))))
+	mux.Handle("/openvas/tasks/list", instrumentOpenVAS("list_tasks", openVASDryRun(openVASListTasksHandler(openVASService))))
+	mux.Handle("/openvas/tasks/modify", instrumentOpenVAS("modify_task", openVASDryRun(openVASModifyTaskHandler(openVASService))))
+	mux.Handle("/openvas/tasks/clone", instrumentOpenVAS("clone_task", openVASDryRun(openVASCloneTaskHandler(openVASService))))
+	mux.Handle("/openvas/tasks/start", instrumentOpenVAS("start_task", openVASDryRun(openVASStartTaskHandler(openVASService, tasks))))
+	mux.Handle("/openvas/tasks/stop", instrumentOpenVAS("stop_task", openVASDryRun(openVASStopTaskHandler(openVASService, tasks))))
+	mux.Handle("/openvas/tasks/resume", instrumentOpenVAS("resume_task", openVASDryRun(openVASResumeTaskHandler(openVASService, tasks))))
+	mux.Handle("/openvas/tasks/tracked", trackedTasksHandler(tasks))
+	mux.Handle("/openvas/tasks/status", instrumentOpenVAS("get_task_status", openVASDryRun(openVASTaskStatusHandler(openVASService))))
+	mux.Handle("/openvas/tasks/progress", instrumentOpenVAS("get_task_progress", openVASDryRun(openVASTaskProgressHandler(openVASService))))
+	mux.Handle("/openvas/tasks/wait", instrumentOpenVAS("wait_task", openVASDryRun(openVASWaitTaskHandler(openVASService, maxTaskWait))))
+	mux.Handle("/openvas/task-stream", instrumentOpenVAS("stream_task", openVASTaskStreamHandler(openVASService)))
+	mux.Handle("/openvas/reports", instrumentOpenVAS("get_report", openVASDryRun(openVASGetReportHandler(openVASService))))
+	mux.Handle("/openvas/report-summary", instrumentOpenVAS("summarize_report", openVASDryRun(openVASReportSummaryHandler(openVASService))))
+	mux.Handle("/openvas/reports/cves", instrumentOpenVAS("get_report_cves", openVASDryRun(openVASReportCVEsHandler(openVASService))))
+	mux.Handle("/openvas/reports/list", instrumentOpenVAS("list_reports", openVASDryRun(openVASListReportsHandler(openVASService))))
+	mux.Handle("/openvas/results", instrumentOpenVAS("get_results", openVASDryRun(openVASGetResultsHandler(openVASService))))
+	mux.Handle("/openvas/report-formats", instrumentOpenVAS("get_report_formats", openVASDryRun(openVASReportFormatsHandler(openVASService))))
+	mux.Handle("/openvas/reports/delete", instrumentOpenVAS("delete_report", openVASDryRun(openVASDeleteReportHandler(openVASService))))
+	mux.Handle("/openvas/reports/export", instrumentOpenVAS("export_report", openVASDryRun(openVASExportReportHandler(openVASService))))
+	mux.Handle("/openvas/overrides", instrumentOpenVAS("create_override", openVASDryRun(openVASCreateOverrideHandler(openVASService))))
+	mux.Handle("/openvas/scan", instrumentOpenVAS("scan", openVASDryRun(openVASScanHandler(openVASService, tasks, maxTaskWait))))
+	// User management needs an ADMIN_API_KEY; it is deliberately kept out
+	// of dry-run mode, whose records would contain the new password.
+	adminKeys := parseAPIKeys(os.Getenv("ADMIN_API_KEY"))
+	mux.Handle("/openvas/users", instrumentOpenVAS("get_users", adminOnlyMiddleware(adminKeys, openVASUsersHandler(openVASService))))
+	mux.Handle("/openvas/users/create", instrumentOpenVAS("create_user", adminOnlyMiddleware(adminKeys, openVASCreateUserHandler(openVASService))))
+	mux.Handle("/recon/full", instrumentOpenVAS("recon_full", reconFullHandler(scanner, openVASService, tasks)))
+	mux.Handle("/openvas/scan/existing", instrumentOpenVAS("scan_existing", openVASDryRun(openVASScanExistingHandler(openVASService, tasks))))
+
+	// Every endpoint except the public ones requires X-API-Key when API_KEY
+	// is set. Multiple comma-separated keys allow rotation.
+	apiKeys := parseAPIKeys(os.Getenv("API_KEY"))
+	if len(apiKeys) == 0 {
+		log.Printf("API_KEY is not set; API authentication is disabled")
+	}
+	// Admin keys are API keys too, so admins don't need a second header.
+	if len(apiKeys) > 0 {
+		apiKeys = append(apiKeys, adminKeys...)
+	}
+	handler := apiKeyMiddleware(apiKeys, mux)
+	// Large responses such as OpenVAS reports are gzipped for clients that
+	// accept it once they reach GZIP_MIN_BYTES.
+	handler = gzipMiddleware(envInt("GZIP_MIN_BYTES", defaultGzipMinBytes), handler)
+
+	// Browser clients on other origins must be listed in CORS_ALLOWED_ORIGINS
+	// (comma-separated, e.g. https://ui.example.com). CORS runs before the
+	// API key check because preflight requests never carry the key.
+	handler = corsMiddleware(envList("CORS_ALLOWED_ORIGINS"), handler)
+
+	inFlight := &inFlightCounter{}
+	handler = inFlight.middleware(handler)
+	handler = loggingMiddleware(slog.New(slog.NewJSONHandler(os.Stdout, nil)), handler)
+	// The body limit goes outermost: http.MaxBytesReader can only make the
+	// server close the connection after a 413 when it is handed the
+	// server's own ResponseWriter, not one wrapped by gzip or logging.
+	handler = bodyLimitMiddleware(int64(envInt("MAX_BODY_BYTES", defaultMaxBodyBytes)), handler)
+
+	// -addr wins over LISTEN_ADDR so one-off instances can be started on
+	// another port without touching the environment. The default stays at
+	// :8080, the port the server has always used and the chatbot's API
+	// client calls.
+	addr := envString("LISTEN_ADDR", ":8080")
+	if *addrFlag != "" {
+		addr = *addrFlag
+	}
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Go backend %s (commit %s) listening on %s", version, commit, addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server failed: %v", err)
+		}
+	case <-sigCtx.Done():
+		shutdown(srv, cancelBase, inFlight, tasks, envDuration("SHUTDOWN_TIMEOUT", 30*time.Second))
+	}
+}
+
+// shutdown stops accepting connections and waits up to timeout for in-flight
+// requests to finish. Whatever is still running afterwards (including async
+// scans) has its context cancelled so nmap children are killed. The OpenVAS
+// task registry is persisted last.
+func shutdown(srv *http.Server, cancelBase context.CancelFunc, inFlight *inFlightCounter, tasks *taskRegistry, timeout time.Duration) {
+	pending := inFlight.count()
+	log.Printf("shutting down, waiting up to %s for %d in-flight request(s)", timeout, pending)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("graceful shutdown timed out: %v; cancelling remaining scans", err)
+		cancelBase()
+		_ = srv.Close()
+	}
+	cancelBase()
+
+	remaining := inFlight.count()
+	log.Printf("drained %d in-flight request(s), %d cancelled", pending-remaining, remaining)
+
+	if err := tasks.save(); err != nil {
+		log.Printf("failed to save task registry: %v", err)
 	}
 }
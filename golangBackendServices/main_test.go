@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nmapArgs builds the nmap arguments for req as an unprivileged server, so
+// the result doesn't depend on who runs the tests.
+func nmapArgs(t *testing.T, ctx context.Context, req scanRequest) ([]string, error) {
+	t.Helper()
+	privileged := nmapPrivileged
+	nmapPrivileged = false
+	t.Cleanup(func() { nmapPrivileged = privileged })
+	return buildNmapArgs(ctx, req)
+}
+
+func TestBuildNmapArgsResolveAll(t *testing.T) {
+	tests := []struct {
+		name string
+		req  scanRequest
+		want []string
+	}{
+		{"off", scanRequest{Target: "scanme.nmap.org"}, []string{"-T2", "--open", "scanme.nmap.org"}},
+		{"on", scanRequest{Target: "scanme.nmap.org", ResolveAll: true}, []string{"-T2", "--open", "--resolve-all", "scanme.nmap.org"}},
+		{"with ipv6", scanRequest{Target: "scanme.nmap.org", ResolveAll: true, IPv6: true}, []string{"-T2", "--open", "--resolve-all", "-6", "scanme.nmap.org"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nmapArgs(t, context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("buildNmapArgs: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildNmapArgsWarnings(t *testing.T) {
+	intensity := 5
+	tests := []struct {
+		name    string
+		req     scanRequest
+		want    string
+		dropped string
+	}{
+		{
+			name:    "unknown scan type",
+			req:     scanRequest{Target: "10.0.0.1", ScanType: "tcp_window"},
+			want:    `unknown scan_type "tcp_window" ignored; nmap's default scan type was used`,
+			dropped: "-sW",
+		},
+		{
+			name: "json output",
+			req:  scanRequest{Target: "10.0.0.1", OutputFormat: "json"},
+			want: `nmap has no JSON output; output_format "json" ignored, use xml instead`,
+		},
+		{
+			name: "script args without scripts",
+			req:  scanRequest{Target: "10.0.0.1", ScriptArgs: map[string]string{"http.useragent": "scanner"}},
+			want: "script_args given without scripts, flag_sc or aggressive; no script will use them",
+		},
+		{
+			name:    "version options without service detection",
+			req:     scanRequest{Target: "10.0.0.1", VersionIntensity: &intensity},
+			want:    "version_intensity and version_mode only apply with service_detection; ignored",
+			dropped: "--version-intensity",
+		},
+		{
+			name: "flag alias repeated",
+			req:  scanRequest{Target: "10.0.0.1", ServiceDetection: true, FlagSV: true},
+			want: "service_detection and flag_sv both set; they are the same option",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, w := withWarnings(context.Background())
+			args, err := nmapArgs(t, ctx, tt.req)
+			if err != nil {
+				t.Fatalf("buildNmapArgs: %v", err)
+			}
+			if got := w.list(); !slices.Contains(got, tt.want) {
+				t.Errorf("warnings = %q, want %q", got, tt.want)
+			}
+			if tt.dropped != "" && slices.Contains(args, tt.dropped) {
+				t.Errorf("args %q still contain %s", args, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestBuildNmapArgsNoWarnings(t *testing.T) {
+	ctx, w := withWarnings(context.Background())
+	if _, err := nmapArgs(t, ctx, scanRequest{Target: "10.0.0.1", Ports: "22,80", ServiceDetection: true}); err != nil {
+		t.Fatalf("buildNmapArgs: %v", err)
+	}
+	if got := w.list(); got != nil {
+		t.Errorf("warnings = %q, want none", got)
+	}
+}
+
+func TestBuildNmapArgsExclude(t *testing.T) {
+	excludeFile := filepath.Join(t.TempDir(), "exclude.txt")
+	if err := os.WriteFile(excludeFile, []byte("# gateway\n10.0.0.1\n10.0.0.254 # printer\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		req     scanRequest
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "exclude list",
+			req:  scanRequest{Target: "10.0.0.0/24", Exclude: "10.0.0.1, 10.0.0.254"},
+			want: []string{"-T2", "--open", "--exclude", "10.0.0.1,10.0.0.254", "10.0.0.0/24"},
+		},
+		{
+			name: "exclude file",
+			req:  scanRequest{Target: "10.0.0.0/24", ExcludeFile: excludeFile},
+			want: []string{"-T2", "--open", "--excludefile", excludeFile, "10.0.0.0/24"},
+		},
+		{
+			name: "both",
+			req:  scanRequest{Target: "10.0.0.0/24", Exclude: "10.0.0.2", ExcludeFile: excludeFile},
+			want: []string{"-T2", "--open", "--exclude", "10.0.0.2", "--excludefile", excludeFile, "10.0.0.0/24"},
+		},
+		{
+			name:    "option in exclude list",
+			req:     scanRequest{Target: "10.0.0.0/24", Exclude: "10.0.0.1,--script=exploit"},
+			wantErr: "invalid exclude entry",
+		},
+		{
+			name:    "missing exclude file",
+			req:     scanRequest{Target: "10.0.0.0/24", ExcludeFile: filepath.Join(t.TempDir(), "missing.txt")},
+			wantErr: "exclude_file is not readable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nmapArgs(t, context.Background(), tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildNmapArgs: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildNmapArgsIPv6(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     scanRequest
+		want    []string
+		wantErr bool
+	}{
+		{"unbracketed", scanRequest{Target: "2001:db8::1"}, []string{"-T2", "--open", "-6", "2001:db8::1"}, false},
+		{"bracketed", scanRequest{Target: "[2001:db8::1]"}, []string{"-T2", "--open", "-6", "2001:db8::1"}, false},
+		{"cidr", scanRequest{Target: "2001:db8::/64"}, []string{"-T2", "--open", "-6", "2001:db8::/64"}, false},
+		{"bracketed cidr", scanRequest{Target: "[2001:db8::]/64"}, []string{"-T2", "--open", "-6", "2001:db8::/64"}, false},
+		{"explicit flag", scanRequest{Target: "2001:db8::1", IPv6: true}, []string{"-T2", "--open", "-6", "2001:db8::1"}, false},
+		{"hostname with flag", scanRequest{Target: "scanme.nmap.org", IPv6: true}, []string{"-T2", "--open", "-6", "scanme.nmap.org"}, false},
+		{"several", scanRequest{Target: "2001:db8::1", Targets: []string{"[2001:db8::2]"}}, []string{"-T2", "--open", "-6", "2001:db8::1", "2001:db8::2"}, false},
+		{"ipv4 stays ipv4", scanRequest{Target: "10.0.0.1"}, []string{"-T2", "--open", "10.0.0.1"}, false},
+		{"mixed families", scanRequest{Target: "10.0.0.1", Targets: []string{"2001:db8::1"}}, nil, true},
+		{"flag with ipv4", scanRequest{Target: "10.0.0.1", IPv6: true}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nmapArgs(t, context.Background(), tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildNmapArgs error = %v, want error: %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupeNmapFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"no repeats", []string{"-T2", "-sV", "--open"}, []string{"-T2", "-sV", "--open"}},
+		{"repeated switch", []string{"-T4", "-sV", "-O", "-sV"}, []string{"-T4", "-sV", "-O"}},
+		{"aggressive overlaps", []string{"-A", "--traceroute", "-sC", "--traceroute", "-A"}, []string{"-A", "--traceroute", "-sC"}},
+		{"ipv6 twice", []string{"--resolve-all", "-6", "-6"}, []string{"--resolve-all", "-6"}},
+		// Timing templates aren't switches; which one wins is
+		// buildNmapArgs's call, so both are passed through in order.
+		{"conflicting timing", []string{"-T2", "-sV", "-T4"}, []string{"-T2", "-sV", "-T4"}},
+		{"repeated value flags", []string{"-p", "22", "-p", "22"}, []string{"-p", "22", "-p", "22"}},
+		{"empty", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := slices.Clone(tt.args)
+			if got := dedupeNmapFlags(in); !slices.Equal(got, tt.want) {
+				t.Errorf("dedupeNmapFlags(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+			if !slices.Equal(in, tt.args) {
+				t.Errorf("dedupeNmapFlags modified its input: %q", in)
+			}
+		})
+	}
+}
+
+func TestBuildNmapArgsOverlappingOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		req  scanRequest
+		want []string
+	}{
+		{
+			name: "service detection twice",
+			req:  scanRequest{Target: "10.0.0.1", ServiceDetection: true, FlagSV: true},
+			want: []string{"-T2", "--open", "-sV", "10.0.0.1"},
+		},
+		{
+			name: "timing overrides profile",
+			req:  scanRequest{Target: "10.0.0.1", Profile: "quick", Timing: "T3"},
+			want: []string{"-T3", "--top-ports", "100", "--open", "10.0.0.1"},
+		},
+		{
+			name: "ipv6 flag and ipv6 target",
+			req:  scanRequest{Target: "2001:db8::1", IPv6: true, ResolveAll: true},
+			want: []string{"-T2", "--open", "--resolve-all", "-6", "2001:db8::1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nmapArgs(t, context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("buildNmapArgs: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeNmap puts an nmap script on nmapPath that writes each -oX, -oN and -oG
+// file, with the flag as its content, and prints a scan report for one host.
+func fakeNmap(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	script := `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-oX|-oN|-oG) echo "$1" > "$2"; shift ;;
+	esac
+	shift
+done
+echo 'Nmap scan report for 10.0.0.1'
+`
+	path := filepath.Join(dir, "nmap")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oldPath, oldWorkDir := nmapPath, scanWorkDir
+	nmapPath, scanWorkDir = path, t.TempDir()
+	t.Cleanup(func() { nmapPath, scanWorkDir = oldPath, oldWorkDir })
+}
+
+func TestScanWritesEveryOutputFormat(t *testing.T) {
+	fakeNmap(t)
+	flags := map[string]string{"xml": "-oX", "normal": "-oN", "greppable": "-oG"}
+
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+	}{
+		{"xml and greppable", []string{"xml", "greppable"}, []string{"xml", "greppable"}},
+		{"repeats and case", []string{"greppable", "NORMAL", "greppable"}, []string{"greppable", "normal"}},
+		{"all", []string{"all"}, []string{"xml", "normal", "greppable"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formats, err := normalizeOutputFormats(tt.requested)
+			if err != nil {
+				t.Fatalf("normalizeOutputFormats: %v", err)
+			}
+			if !slices.Equal(formats, tt.want) {
+				t.Fatalf("formats = %q, want %q", formats, tt.want)
+			}
+
+			s := &nmapScanner{outputs: newScanOutputStore(time.Hour)}
+			ctx, w := withWarnings(context.Background())
+			resp, err := s.run(ctx, scanRequest{Target: "10.0.0.1", OutputFormats: formats}, []string{"-T2", "10.0.0.1"})
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if len(resp.OutputFiles) != len(tt.want) {
+				t.Errorf("output files = %v, want one per format %q", resp.OutputFiles, tt.want)
+			}
+			if got := w.list(); got != nil {
+				t.Errorf("warnings = %q", got)
+			}
+
+			seen := make(map[string]bool)
+			for _, f := range tt.want {
+				link, ok := resp.OutputFiles[f]
+				if !ok {
+					t.Errorf("no download link for %s", f)
+					continue
+				}
+				u, err := url.Parse(link)
+				if err != nil {
+					t.Fatalf("bad link %q: %v", link, err)
+				}
+				path, ok := s.outputs.path(u.Query().Get("id"), f)
+				if !ok {
+					t.Fatalf("link %q does not resolve", link)
+				}
+				if seen[path] {
+					t.Errorf("%s shares the file %s with another format", f, path)
+				}
+				seen[path] = true
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("%s output: %v", f, err)
+				}
+				if got := strings.TrimSpace(string(data)); got != flags[f] {
+					t.Errorf("%s file was written by %s, want %s", f, got, flags[f])
+				}
+			}
+		})
+	}
+}
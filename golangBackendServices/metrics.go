@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics exposed on /metrics. nmap metrics are labelled by
+// scan_type ("default" when the request leaves it to nmap); OpenVAS metrics
+// by GMP operation.
+var (
+	nmapScansTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nmap_scans_total",
+		Help: "Total number of nmap scans executed.",
+	}, []string{"scan_type"})
+
+	nmapScanErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nmap_scan_errors_total",
+		Help: "Total number of nmap scans that exited with an error.",
+	}, []string{"scan_type"})
+
+	nmapScanDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nmap_scan_duration_seconds",
+		Help:    "Duration of nmap scans in seconds.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+	}, []string{"scan_type"})
+
+	nmapScansRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "nmap_scans_running",
+		Help: "Number of nmap scans currently running.",
+	})
+
+	openVASOperationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "openvas_operations_total",
+		Help: "Total number of OpenVAS operations by outcome.",
+	}, []string{"operation", "result"})
+)
+
+// scanTypeLabel returns the metric label for a request's scan type.
+func scanTypeLabel(scanType string) string {
+	if scanType == "" {
+		return "default"
+	}
+	return scanType
+}
+
+// observeNmapScan starts timing a scan and returns a function that records
+// its outcome.
+func observeNmapScan(scanType string) func(err error) {
+	label := scanTypeLabel(scanType)
+	start := time.Now()
+	nmapScansRunning.Inc()
+
+	return func(err error) {
+		nmapScansRunning.Dec()
+		nmapScansTotal.WithLabelValues(label).Inc()
+		nmapScanDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+		if err != nil {
+			nmapScanErrorsTotal.WithLabelValues(label).Inc()
+		}
+	}
+}
+
+// instrumentOpenVAS counts calls to an OpenVAS endpoint under the given
+// operation name, split into success and error by response status.
+func instrumentOpenVAS(operation string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		result := "success"
+		if rec.status >= 400 {
+			result = "error_" + strconv.Itoa(rec.status)
+		}
+		openVASOperationsTotal.WithLabelValues(operation, result).Inc()
+	})
+}
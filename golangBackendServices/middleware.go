@@ -0,0 +1,405 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// publicPaths are served without an API key: health checks must work for
+// load balancers, and the dashboard page itself prompts for the key.
+var publicPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/":        true,
+}
+
+// parseAPIKeys splits a comma-separated API_KEY value into individual keys so
+// that old and new keys can both be accepted during rotation.
+func parseAPIKeys(raw string) []string {
+	var keys []string
+	for _, k := range strings.Split(raw, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// apiKeyMiddleware rejects requests whose X-API-Key header doesn't match one
+// of keys with 401. With no keys configured every request is let through.
+// Either way the caller's identity is attached to the request context for
+// the audit log.
+func apiKeyMiddleware(keys []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := clientIdentity{RemoteAddr: r.RemoteAddr}
+		if len(keys) == 0 || publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r.WithContext(withClientIdentity(r.Context(), id)))
+			return
+		}
+
+		key, ok := matchAPIKey(keys, r.Header.Get("X-API-Key"))
+		if !ok {
+			writeJSONError(w, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		id.APIKeyID = apiKeyID(key)
+		next.ServeHTTP(w, r.WithContext(withClientIdentity(r.Context(), id)))
+	})
+}
+
+// adminOnlyMiddleware only lets requests through whose X-API-Key matches one
+// of adminKeys. With no admin keys configured the endpoint is disabled and
+// answers 403, rather than being open to every API key holder.
+func adminOnlyMiddleware(adminKeys []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(adminKeys) == 0 {
+			writeJSONError(w, http.StatusForbidden, "admin endpoints are disabled; set ADMIN_API_KEY")
+			return
+		}
+		if !validAPIKey(adminKeys, r.Header.Get("X-API-Key")) {
+			writeJSONError(w, http.StatusForbidden, "admin API key required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validAPIKey compares the presented key against every configured key in
+// constant time.
+func validAPIKey(keys []string, presented string) bool {
+	_, ok := matchAPIKey(keys, presented)
+	return ok
+}
+
+// matchAPIKey is validAPIKey, also returning the key that matched.
+func matchAPIKey(keys []string, presented string) (string, bool) {
+	if presented == "" {
+		return "", false
+	}
+	matched := ""
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(presented)) == 1 {
+			matched = k
+		}
+	}
+	return matched, matched != ""
+}
+
+// apiKeyID identifies an API key in logs without revealing it: the first 12
+// hex digits of its SHA-256, as printed by
+// printf %s "$KEY" | sha256sum | cut -c1-12.
+func apiKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// clientIdentity is who made a request, as recorded in the audit log.
+// APIKeyID is empty when API_KEY is not set.
+type clientIdentity struct {
+	APIKeyID   string
+	RemoteAddr string
+}
+
+type clientIdentityKey struct{}
+
+func withClientIdentity(ctx context.Context, id clientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, id)
+}
+
+// clientIdentityFromContext returns the identity set by apiKeyMiddleware, if
+// any.
+func clientIdentityFromContext(ctx context.Context) clientIdentity {
+	id, _ := ctx.Value(clientIdentityKey{}).(clientIdentity)
+	return id
+}
+
+// corsMiddleware lets browsers on the allowed origins call the API. Preflight
+// OPTIONS requests are answered directly, and cross-origin requests from any
+// other origin are rejected with 403 rather than having their Origin
+// reflected back. With no origins configured CORS is disabled and requests
+// pass through untouched.
+func corsMiddleware(origins []string, next http.Handler) http.Handler {
+	if len(origins) == 0 {
+		return next
+	}
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowed[strings.TrimRight(o, "/")] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || sameOrigin(origin, r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !allowed[origin] {
+			writeJSONError(w, http.StatusForbidden, "origin not allowed")
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Expose-Headers", "X-Request-ID, Retry-After")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key")
+			h.Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin reports whether origin points at the host serving r, as it does
+// for the dashboard, whose POSTs carry an Origin header too.
+func sameOrigin(origin string, r *http.Request) bool {
+	_, host, ok := strings.Cut(origin, "://")
+	return ok && strings.EqualFold(host, r.Host)
+}
+
+// defaultMaxBodyBytes caps request bodies unless MAX_BODY_BYTES says
+// otherwise.
+const defaultMaxBodyBytes = 1 << 20
+
+// bodyLimitMiddleware caps every request body at limit bytes. Reading past
+// the limit fails with *http.MaxBytesError, which writeError turns into 413.
+func bodyLimitMiddleware(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// defaultGzipMinBytes is the smallest response body gzipMiddleware
+// compresses unless GZIP_MIN_BYTES says otherwise; below it the overhead
+// isn't worth it.
+const defaultGzipMinBytes = 1024
+
+// gzipMiddleware gzips responses for clients that accept it once the body
+// reaches minBytes. Smaller bodies, Server-Sent Events, range requests and
+// responses that are already encoded are passed through unchanged.
+func gzipMiddleware(minBytes int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, minBytes: minBytes, status: http.StatusOK}
+		defer gw.finish()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		q := strings.ReplaceAll(strings.TrimSpace(params), " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it knows
+// whether to compress it: the body reaching minBytes switches to gzip, while
+// the handler finishing or flushing first sends it as is.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minBytes int
+	status   int
+
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.decided {
+		return
+	}
+	g.status = code
+	h := g.Header()
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified ||
+		h.Get("Content-Encoding") != "" ||
+		strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") {
+		g.passThrough()
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !g.decided {
+		g.WriteHeader(g.status)
+	}
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= g.minBytes {
+		g.startGzip()
+		if _, err := g.gz.Write(g.buf); err != nil {
+			return 0, err
+		}
+		g.buf = nil
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been buffered so far. A handler that flushes is
+// streaming, so an undecided response is sent uncompressed.
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		g.passThrough()
+	}
+	if g.gz != nil {
+		_ = g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+func (g *gzipResponseWriter) startGzip() {
+	g.decided = true
+	h := g.Header()
+	if h.Get("Content-Type") == "" {
+		// Sniff from the plain bytes; the server would see gzip otherwise.
+		h.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	g.ResponseWriter.WriteHeader(g.status)
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+}
+
+// passThrough sends the status and any buffered body uncompressed.
+func (g *gzipResponseWriter) passThrough() {
+	g.decided = true
+	g.ResponseWriter.WriteHeader(g.status)
+	if len(g.buf) > 0 {
+		_, _ = g.ResponseWriter.Write(g.buf)
+		g.buf = nil
+	}
+}
+
+// finish completes the response once the handler has returned.
+func (g *gzipResponseWriter) finish() {
+	if !g.decided {
+		g.passThrough()
+	}
+	if g.gz != nil {
+		_ = g.gz.Close()
+	}
+}
+
+// inFlightCounter tracks how many requests are currently being served so
+// shutdown can report how many were drained.
+type inFlightCounter struct {
+	n atomic.Int64
+}
+
+func (c *inFlightCounter) count() int64 {
+	return c.n.Load()
+}
+
+func (c *inFlightCounter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.n.Add(1)
+		defer c.n.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+type requestIDKey struct{}
+
+// requestIDFromContext returns the ID assigned by loggingMiddleware, if any.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Flush lets streaming handlers flush through the recorder.
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// loggingMiddleware assigns every request an ID, echoes it in the
+// X-Request-ID response header and logs one structured line per request
+// with method, path, remote address, status and duration.
+func loggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := newRequestID()
+
+		w.Header().Set("X-Request-ID", id)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+
+		next.ServeHTTP(rec, r)
+
+		logger.Info("request",
+			"request_id", id,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"remote_addr", r.RemoteAddr,
+			"status", rec.status,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	})
+}
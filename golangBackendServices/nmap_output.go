@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nmapFileFormats maps the output formats nmap can write to disk to their
+// flag and the file name used inside a scan's output directory.
+var nmapFileFormats = map[string]struct {
+	flag     string
+	fileName string
+}{
+	"xml":       {flag: "-oX", fileName: "scan.xml"},
+	"normal":    {flag: "-oN", fileName: "scan.nmap"},
+	"greppable": {flag: "-oG", fileName: "scan.gnmap"},
+}
+
+// normalizeOutputFormats validates and dedupes the requested file output
+// formats while preserving their order. "all" expands to every supported
+// format, matching nmap's -oA.
+func normalizeOutputFormats(formats []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+
+	for _, f := range formats {
+		f = strings.ToLower(strings.TrimSpace(f))
+		switch {
+		case f == "":
+			continue
+		case f == "all":
+			add("xml")
+			add("normal")
+			add("greppable")
+		case nmapFileFormats[f].flag != "":
+			add(f)
+		default:
+			return nil, fmt.Errorf("invalid output format %q. Must be one of: xml, normal, greppable, all", f)
+		}
+	}
+	return out, nil
+}
+
+// inlineOutputFormats resolves the single output_format of a scan request
+// (xml, normal, greppable or all), whose files are returned in the response
+// body rather than kept for download. It returns nil for unknown formats.
+func inlineOutputFormats(format string) []string {
+	if strings.TrimSpace(format) == "" {
+		return nil
+	}
+	formats, err := normalizeOutputFormats([]string{format})
+	if err != nil {
+		return nil
+	}
+	return formats
+}
+
+// workDirOutputFormats returns the formats a scan writes into its temporary
+// directory: the inline output_format files plus, unless they already go to
+// the output store or inline, an XML copy from which the structured host
+// results are parsed.
+func workDirOutputFormats(req scanRequest) []string {
+	formats := inlineOutputFormats(req.OutputFormat)
+	if !slices.Contains(formats, "xml") && !slices.Contains(req.OutputFormats, "xml") {
+		formats = append(formats, "xml")
+	}
+	return formats
+}
+
+// readOutputFiles reads back the files nmap wrote into dir, keyed by format.
+// Formats whose file is missing are left out.
+func readOutputFiles(dir string, formats []string) map[string]string {
+	contents := make(map[string]string, len(formats))
+	for _, f := range formats {
+		data, err := os.ReadFile(filepath.Join(dir, nmapFileFormats[f].fileName))
+		if err != nil {
+			continue
+		}
+		contents[f] = string(data)
+	}
+	return contents
+}
+
+// scanWorkDir is where scans create their temporary output directories. It
+// is set from SCAN_WORK_DIR; empty means the system temp directory.
+var scanWorkDir string
+
+// prepareWorkDir creates dir if needed and checks that files can be created
+// in it, so a read-only or missing scratch directory is reported at startup
+// rather than halfway through a scan. An empty dir checks the system temp
+// directory.
+func prepareWorkDir(dir string) error {
+	name := dir
+	if name == "" {
+		name = os.TempDir()
+	} else if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("cannot create scan work directory %s: %w", dir, err)
+	}
+
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("scan work directory %s is not writable: %w", name, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// scanOutput is a directory of nmap output files for a single scan.
+type scanOutput struct {
+	dir       string
+	formats   []string
+	createdAt time.Time
+}
+
+// scanOutputStore keeps track of on-disk nmap output files so they can be
+// downloaded after the scan and removed once they are older than ttl.
+type scanOutputStore struct {
+	mu      sync.Mutex
+	outputs map[string]*scanOutput
+	ttl     time.Duration
+}
+
+func newScanOutputStore(ttl time.Duration) *scanOutputStore {
+	return &scanOutputStore{
+		outputs: make(map[string]*scanOutput),
+		ttl:     ttl,
+	}
+}
+
+// prepare creates an output directory for the given formats and returns its
+// ID together with the nmap flags that write each format into it.
+func (s *scanOutputStore) prepare(formats []string) (string, []string, error) {
+	dir, err := os.MkdirTemp(scanWorkDir, "nmap-scan-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	args := outputFileArgs(dir, formats)
+
+	id := newJobID()
+	s.mu.Lock()
+	s.outputs[id] = &scanOutput{
+		dir:       dir,
+		formats:   formats,
+		createdAt: time.Now(),
+	}
+	s.mu.Unlock()
+
+	return id, args, nil
+}
+
+// outputFileArgs returns the nmap flags that write each format into dir.
+func outputFileArgs(dir string, formats []string) []string {
+	var args []string
+	for _, f := range formats {
+		spec := nmapFileFormats[f]
+		args = append(args, spec.flag, filepath.Join(dir, spec.fileName))
+	}
+	return args
+}
+
+// links returns the download URL of every format produced for id.
+func (s *scanOutputStore) links(id string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out, ok := s.outputs[id]
+	if !ok {
+		return nil
+	}
+
+	links := make(map[string]string, len(out.formats))
+	for _, f := range out.formats {
+		if _, err := os.Stat(filepath.Join(out.dir, nmapFileFormats[f].fileName)); err != nil {
+			continue
+		}
+		links[f] = "/scan-output?" + url.Values{"id": {id}, "format": {f}}.Encode()
+	}
+	return links
+}
+
+// path resolves the file for a given output ID and format.
+func (s *scanOutputStore) path(id, format string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out, ok := s.outputs[id]
+	if !ok {
+		return "", false
+	}
+	for _, f := range out.formats {
+		if f == format {
+			return filepath.Join(out.dir, nmapFileFormats[f].fileName), true
+		}
+	}
+	return "", false
+}
+
+// cleanup deletes output directories older than the store TTL.
+func (s *scanOutputStore) cleanup() {
+	cutoff := time.Now().Add(-s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, out := range s.outputs {
+		if out.createdAt.Before(cutoff) {
+			if err := os.RemoveAll(out.dir); err != nil {
+				log.Printf("failed to remove scan output %s: %v", out.dir, err)
+				continue
+			}
+			delete(s.outputs, id)
+		}
+	}
+}
+
+// runCleanup periodically expires old output files until ctx is cancelled.
+func (s *scanOutputStore) runCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.cleanup()
+		}
+	}
+}
+
+// scanOutputHandler serves a stored nmap output file by ID and format.
+func scanOutputHandler(outputs *scanOutputStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		id := strings.TrimSpace(r.URL.Query().Get("id"))
+		format := strings.TrimSpace(r.URL.Query().Get("format"))
+		if id == "" || format == "" {
+			writeJSONError(w, http.StatusBadRequest, "id and format are required")
+			return
+		}
+
+		path, ok := outputs.path(id, format)
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "scan output not found")
+			return
+		}
+
+		if format == "xml" {
+			w.Header().Set("Content-Type", "application/xml")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(path)))
+		http.ServeFile(w, r, path)
+	})
+}
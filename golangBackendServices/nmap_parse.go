@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// scanReportLine matches the per-host header in nmap's normal output, e.g.
+// "Nmap scan report for example.com (93.184.216.34)" or
+// "Nmap scan report for 10.0.0.1".
+var scanReportLine = regexp.MustCompile(`^Nmap scan report for (?:(\S+) \(([^)]+)\)|(\S+))\s*$`)
+
+// portLine matches a row of the port table in nmap's normal output, e.g.
+// "22/tcp   open  ssh     OpenSSH 8.9p1".
+var portLine = regexp.MustCompile(`^(\d+)/(tcp|udp|sctp)\s+(\S+)(?:\s+(\S+))?(?:\s+(.*))?$`)
+
+// osGuess matches one entry of the "Aggressive OS guesses:" line of nmap's
+// normal output, e.g. "Linux 4.15 - 5.6 (96%)".
+var osGuess = regexp.MustCompile(`\s*([^,][^(]*?) \((\d+)%\)`)
+
+// parseScannedAddresses returns the addresses nmap reported on, in order and
+// without duplicates. With --resolve-all this lists every A/AAAA record of a
+// hostname rather than just the first one.
+func parseScannedAddresses(output string) []string {
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, line := range strings.Split(output, "\n") {
+		m := scanReportLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		addr := m[2]
+		if addr == "" {
+			addr = m[3]
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// portResult is one row of a host's port table.
+type portResult struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	State    string `json:"state"`
+	Service  string `json:"service,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+// hostReport is the part of nmap's normal output about a single host.
+type hostReport struct {
+	// Host is the name the host was scanned as; for IP targets it equals
+	// Address.
+	Host    string       `json:"host"`
+	Address string       `json:"address"`
+	Ports   []portResult `json:"ports,omitempty"`
+	// OSMatches are the OS guesses printed with -O.
+	OSMatches []osMatch `json:"os_matches,omitempty"`
+}
+
+// parseHostReports splits nmap's normal output into one report per scanned
+// host, with the rows of its port table and its OS guesses.
+func parseHostReports(output string) []hostReport {
+	var hosts []hostReport
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if m := scanReportLine.FindStringSubmatch(line); m != nil {
+			h := hostReport{Host: m[1], Address: m[2]}
+			if h.Address == "" {
+				h.Host, h.Address = m[3], m[3]
+			}
+			hosts = append(hosts, h)
+			continue
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+		if details, ok := strings.CutPrefix(line, "OS details: "); ok {
+			// nmap only prints OS details for exact matches.
+			h := &hosts[len(hosts)-1]
+			for _, name := range strings.Split(details, ", ") {
+				h.OSMatches = append(h.OSMatches, osMatch{Name: name, Accuracy: 100})
+			}
+			continue
+		}
+		if guesses, ok := strings.CutPrefix(line, "Aggressive OS guesses: "); ok {
+			h := &hosts[len(hosts)-1]
+			for _, g := range osGuess.FindAllStringSubmatch(guesses, -1) {
+				accuracy, _ := strconv.Atoi(g[2])
+				h.OSMatches = append(h.OSMatches, osMatch{Name: g[1], Accuracy: accuracy})
+			}
+			continue
+		}
+		if m := portLine.FindStringSubmatch(line); m != nil {
+			port, err := strconv.Atoi(m[1])
+			if err != nil {
+				continue
+			}
+			h := &hosts[len(hosts)-1]
+			h.Ports = append(h.Ports, portResult{
+				Port:     port,
+				Protocol: m[2],
+				State:    m[3],
+				Service:  m[4],
+				Version:  strings.TrimSpace(m[5]),
+			})
+		}
+	}
+	return hosts
+}
+
+// parseNmapWarnings picks out the non-fatal problems nmap reports in its
+// output: explicit warnings, unresolvable targets and scans where no host
+// answered.
+func parseNmapWarnings(output string) []string {
+	var out []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		lower := strings.ToLower(line)
+		switch {
+		case strings.HasPrefix(lower, "warning:"):
+			out = append(out, "nmap: "+line)
+		case strings.HasPrefix(lower, "failed to resolve"):
+			out = append(out, "target skipped: "+line)
+		case strings.HasPrefix(line, "Nmap done:") && strings.Contains(line, "(0 hosts up)"):
+			out = append(out, "no hosts responded; the target may be down or blocking probes")
+		}
+	}
+	return out
+}
+
+// scriptArgKeyPattern matches NSE script argument names such as
+// "http.useragent" or "userdb".
+var scriptArgKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.\-]*$`)
+
+// formatScriptArgs serializes NSE script arguments into the key=value,...
+// form --script-args expects. Keys are sorted for a stable command line.
+// Values containing separators, quotes or whitespace are double-quoted with
+// embedded quotes and backslashes escaped, as nmap's argument parser
+// requires.
+func formatScriptArgs(args map[string]string) (string, error) {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		if !scriptArgKeyPattern.MatchString(k) {
+			return "", fmt.Errorf("invalid script_args key %q", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := args[k]
+		if strings.ContainsAny(v, "\x00\r\n") {
+			return "", fmt.Errorf("invalid script_args value for %q: must not contain control characters", k)
+		}
+		if strings.ContainsAny(v, ",=\"'{} \t\\") {
+			v = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+		}
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, ","), nil
+}
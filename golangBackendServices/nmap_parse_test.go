@@ -0,0 +1,51 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseScannedAddresses(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name: "resolve all",
+			output: `Starting Nmap 7.94 ( https://nmap.org ) at 2024-05-01 10:00 UTC
+Nmap scan report for example.com (93.184.216.34)
+Host is up (0.012s latency).
+Nmap scan report for example.com (93.184.216.35)
+Host is up (0.013s latency).
+Nmap done: 2 IP addresses (2 hosts up) scanned in 1.20 seconds`,
+			want: []string{"93.184.216.34", "93.184.216.35"},
+		},
+		{
+			name: "bare addresses and repeats",
+			output: `Nmap scan report for 10.0.0.1
+Nmap scan report for 10.0.0.10
+Nmap scan report for scanme.nmap.org (45.33.32.156)
+Nmap scan report for 10.0.0.1`,
+			want: []string{"10.0.0.1", "10.0.0.10", "45.33.32.156"},
+		},
+		{
+			name:   "ipv6",
+			output: "Nmap scan report for scanme.nmap.org (2600:3c01::f03c:91ff:fe18:bb2f)\n",
+			want:   []string{"2600:3c01::f03c:91ff:fe18:bb2f"},
+		},
+		{
+			name:   "no hosts up",
+			output: "Nmap done: 1 IP address (0 hosts up) scanned in 3.04 seconds\n",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseScannedAddresses(tt.output); !slices.Equal(got, tt.want) {
+				t.Errorf("parseScannedAddresses = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
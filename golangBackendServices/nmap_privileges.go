@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// nmapPath is the nmap binary to run, from NMAP_PATH.
+var nmapPath = "nmap"
+
+// nmapPrivileged reports whether nmap may use raw sockets. It is true when
+// running as root, or when NMAP_PRIVILEGED says the binary has the needed
+// capabilities (e.g. via setcap), in which case --privileged is passed.
+var nmapPrivileged = os.Geteuid() == 0
+
+// privilegedNmapFlags are options that need raw socket access. Without it
+// nmap either falls back to a connect scan or refuses to run.
+var privilegedNmapFlags = map[string]string{
+	"-sS":           "tcp_syn",
+	"-sU":           "udp",
+	"-sA":           "tcp_ack",
+	"-sF":           "tcp_fin",
+	"-sN":           "tcp_null",
+	"-sX":           "tcp_xmas",
+	"-O":            "os_detection",
+	"-A":            "aggressive",
+	"--traceroute":  "traceroute",
+	"-f":            "stealth_options.fragment",
+	"-D":            "stealth_options.decoys",
+	"--data-length": "stealth_options.data_length",
+	"--spoof-mac":   "stealth_options.spoof_mac",
+}
+
+// checkNmapPrivileges rejects arguments that need raw sockets when nmap
+// isn't privileged, instead of letting nmap silently fall back to a connect
+// scan or fail with a permission error buried in its output.
+func checkNmapPrivileges(args []string) error {
+	if nmapPrivileged {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var options []string
+	for _, a := range args {
+		if name, ok := privilegedNmapFlags[a]; ok && !seen[name] {
+			seen[name] = true
+			options = append(options, name)
+		}
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	sort.Strings(options)
+	return fmt.Errorf("%s requires root privileges, which this server does not have; nmap would fall back to a connect scan (tcp_connect) or fail. Use tcp_connect without OS detection or traceroute, or run the server as root", strings.Join(options, ", "))
+}
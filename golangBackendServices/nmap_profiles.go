@@ -0,0 +1,55 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// scanProfiles are named presets for common flag combinations. A profile
+// only fills in fields the request leaves unset, so explicit fields always
+// win.
+var scanProfiles = map[string]scanRequest{
+	// -T4 -F
+	"quick": {Timing: "T4", TopPorts: 100},
+	// -T4 -A -p-
+	"thorough": {Timing: "T4", Aggressive: true, Ports: "1-65535"},
+	// -sS -T2 -f
+	"stealth": {Timing: "T2", ScanType: "tcp_syn", StealthOptions: &stealthOptions{Fragment: true}},
+	// --script vuln
+	"vuln": {Scripts: "vuln"},
+}
+
+// scanProfileNames lists the known profiles for error messages.
+func scanProfileNames() string {
+	names := make([]string, 0, len(scanProfiles))
+	for name := range scanProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// applyScanProfile fills the unset fields of req from profile p. A port
+// selection in the request replaces the profile's, whether it uses ports or
+// top_ports, and a ping scan takes no ports at all.
+func applyScanProfile(req scanRequest, p scanRequest) scanRequest {
+	if req.Timing == "" {
+		req.Timing = p.Timing
+	}
+	if req.ScanType == "" {
+		req.ScanType = p.ScanType
+	}
+	if req.Ports == "" && req.TopPorts == 0 && req.ScanType != "ping" {
+		req.Ports = p.Ports
+		req.TopPorts = p.TopPorts
+	}
+	if req.Scripts == "" {
+		req.Scripts = p.Scripts
+	}
+	req.Aggressive = req.Aggressive || p.Aggressive
+	if req.StealthOptions == nil && p.StealthOptions != nil {
+		opts := *p.StealthOptions
+		req.StealthOptions = &opts
+	}
+	return req
+}
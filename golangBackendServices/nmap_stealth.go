@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxDataLength is the largest --data-length nmap accepts.
+const maxDataLength = 1400
+
+// stealthOptions are the evasion options of a scan request. Each of them
+// shapes raw packets, so they all need root (or NMAP_PRIVILEGED); without it
+// the request is rejected by checkNmapPrivileges.
+type stealthOptions struct {
+	// Fragment splits probes into 8-byte IP fragments (-f).
+	Fragment bool `json:"fragment,omitempty"`
+	// Decoys is a comma-separated list of decoy addresses (-D). ME marks
+	// the position of the real source, and RND or RND:N adds random ones.
+	Decoys string `json:"decoys,omitempty"`
+	// DataLength appends that many random bytes to each probe
+	// (--data-length, 0-1400).
+	DataLength int `json:"data_length,omitempty"`
+	// SpoofMAC sends probes from another MAC address (--spoof-mac): a full
+	// address, a vendor name such as "Cisco", or "0" for a random one. It
+	// only has an effect on the local Ethernet segment.
+	SpoofMAC string `json:"spoof_mac,omitempty"`
+}
+
+var (
+	randomDecoyPattern = regexp.MustCompile(`^RND(:[0-9]+)?$`)
+	macVendorPattern   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+)
+
+// decoyList returns the decoys with blanks removed.
+func (o stealthOptions) decoyList() []string {
+	var decoys []string
+	for _, d := range strings.Split(o.Decoys, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			decoys = append(decoys, d)
+		}
+	}
+	return decoys
+}
+
+// problems lists everything wrong with o.
+func (o stealthOptions) problems() []string {
+	var problems []string
+	for _, d := range o.decoyList() {
+		if d == "ME" || randomDecoyPattern.MatchString(d) || net.ParseIP(d) != nil {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("invalid decoy %q: must be an IP address, ME, RND or RND:<count>", d))
+	}
+	if o.DataLength < 0 || o.DataLength > maxDataLength {
+		problems = append(problems, fmt.Sprintf("data_length must be between 0 and %d", maxDataLength))
+	}
+	if mac := strings.TrimSpace(o.SpoofMAC); mac != "" && mac != "0" && !macVendorPattern.MatchString(mac) {
+		if hw, err := net.ParseMAC(mac); err != nil || len(hw) != 6 {
+			problems = append(problems, fmt.Sprintf("invalid spoof_mac %q: must be a MAC address, a vendor name or 0 for a random one", mac))
+		}
+	}
+	return problems
+}
+
+// args returns the nmap arguments for o.
+func (o stealthOptions) args() []string {
+	var args []string
+	if o.Fragment {
+		args = append(args, "-f")
+	}
+	if decoys := o.decoyList(); len(decoys) > 0 {
+		args = append(args, "-D", strings.Join(decoys, ","))
+	}
+	if o.DataLength > 0 {
+		args = append(args, "--data-length", strconv.Itoa(o.DataLength))
+	}
+	if mac := strings.TrimSpace(o.SpoofMAC); mac != "" {
+		args = append(args, "--spoof-mac", mac)
+	}
+	return args
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var nmapVersionPattern = regexp.MustCompile(`(?m)^Nmap version (\S+)`)
+
+// nmapVersionInfo describes the installed nmap binary.
+type nmapVersionInfo struct {
+	Version          string   `json:"version"`
+	Platform         string   `json:"platform,omitempty"`
+	CompiledWith     []string `json:"compiled_with"`
+	CompiledWithout  []string `json:"compiled_without"`
+	NsockEngines     []string `json:"nsock_engines,omitempty"`
+	NSEAvailable     bool     `json:"nse_available"`
+	IPv6Available    bool     `json:"ipv6_available"`
+	OpenSSLAvailable bool     `json:"openssl_available"`
+	LibpcapAvailable bool     `json:"libpcap_available"`
+	Raw              string   `json:"raw"`
+}
+
+// parseNmapVersion parses the output of `nmap --version`.
+func parseNmapVersion(out string) (nmapVersionInfo, error) {
+	m := nmapVersionPattern.FindStringSubmatch(out)
+	if m == nil {
+		return nmapVersionInfo{}, fmt.Errorf("unrecognized nmap --version output")
+	}
+
+	info := nmapVersionInfo{
+		Version:         m[1],
+		CompiledWith:    []string{},
+		CompiledWithout: []string{},
+		Raw:             out,
+	}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Platform":
+			info.Platform = value
+		case "Compiled with":
+			info.CompiledWith = append(info.CompiledWith, strings.Fields(value)...)
+		case "Compiled without":
+			info.CompiledWithout = append(info.CompiledWithout, strings.Fields(value)...)
+		case "Available nsock engines":
+			info.NsockEngines = strings.Fields(value)
+		}
+	}
+
+	for _, lib := range info.CompiledWith {
+		switch {
+		case strings.HasPrefix(lib, "liblua"), strings.HasPrefix(lib, "nmap-liblua"):
+			info.NSEAvailable = true
+		case lib == "ipv6":
+			info.IPv6Available = true
+		case strings.HasPrefix(lib, "openssl"):
+			info.OpenSSLAvailable = true
+		case strings.HasPrefix(lib, "libpcap"):
+			info.LibpcapAvailable = true
+		}
+	}
+	return info, nil
+}
+
+// nmapVersionCache runs `nmap --version` once and remembers the result,
+// since the binary doesn't change while the server is running. Failures are
+// not cached so a later install of nmap is picked up.
+type nmapVersionCache struct {
+	mu   sync.Mutex
+	info *nmapVersionInfo
+}
+
+func (c *nmapVersionCache) get(ctx context.Context) (nmapVersionInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.info != nil {
+		return *c.info, nil
+	}
+
+	out, err := exec.CommandContext(ctx, nmapPath, "--version").CombinedOutput()
+	if err != nil {
+		return nmapVersionInfo{}, fmt.Errorf("nmap --version failed: %w; output: %s", err, string(out))
+	}
+	info, err := parseNmapVersion(string(out))
+	if err != nil {
+		return nmapVersionInfo{}, err
+	}
+	c.info = &info
+	return info, nil
+}
+
+// nmapVersionHandler reports the installed nmap version and its compiled-in
+// features, so callers can tell whether e.g. NSE scripts are supported.
+func nmapVersionHandler(cache *nmapVersionCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		info, err := cache.get(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to get nmap version", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(info); err != nil {
+			log.Printf("failed to encode nmap version response: %v", err)
+		}
+	})
+}
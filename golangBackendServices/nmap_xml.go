@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nmapRun is the root of nmap's XML output (-oX).
+type nmapRun struct {
+	XMLName xml.Name   `xml:"nmaprun"`
+	Hosts   []nmapHost `xml:"host"`
+}
+
+type nmapHost struct {
+	Status    nmapHostStatus `xml:"status"`
+	Addresses []nmapAddress  `xml:"address"`
+	Hostnames []nmapHostname `xml:"hostnames>hostname"`
+	Ports     []nmapPort     `xml:"ports>port"`
+	OSMatches []nmapOSMatch  `xml:"os>osmatch"`
+	Hops      []nmapHop      `xml:"trace>hop"`
+}
+
+// nmapHop is one hop of a --traceroute trace. RTT is in milliseconds and
+// empty for hops that didn't answer.
+type nmapHop struct {
+	TTL    int    `xml:"ttl,attr"`
+	IPAddr string `xml:"ipaddr,attr"`
+	RTT    string `xml:"rtt,attr"`
+	Host   string `xml:"host,attr"`
+}
+
+type nmapPort struct {
+	Protocol string `xml:"protocol,attr"`
+	PortID   int    `xml:"portid,attr"`
+	State    struct {
+		State string `xml:"state,attr"`
+	} `xml:"state"`
+	Service nmapPortService `xml:"service"`
+}
+
+type nmapPortService struct {
+	Name      string `xml:"name,attr"`
+	Product   string `xml:"product,attr"`
+	Version   string `xml:"version,attr"`
+	ExtraInfo string `xml:"extrainfo,attr"`
+}
+
+type nmapOSMatch struct {
+	Name     string `xml:"name,attr"`
+	Accuracy int    `xml:"accuracy,attr"`
+}
+
+type nmapHostStatus struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+	Vendor   string `xml:"vendor,attr"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// parseNmapXML decodes nmap's XML output.
+func parseNmapXML(data string) (nmapRun, error) {
+	var run nmapRun
+	if err := xml.Unmarshal([]byte(data), &run); err != nil {
+		return run, fmt.Errorf("failed to parse nmap XML output: %w", err)
+	}
+	return run, nil
+}
+
+// ipAddress returns the host's IPv4 or IPv6 address.
+func (h nmapHost) ipAddress() string {
+	for _, a := range h.Addresses {
+		if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+			return a.Addr
+		}
+	}
+	return ""
+}
+
+// macAddress returns the host's MAC address and the vendor nmap derived from
+// it. nmap only sees MACs on the local network segment when run as root.
+func (h nmapHost) macAddress() (mac, vendor string) {
+	for _, a := range h.Addresses {
+		if a.AddrType == "mac" {
+			return a.Addr, a.Vendor
+		}
+	}
+	return "", ""
+}
+
+// hostname returns the first name nmap knows the host by, preferring the
+// name it was given on the command line over the reverse DNS name.
+func (h nmapHost) hostname() string {
+	for _, n := range h.Hostnames {
+		if n.Type == "user" {
+			return n.Name
+		}
+	}
+	if len(h.Hostnames) > 0 {
+		return h.Hostnames[0].Name
+	}
+	return ""
+}
+
+// HostResult is everything a scan found out about one host, taken from
+// nmap's XML output.
+type HostResult struct {
+	Address   string       `json:"address"`
+	Hostnames []string     `json:"hostnames,omitempty"`
+	Status    string       `json:"status"`
+	Ports     []portResult `json:"ports,omitempty"`
+	// OSMatches are nmap's OS guesses; only present with OS detection.
+	OSMatches []osMatch `json:"os_matches,omitempty"`
+	// Route is the network path to the host; only present with
+	// traceroute, and empty when the trace produced no hops.
+	Route []routeHop `json:"route,omitempty"`
+}
+
+// routeHop is one hop of a traceroute. RTT is in milliseconds and nil when
+// the hop didn't answer in time.
+type routeHop struct {
+	TTL    int      `json:"ttl"`
+	IPAddr string   `json:"ipaddr,omitempty"`
+	RTT    *float64 `json:"rtt,omitempty"`
+	Host   string   `json:"host,omitempty"`
+}
+
+// osMatch is one OS guess and how confident nmap is in it, in percent.
+type osMatch struct {
+	Name     string `json:"name"`
+	Accuracy int    `json:"accuracy"`
+}
+
+// sortOSMatches orders OS guesses most accurate first, keeping nmap's order
+// among equally accurate ones.
+func sortOSMatches(matches []osMatch) {
+	sort.SliceStable(matches, func(i, k int) bool {
+		return matches[i].Accuracy > matches[k].Accuracy
+	})
+}
+
+// hostResults converts the hosts of an nmap run into HostResults.
+func hostResults(run nmapRun) []HostResult {
+	results := make([]HostResult, 0, len(run.Hosts))
+	for _, h := range run.Hosts {
+		r := HostResult{Address: h.ipAddress(), Status: h.Status.State}
+		for _, n := range h.Hostnames {
+			if n.Name != "" && !slices.Contains(r.Hostnames, n.Name) {
+				r.Hostnames = append(r.Hostnames, n.Name)
+			}
+		}
+		for _, p := range h.Ports {
+			r.Ports = append(r.Ports, portResult{
+				Port:     p.PortID,
+				Protocol: p.Protocol,
+				State:    p.State.State,
+				Service:  p.Service.Name,
+				Version:  p.Service.versionString(),
+			})
+		}
+		for _, m := range h.OSMatches {
+			r.OSMatches = append(r.OSMatches, osMatch{Name: m.Name, Accuracy: m.Accuracy})
+		}
+		sortOSMatches(r.OSMatches)
+		for _, hop := range h.Hops {
+			rh := routeHop{TTL: hop.TTL, IPAddr: hop.IPAddr, Host: hop.Host}
+			if rtt, err := strconv.ParseFloat(hop.RTT, 64); err == nil {
+				rh.RTT = &rtt
+			}
+			r.Route = append(r.Route, rh)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// versionString joins product, version and extra info the way the VERSION
+// column of nmap's normal output does, e.g. "OpenSSH 8.9p1 (protocol 2.0)".
+func (s nmapPortService) versionString() string {
+	parts := make([]string, 0, 3)
+	for _, p := range []string{s.Product, s.Version} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if s.ExtraInfo != "" {
+		parts = append(parts, "("+s.ExtraInfo+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// readHostResults parses the XML nmap wrote to xmlPath, falling back to its
+// normal output when the file is missing or unreadable, e.g. because nmap
+// failed early.
+func readHostResults(xmlPath, rawOutput string) []HostResult {
+	if data, err := os.ReadFile(xmlPath); err == nil {
+		if run, err := parseNmapXML(string(data)); err == nil {
+			return hostResults(run)
+		}
+	}
+	return hostResultsFromText(rawOutput)
+}
+
+// hostResultsFromText builds HostResults from nmap's normal output, for when
+// no XML output is available. It only knows about hosts that were up, their
+// ports and OS guesses.
+func hostResultsFromText(output string) []HostResult {
+	var results []HostResult
+	for _, h := range parseHostReports(output) {
+		r := HostResult{Address: h.Address, Status: "up", Ports: h.Ports, OSMatches: h.OSMatches}
+		sortOSMatches(r.OSMatches)
+		if h.Host != h.Address {
+			r.Hostnames = []string{h.Host}
+		}
+		results = append(results, r)
+	}
+	return results
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrScriptNotAllowed is returned when a scan asks for an NSE script that
+// isn't on the configured allowlist.
+var ErrScriptNotAllowed = errors.New("script not allowed")
+
+// scriptAllowlist restricts which NSE scripts and categories scans may run.
+// It is nil, allowing everything, unless NSE_SCRIPT_ALLOWLIST or
+// NSE_SCRIPT_ALLOWLIST_FILE is set.
+var scriptAllowlist *nseAllowlist
+
+// nseAllowlist holds permitted script names, categories (safe, discovery,
+// ...) and shell-style patterns such as http-*.
+type nseAllowlist struct {
+	entries []string
+}
+
+// loadScriptAllowlist builds the allowlist from a comma-separated list and
+// an optional file with one entry per line ('#' starts a comment). It
+// returns nil when neither yields any entry.
+func loadScriptAllowlist(list []string, file string) (*nseAllowlist, error) {
+	entries := append([]string(nil), list...)
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read NSE script allowlist: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+			for _, e := range strings.Split(line, ",") {
+				if e = strings.TrimSpace(e); e != "" {
+					entries = append(entries, e)
+				}
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	for _, e := range entries {
+		if _, err := path.Match(e, ""); err != nil {
+			return nil, fmt.Errorf("invalid NSE script allowlist pattern %q: %w", e, err)
+		}
+	}
+	return &nseAllowlist{entries: entries}, nil
+}
+
+// allows reports whether a single script name, category or pattern is
+// permitted.
+func (a *nseAllowlist) allows(script string) bool {
+	for _, e := range a.entries {
+		if ok, _ := path.Match(e, script); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// check validates a --script value against the allowlist. Boolean
+// expressions such as "default and not intrusive" can't be checked reliably
+// and are rejected outright.
+func (a *nseAllowlist) check(scripts string) error {
+	if a == nil {
+		return nil
+	}
+	for _, s := range strings.Split(scripts, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if strings.ContainsAny(s, " ()") {
+			return fmt.Errorf("%w: script expression %q can't be used while an allowlist is configured", ErrScriptNotAllowed, s)
+		}
+		if !a.allows(strings.TrimPrefix(s, "+")) {
+			return fmt.Errorf("%w: %q is not on the NSE script allowlist", ErrScriptNotAllowed, s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultOpenVASCacheTTL is how long rarely-changing gvmd listings (configs,
+// scanners, report formats) are served from memory.
+const defaultOpenVASCacheTTL = 5 * time.Minute
+
+// listingCache holds the result of one gvmd listing for ttl. Only successful
+// fetches are cached. A nil cache fetches on every call.
+type listingCache[T any] struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	value     T
+	fetchedAt time.Time
+}
+
+func newListingCache[T any](ttl time.Duration) *listingCache[T] {
+	return &listingCache[T]{ttl: ttl}
+}
+
+// get returns the cached value if it is younger than the TTL, otherwise it
+// calls fetch and caches the result. refresh forces a fetch. The returned
+// time is when the value was fetched from gvmd.
+func (c *listingCache[T]) get(ctx context.Context, refresh bool, fetch func(context.Context) (T, error)) (T, time.Time, error) {
+	if c == nil {
+		v, err := fetch(ctx)
+		return v, time.Now().UTC(), err
+	}
+
+	// Holding the lock across the fetch means concurrent callers share one
+	// gvm-cli call instead of each starting their own.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !refresh && !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < c.ttl {
+		return c.value, c.fetchedAt, nil
+	}
+
+	v, err := fetch(ctx)
+	if err != nil {
+		var zero T
+		return zero, time.Time{}, err
+	}
+	c.value = v
+	c.fetchedAt = time.Now().UTC()
+	return v, c.fetchedAt, nil
+}
+
+// openVASListingCaches groups the caches kept by OpenVASService.
+type openVASListingCaches struct {
+	configs       *listingCache[[]OpenVASConfig]
+	scanners      *listingCache[[]OpenVASScanner]
+	reportFormats *listingCache[[]ReportFormat]
+}
+
+func newOpenVASListingCaches(ttl time.Duration) *openVASListingCaches {
+	return &openVASListingCaches{
+		configs:       newListingCache[[]OpenVASConfig](ttl),
+		scanners:      newListingCache[[]OpenVASScanner](ttl),
+		reportFormats: newListingCache[[]ReportFormat](ttl),
+	}
+}
+
+// CachedConfigs is ListConfigs served from the listing cache. refresh
+// bypasses the cache. It also returns when the configs were fetched.
+func (s *OpenVASService) CachedConfigs(ctx context.Context, refresh bool) ([]OpenVASConfig, time.Time, error) {
+	var c *listingCache[[]OpenVASConfig]
+	if s.caches != nil {
+		c = s.caches.configs
+	}
+	return c.get(ctx, refresh, s.ListConfigs)
+}
+
+// CachedScanners is GetScanners served from the listing cache.
+func (s *OpenVASService) CachedScanners(ctx context.Context, refresh bool) ([]OpenVASScanner, time.Time, error) {
+	var c *listingCache[[]OpenVASScanner]
+	if s.caches != nil {
+		c = s.caches.scanners
+	}
+	return c.get(ctx, refresh, s.GetScanners)
+}
+
+// CachedReportFormats is GetReportFormats served from the listing cache.
+func (s *OpenVASService) CachedReportFormats(ctx context.Context, refresh bool) ([]ReportFormat, time.Time, error) {
+	var c *listingCache[[]ReportFormat]
+	if s.caches != nil {
+		c = s.caches.reportFormats
+	}
+	return c.get(ctx, refresh, s.GetReportFormats)
+}
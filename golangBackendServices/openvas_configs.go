@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OpenVASConfig is a single scan configuration (profile) known to gvmd.
+type OpenVASConfig struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// internal XML structs for parsing <get_configs/> output.
+type openVASGetConfigsXML struct {
+	Configs []openVASConfigXML `xml:"config"`
+}
+
+type openVASConfigXML struct {
+	ID      string `xml:"id,attr"`
+	Name    string `xml:"name"`
+	Comment string `xml:"comment"`
+}
+
+// parseConfigs converts a raw get_configs_response into OpenVASConfig values.
+func parseConfigs(raw string) ([]OpenVASConfig, error) {
+	var parsed openVASGetConfigsXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_configs_response XML: %w", err)
+	}
+
+	configs := make([]OpenVASConfig, 0, len(parsed.Configs))
+	for _, c := range parsed.Configs {
+		configs = append(configs, OpenVASConfig{
+			ID:      c.ID,
+			Name:    strings.TrimSpace(c.Name),
+			Comment: strings.TrimSpace(c.Comment),
+		})
+	}
+	return configs, nil
+}
+
+// ConfigDetails is a scan config with the NVT families it enables and the
+// preferences it sets.
+type ConfigDetails struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Comment     string             `json:"comment,omitempty"`
+	NVTCount    int                `json:"nvt_count"`
+	Families    []ConfigFamily     `json:"families"`
+	Preferences []ConfigPreference `json:"preferences"`
+}
+
+// ConfigFamily is an NVT family enabled in a scan config. Growing families
+// pick up new NVTs from feed updates automatically.
+type ConfigFamily struct {
+	Name        string `json:"name"`
+	NVTCount    int    `json:"nvt_count"`
+	MaxNVTCount int    `json:"max_nvt_count"`
+	Growing     bool   `json:"growing"`
+}
+
+// ConfigPreference is a scanner or NVT preference of a scan config. NVT
+// preferences carry the NVT they belong to.
+type ConfigPreference struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Default string `json:"default,omitempty"`
+	NVTOID  string `json:"nvt_oid,omitempty"`
+	NVTName string `json:"nvt_name,omitempty"`
+}
+
+// internal XML structs for parsing <get_configs families='1'
+// preferences='1'/> output.
+type openVASGetConfigDetailsXML struct {
+	Configs []struct {
+		ID       string `xml:"id,attr"`
+		Name     string `xml:"name"`
+		Comment  string `xml:"comment"`
+		NVTCount string `xml:"nvt_count"`
+		Families []struct {
+			Name        string `xml:"name"`
+			NVTCount    string `xml:"nvt_count"`
+			MaxNVTCount string `xml:"max_nvt_count"`
+			Growing     string `xml:"growing"`
+		} `xml:"families>family"`
+		Preferences []struct {
+			NVT struct {
+				OID  string `xml:"oid,attr"`
+				Name string `xml:"name"`
+			} `xml:"nvt"`
+			Name    string `xml:"name"`
+			HRName  string `xml:"hr_name"`
+			Value   string `xml:"value"`
+			Default string `xml:"default"`
+		} `xml:"preferences>preference"`
+	} `xml:"config"`
+}
+
+// parseConfigDetails extracts config configID from a raw get_configs_response.
+// Families with no NVTs selected are left out, as are NVT preferences still
+// at their default; scanner preferences are always kept. The NVT count and
+// family counts are -1 in gvmd when growing, and are reported as 0 then.
+func parseConfigDetails(raw, configID string) (ConfigDetails, error) {
+	var parsed openVASGetConfigDetailsXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return ConfigDetails{}, fmt.Errorf("failed to parse get_configs_response XML: %w", err)
+	}
+
+	count := func(s string) int {
+		n, _ := strconv.Atoi(strings.TrimSpace(s))
+		return max(n, 0)
+	}
+
+	for _, c := range parsed.Configs {
+		if strings.TrimSpace(c.ID) != configID {
+			continue
+		}
+
+		details := ConfigDetails{
+			ID:          configID,
+			Name:        strings.TrimSpace(c.Name),
+			Comment:     strings.TrimSpace(c.Comment),
+			NVTCount:    count(c.NVTCount),
+			Families:    []ConfigFamily{},
+			Preferences: []ConfigPreference{},
+		}
+		for _, f := range c.Families {
+			growing := strings.TrimSpace(f.Growing) == "1"
+			nvts := count(f.NVTCount)
+			if nvts == 0 && !growing {
+				continue
+			}
+			details.Families = append(details.Families, ConfigFamily{
+				Name:        strings.TrimSpace(f.Name),
+				NVTCount:    nvts,
+				MaxNVTCount: count(f.MaxNVTCount),
+				Growing:     growing,
+			})
+		}
+		for _, p := range c.Preferences {
+			oid := strings.TrimSpace(p.NVT.OID)
+			if oid != "" && p.Value == p.Default {
+				continue
+			}
+			name := strings.TrimSpace(p.HRName)
+			if name == "" {
+				name = strings.TrimSpace(p.Name)
+			}
+			details.Preferences = append(details.Preferences, ConfigPreference{
+				Name:    name,
+				Value:   p.Value,
+				Default: p.Default,
+				NVTOID:  oid,
+				NVTName: strings.TrimSpace(p.NVT.Name),
+			})
+		}
+		return details, nil
+	}
+	return ConfigDetails{}, fmt.Errorf("%w: %s", ErrConfigNotFound, configID)
+}
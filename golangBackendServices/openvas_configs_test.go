@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfigs(t *testing.T) {
+	raw, err := os.ReadFile("testdata/get_configs_response.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configs, err := parseConfigs(string(raw))
+	if err != nil {
+		t.Fatalf("parseConfigs: %v", err)
+	}
+
+	want := []struct{ id, name string }{
+		{"d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663", "Base"},
+		{"8715c877-47a0-438d-98a3-27c7a6ab2196", "Discovery"},
+		{"daba56c8-73ec-11df-a475-002264764cea", "Full and fast"},
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("got %d configs, want %d: %+v", len(configs), len(want), configs)
+	}
+	for i, w := range want {
+		if configs[i].ID != w.id || configs[i].Name != w.name {
+			t.Errorf("config %d = %s %q, want %s %q", i, configs[i].ID, configs[i].Name, w.id, w.name)
+		}
+	}
+	if got := configs[2].Comment; got != "Most NVT's; optimized by using previously collected information. Version 20201215." {
+		t.Errorf("comment = %q", got)
+	}
+}
+
+func TestListConfigs(t *testing.T) {
+	fixture, err := filepath.Abs("testdata/get_configs_response.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := fakeGVMCLI(t, "cat '"+fixture+"'\n")
+
+	configs, err := svc.ListConfigs(context.Background())
+	if err != nil {
+		t.Fatalf("ListConfigs: %v", err)
+	}
+	var names []string
+	for _, c := range configs {
+		names = append(names, c.Name)
+	}
+	if got, want := strings.Join(names, ", "), "Base, Discovery, Full and fast"; got != want {
+		t.Errorf("config names = %s, want %s", got, want)
+	}
+}
+
+func TestParseConfigsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"truncated", `<get_configs_response status="200"><config id="daba56c8-73ec-11df-a475-002264764cea"><name>Full`},
+		{"mismatched tags", `<get_configs_response><config></name></get_configs_response>`},
+		{"empty", ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if configs, err := parseConfigs(tt.raw); err == nil {
+				t.Fatalf("parseConfigs succeeded with %+v, want an error", configs)
+			}
+		})
+	}
+}
+
+const configDetailsFixture = `<get_configs_response status="200" status_text="OK">
+  <config id="daba56c8-73ec-11df-a475-002264764cea">
+    <name>Full and fast</name>
+    <comment>Most NVTs</comment>
+    <nvt_count>-1<growing>1</growing></nvt_count>
+    <families>
+      <family><name>Brute force attacks</name><nvt_count>0</nvt_count><max_nvt_count>12</max_nvt_count><growing>0</growing></family>
+      <family><name>Web Servers</name><nvt_count>-1</nvt_count><max_nvt_count>900</max_nvt_count><growing>1</growing></family>
+      <family><name>Databases</name><nvt_count>5</nvt_count><max_nvt_count>40</max_nvt_count><growing>0</growing></family>
+    </families>
+    <preferences>
+      <preference><nvt oid=""><name></name></nvt><name>max_hosts</name><hr_name>max_hosts</hr_name><value>20</value><default>20</default></preference>
+      <preference><nvt oid="1.3.6.1.4.1.25623.1.0.100315"><name>Ping Host</name></nvt><id>1</id><hr_name>Use nmap</hr_name><name>Use nmap</name><value>no</value><default>yes</default></preference>
+      <preference><nvt oid="1.3.6.1.4.1.25623.1.0.10330"><name>Services</name></nvt><id>1</id><hr_name>Network connection timeout</hr_name><name>Network connection timeout</name><value>5</value><default>5</default></preference>
+    </preferences>
+  </config>
+</get_configs_response>`
+
+func TestParseConfigDetails(t *testing.T) {
+	got, err := parseConfigDetails(configDetailsFixture, "daba56c8-73ec-11df-a475-002264764cea")
+	if err != nil {
+		t.Fatalf("parseConfigDetails: %v", err)
+	}
+
+	want := ConfigDetails{
+		ID:       "daba56c8-73ec-11df-a475-002264764cea",
+		Name:     "Full and fast",
+		Comment:  "Most NVTs",
+		NVTCount: 0,
+		Families: []ConfigFamily{
+			{Name: "Web Servers", NVTCount: 0, MaxNVTCount: 900, Growing: true},
+			{Name: "Databases", NVTCount: 5, MaxNVTCount: 40},
+		},
+		Preferences: []ConfigPreference{
+			{Name: "max_hosts", Value: "20", Default: "20"},
+			{Name: "Use nmap", Value: "no", Default: "yes", NVTOID: "1.3.6.1.4.1.25623.1.0.100315", NVTName: "Ping Host"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseConfigDetails =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseConfigDetailsNotFound(t *testing.T) {
+	_, err := parseConfigDetails(configDetailsFixture, "d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663")
+	if !errors.Is(err, ErrConfigNotFound) {
+		t.Fatalf("err = %v, want ErrConfigNotFound", err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// DashboardTaskCounts counts tasks by state. gvmd has no "failed" status;
+// Failed counts tasks that were stopped or interrupted before finishing.
+type DashboardTaskCounts struct {
+	Total   int `json:"total"`
+	Running int `json:"running"`
+	Done    int `json:"done"`
+	Failed  int `json:"failed"`
+	New     int `json:"new"`
+}
+
+// Dashboard is a one-shot overview of the scanning environment. A section
+// that couldn't be fetched is left out and reported as a warning.
+type Dashboard struct {
+	Tasks        *DashboardTaskCounts `json:"tasks,omitempty"`
+	TargetCount  *int                 `json:"target_count,omitempty"`
+	LatestReport *ReportSummary       `json:"latest_report,omitempty"`
+	// LatestSeverity breaks down the findings of LatestReport.
+	LatestSeverity *ReportSeveritySummary `json:"latest_severity,omitempty"`
+}
+
+// countTasks tallies task statuses for the dashboard.
+func countTasks(tasks []TaskSummary) DashboardTaskCounts {
+	counts := DashboardTaskCounts{Total: len(tasks)}
+	for _, t := range tasks {
+		switch t.Status {
+		case "Running", "Requested", "Queued":
+			counts.Running++
+		case "Done":
+			counts.Done++
+		case "Stopped", "Interrupted", "Stop Requested":
+			counts.Failed++
+		case "New":
+			counts.New++
+		}
+	}
+	return counts
+}
+
+// GetDashboard gathers task counts, the number of targets and the severity
+// summary of the newest report, running the GMP calls concurrently. Failed
+// sections are reported as warnings on ctx; an error is only returned when
+// nothing could be fetched.
+func (s *OpenVASService) GetDashboard(ctx context.Context) (Dashboard, error) {
+	var (
+		dash     Dashboard
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []error
+	)
+	fail := func(section string, err error) {
+		addWarning(ctx, "could not load %s: %v", section, err)
+		mu.Lock()
+		failures = append(failures, err)
+		mu.Unlock()
+	}
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		tasks, err := s.ListTasks(ctx, "rows=-1")
+		if err != nil {
+			fail("tasks", err)
+			return
+		}
+		counts := countTasks(tasks)
+		dash.Tasks = &counts
+	}()
+	go func() {
+		defer wg.Done()
+		n, err := s.CountTargets(ctx)
+		if err != nil {
+			fail("targets", err)
+			return
+		}
+		dash.TargetCount = &n
+	}()
+	go func() {
+		defer wg.Done()
+		reports, err := s.ListReports(ctx)
+		if err != nil {
+			fail("reports", err)
+			return
+		}
+		if len(reports) == 0 {
+			return
+		}
+		dash.LatestReport = &reports[0]
+		summary, err := s.SummarizeReport(ctx, reports[0].ID, 0)
+		if err != nil {
+			fail("latest report summary", err)
+			return
+		}
+		dash.LatestSeverity = &summary
+	}()
+	wg.Wait()
+
+	// Sections are independent, so a partial dashboard is still useful;
+	// without any section the cause (e.g. gvmd being down) is the error.
+	if len(failures) > 0 && dash.Tasks == nil && dash.TargetCount == nil && dash.LatestReport == nil {
+		return Dashboard{}, errors.Join(failures...)
+	}
+	return dash, nil
+}
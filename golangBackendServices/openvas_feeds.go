@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultFeedMaxAge is how old a feed may be before it is reported as
+// stale. The Greenbone community feed is normally updated daily.
+const defaultFeedMaxAge = 7 * 24 * time.Hour
+
+// Feed statuses reported by GetFeeds.
+const (
+	FeedStatusOK           = "ok"
+	FeedStatusSyncing      = "syncing"
+	FeedStatusStale        = "stale"
+	FeedStatusNotAvailable = "not_available"
+)
+
+// Feed describes one of gvmd's data feeds (NVT, SCAP, CERT, GVMD_DATA).
+// Version is the feed's timestamp, e.g. "202410140600".
+type Feed struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Status  string `json:"status"`
+	// UpdatedAt is parsed from Version; nil when it isn't a timestamp.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Syncing   bool       `json:"syncing"`
+	Stale     bool       `json:"stale"`
+	// Error is gvmd's explanation when the feed can't be synced.
+	Error string `json:"error,omitempty"`
+}
+
+// internal XML structs for parsing <get_feeds/> output.
+type openVASGetFeedsXML struct {
+	Feeds []struct {
+		Type             string    `xml:"type"`
+		Name             string    `xml:"name"`
+		Version          string    `xml:"version"`
+		CurrentlySyncing *struct{} `xml:"currently_syncing"`
+		SyncNotAvailable *struct {
+			Error string `xml:"error"`
+		} `xml:"sync_not_available"`
+	} `xml:"feed"`
+}
+
+// feedVersionLayout is the timestamp format gvmd uses for feed versions.
+const feedVersionLayout = "200601021504"
+
+// parseFeeds converts a raw get_feeds_response into Feed values. A feed
+// whose version is older than maxAge (relative to now) is marked stale.
+func parseFeeds(raw string, maxAge time.Duration, now time.Time) ([]Feed, error) {
+	var parsed openVASGetFeedsXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_feeds_response XML: %w", err)
+	}
+
+	feeds := make([]Feed, 0, len(parsed.Feeds))
+	for _, f := range parsed.Feeds {
+		feed := Feed{
+			Type:    strings.TrimSpace(f.Type),
+			Name:    strings.TrimSpace(f.Name),
+			Version: strings.TrimSpace(f.Version),
+			Status:  FeedStatusOK,
+			Syncing: f.CurrentlySyncing != nil,
+		}
+		if t, err := time.Parse(feedVersionLayout, feed.Version); err == nil {
+			feed.UpdatedAt = &t
+			feed.Stale = maxAge > 0 && now.Sub(t) > maxAge
+		}
+
+		switch {
+		case f.SyncNotAvailable != nil:
+			feed.Status = FeedStatusNotAvailable
+			feed.Error = strings.TrimSpace(f.SyncNotAvailable.Error)
+		case feed.Syncing:
+			feed.Status = FeedStatusSyncing
+		case feed.Stale:
+			feed.Status = FeedStatusStale
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, nil
+}
@@ -1,38 +1,46 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"encoding/xml"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// openVASVersionResponse is the typed GMP version. VersionRaw is only set
+// when the caller asks for it with ?raw=true.
 type openVASVersionResponse struct {
-	VersionRaw string `json:"version_raw"`
+	OpenVASVersion
+	VersionRaw string   `json:"version_raw,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
 }
 
-// openVASConfig is the JSON representation of a single scan configuration.
-type openVASConfig struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Comment string `json:"comment,omitempty"`
+// openVASAuthCheckResponse confirms that the configured GVM credentials work.
+type openVASAuthCheckResponse struct {
+	Authenticated bool     `json:"authenticated"`
+	Username      string   `json:"username"`
+	Warnings      []string `json:"warnings,omitempty"`
 }
 
 // openVASConfigsResponse wraps all scan configurations in a stable JSON shape.
 type openVASConfigsResponse struct {
-	Configs []openVASConfig `json:"configs"`
+	Configs   []OpenVASConfig `json:"configs"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Warnings  []string        `json:"warnings,omitempty"`
 }
 
-// internal XML structs for parsing <get_configs/> output.
-type openVASGetConfigsXML struct {
-	Configs []openVASConfigXML `xml:"config"`
-}
-
-type openVASConfigXML struct {
-	ID      string `xml:"id,attr"`
-	Name    string `xml:"name"`
-	Comment string `xml:"comment"`
+// openVASScannersResponse lists the scanners available for new tasks.
+type openVASScannersResponse struct {
+	Scanners  []OpenVASScanner `json:"scanners"`
+	FetchedAt time.Time        `json:"fetched_at"`
+	Warnings  []string         `json:"warnings,omitempty"`
 }
 
 // openVASCreateTargetRequest is the JSON input for creating a new target.
@@ -40,13 +48,68 @@ type openVASCreateTargetRequest struct {
 	Name      string `json:"name"`
 	Hosts     string `json:"hosts"`
 	PortRange string `json:"port_range,omitempty"`
+	// PortListID references an existing port list instead of PortRange.
+	PortListID string `json:"port_list_id,omitempty"`
+}
+
+// openVASPortListsResponse lists the port lists known to gvmd.
+type openVASPortListsResponse struct {
+	PortLists []PortList `json:"port_lists"`
+	Warnings  []string   `json:"warnings,omitempty"`
+}
+
+// openVASCreatePortListRequest is the JSON input for creating a port list.
+type openVASCreatePortListRequest struct {
+	Name      string `json:"name"`
+	PortRange string `json:"port_range"`
+}
+
+// openVASCreatePortListResponse returns the ID of a new port list.
+type openVASCreatePortListResponse struct {
+	ID       string   `json:"id"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // openVASCreateTargetResponse is the JSON response returned when a target is
 // created or an existing matching target is reused.
 type openVASCreateTargetResponse struct {
-	ID      string `json:"id"`
-	Existed bool   `json:"existed,omitempty"`
+	ID       string   `json:"id"`
+	Existed  bool     `json:"existed,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASGetTargetRequest is the JSON input for looking up a target.
+type openVASGetTargetRequest struct {
+	TargetID string `json:"target_id"`
+}
+
+// openVASGetTargetResponse describes a target.
+type openVASGetTargetResponse struct {
+	Target
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASGetConfigRequest is the JSON input for looking up a scan config.
+type openVASGetConfigRequest struct {
+	ConfigID string `json:"config_id"`
+}
+
+// openVASGetConfigResponse describes a scan config.
+type openVASGetConfigResponse struct {
+	ConfigDetails
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASDeleteTargetRequest is the JSON input for deleting a target.
+type openVASDeleteTargetRequest struct {
+	TargetID string `json:"target_id"`
+}
+
+// openVASDeleteTargetResponse confirms that a target was deleted.
+type openVASDeleteTargetResponse struct {
+	TargetID string   `json:"target_id"`
+	Deleted  bool     `json:"deleted"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // openVASCreateTaskRequest is the JSON input for creating a new task.
@@ -54,13 +117,69 @@ type openVASCreateTaskRequest struct {
 	Name     string `json:"name"`
 	ConfigID string `json:"config_id"`
 	TargetID string `json:"target_id"`
+	// ScannerID picks the scanner (see /openvas/scanners); it defaults to
+	// the OpenVAS scanner.
+	ScannerID string `json:"scanner_id,omitempty"`
+	// ScheduleID attaches a schedule from /openvas/schedules.
+	ScheduleID string `json:"schedule_id,omitempty"`
+}
+
+// openVASCreateScheduleRequest is the JSON input for creating a schedule.
+type openVASCreateScheduleRequest struct {
+	Name      string `json:"name"`
+	ICalendar string `json:"icalendar"`
+}
+
+// openVASCreateScheduleResponse returns the ID of a new schedule.
+type openVASCreateScheduleResponse struct {
+	ID       string   `json:"id"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // openVASCreateTaskResponse is the JSON response returned when a task is
 // created or an existing matching task is reused.
 type openVASCreateTaskResponse struct {
-	ID      string `json:"id"`
-	Existed bool   `json:"existed,omitempty"`
+	ID       string   `json:"id"`
+	Existed  bool     `json:"existed,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASModifyTaskRequest is a partial update of an existing task; omitted
+// fields are left unchanged.
+type openVASModifyTaskRequest struct {
+	TaskID     string `json:"task_id"`
+	Name       string `json:"name,omitempty"`
+	ConfigID   string `json:"config_id,omitempty"`
+	TargetID   string `json:"target_id,omitempty"`
+	ScannerID  string `json:"scanner_id,omitempty"`
+	ScheduleID string `json:"schedule_id,omitempty"`
+}
+
+// openVASModifyTaskResponse confirms which task was updated.
+type openVASModifyTaskResponse struct {
+	TaskID   string   `json:"task_id"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASCloneTaskRequest is the JSON input for duplicating a task. Name and
+// TargetID optionally rename and retarget the copy.
+type openVASCloneTaskRequest struct {
+	TaskID   string `json:"task_id"`
+	Name     string `json:"name,omitempty"`
+	TargetID string `json:"target_id,omitempty"`
+}
+
+// openVASCloneTaskResponse returns the ID of the new task.
+type openVASCloneTaskResponse struct {
+	ID       string   `json:"id"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASListTasksResponse is one page of tasks.
+type openVASListTasksResponse struct {
+	Tasks    []TaskSummary `json:"tasks"`
+	Filter   string        `json:"filter"`
+	Warnings []string      `json:"warnings,omitempty"`
 }
 
 // openVASStartTaskRequest is the JSON input for starting an existing task.
@@ -71,8 +190,37 @@ type openVASStartTaskRequest struct {
 // openVASStartTaskResponse wraps the raw XML response from gvmd when starting
 // a task so that callers can inspect status details if needed.
 type openVASStartTaskResponse struct {
-	TaskID      string `json:"task_id"`
-	ResponseRaw string `json:"response_raw"`
+	TaskID      string   `json:"task_id"`
+	ResponseRaw string   `json:"response_raw"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// openVASStopTaskRequest is the JSON input for stopping a running task.
+type openVASStopTaskRequest struct {
+	TaskID string `json:"task_id"`
+}
+
+// openVASStopTaskResponse returns the raw gvmd response along with its
+// parsed status_text.
+type openVASStopTaskResponse struct {
+	TaskID      string   `json:"task_id"`
+	StatusText  string   `json:"status_text"`
+	ResponseRaw string   `json:"response_raw"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// openVASResumeTaskRequest is the JSON input for resuming a stopped task.
+type openVASResumeTaskRequest struct {
+	TaskID string `json:"task_id"`
+}
+
+// openVASResumeTaskResponse returns the report ID of the resumed run so the
+// caller can keep tracking it, plus the raw gvmd response.
+type openVASResumeTaskResponse struct {
+	TaskID      string   `json:"task_id"`
+	ReportID    string   `json:"report_id"`
+	ResponseRaw string   `json:"response_raw"`
+	Warnings    []string `json:"warnings,omitempty"`
 }
 
 // openVASTaskStatusRequest is the JSON input for fetching the status/details
@@ -84,281 +232,2146 @@ type openVASTaskStatusRequest struct {
 // openVASTaskStatusResponse wraps the raw XML response from gvmd when querying
 // task status so that callers can inspect status details if needed.
 type openVASTaskStatusResponse struct {
-	TaskID      string `json:"task_id"`
-	ResponseRaw string `json:"response_raw"`
+	TaskID      string   `json:"task_id"`
+	ResponseRaw string   `json:"response_raw"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// openVASTaskProgressRequest is the JSON input for polling a task's progress.
+type openVASTaskProgressRequest struct {
+	TaskID string `json:"task_id"`
+}
+
+// openVASTaskProgressResponse wraps the typed task progress.
+type openVASTaskProgressResponse struct {
+	TaskProgress
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASWaitTaskRequest is the JSON input for blocking until a task has
+// finished. Both durations are optional and given in seconds.
+type openVASWaitTaskRequest struct {
+	TaskID              string `json:"task_id"`
+	TimeoutSeconds      int    `json:"timeout_seconds,omitempty"`
+	PollIntervalSeconds int    `json:"poll_interval_seconds,omitempty"`
+}
+
+// openVASWaitTaskResponse is returned once the task is finished.
+type openVASWaitTaskResponse struct {
+	TaskID   string   `json:"task_id"`
+	ReportID string   `json:"report_id"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // openVASGetReportRequest is the JSON input for fetching a final report by ID.
+// MaxResults, when set, returns only that many of the most severe results.
 type openVASGetReportRequest struct {
-	ReportID string `json:"report_id"`
+	ReportID   string `json:"report_id"`
+	MaxResults int    `json:"max_results,omitempty"`
 }
 
 // openVASGetReportResponse wraps the raw XML response from gvmd when fetching
-// a report so that callers can inspect full vulnerability details.
+// a report so that callers can inspect full vulnerability details. With
+// max_results, Total is the number of results in the report and Truncated
+// says whether some were left out.
 type openVASGetReportResponse struct {
-	ReportID    string `json:"report_id"`
-	ResponseRaw string `json:"response_raw"`
+	ReportID    string         `json:"report_id"`
+	ResponseRaw string         `json:"response_raw"`
+	Results     []ReportResult `json:"results,omitempty"`
+	Warnings    []string       `json:"warnings,omitempty"`
+	Total       *int           `json:"total,omitempty"`
+	Truncated   bool           `json:"truncated,omitempty"`
+}
+
+// Limits for the number of findings returned by /openvas/report-summary.
+const (
+	defaultReportSummaryTop = 10
+	maxReportSummaryTop     = 100
+)
+
+// openVASDeleteReportRequest is the JSON input for deleting a report.
+type openVASDeleteReportRequest struct {
+	ReportID string `json:"report_id"`
+}
+
+type openVASDeleteReportResponse struct {
+	ReportID string   `json:"report_id"`
+	Deleted  bool     `json:"deleted"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASReportSummaryRequest is the JSON input for summarizing a report.
+type openVASReportSummaryRequest struct {
+	ReportID string `json:"report_id"`
+	Top      int    `json:"top,omitempty"`
+}
+
+// openVASReportSummaryResponse wraps a severity summary of a report.
+type openVASReportSummaryResponse struct {
+	ReportSeveritySummary
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASReportCVEsRequest is the JSON input for listing a report's CVEs.
+type openVASReportCVEsRequest struct {
+	ReportID string `json:"report_id"`
+}
+
+type openVASReportCVEsResponse struct {
+	ReportID string   `json:"report_id"`
+	CVEs     []string `json:"cves"`
+	Count    int      `json:"count"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASListReportsResponse lists summaries of every known report.
+type openVASListReportsResponse struct {
+	Reports  []ReportSummary `json:"reports"`
+	Warnings []string        `json:"warnings,omitempty"`
+}
+
+// openVASReportFormatsResponse lists the report formats installed in gvmd.
+type openVASReportFormatsResponse struct {
+	ReportFormats []ReportFormat `json:"report_formats"`
+	FetchedAt     time.Time      `json:"fetched_at"`
+	Warnings      []string       `json:"warnings,omitempty"`
+}
+
+// openVASFeedsResponse lists the gvmd feeds and whether they are usable.
+type openVASFeedsResponse struct {
+	Feeds    []Feed   `json:"feeds"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASNVTFamiliesResponse lists the NVT families.
+type openVASNVTFamiliesResponse struct {
+	Families []NVTFamily `json:"families"`
+	Warnings []string    `json:"warnings,omitempty"`
+}
+
+// openVASNVTsResponse is one page of NVTs. Total counts every NVT matched,
+// not just those on the page.
+type openVASNVTsResponse struct {
+	NVTs     []NVT    `json:"nvts"`
+	Total    int      `json:"total"`
+	Offset   int      `json:"offset"`
+	Limit    int      `json:"limit"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASExportReportRequest is the JSON input for downloading a report in a
+// specific format.
+type openVASExportReportRequest struct {
+	ReportID string `json:"report_id"`
+	Format   string `json:"format"`
+}
+
+// openVASGetResultsRequest is the JSON input for listing a task's findings
+// above a severity threshold.
+type openVASGetResultsRequest struct {
+	TaskID      string  `json:"task_id"`
+	MinSeverity float64 `json:"min_severity,omitempty"`
+	// MinQOD drops findings with a lower quality of detection (0-100).
+	// Unset, gvmd's default of 70 applies.
+	MinQOD *int `json:"min_qod,omitempty"`
+}
+
+// openVASGetResultsResponse lists the parsed findings of a task.
+type openVASGetResultsResponse struct {
+	TaskID      string         `json:"task_id"`
+	MinSeverity float64        `json:"min_severity"`
+	MinQOD      *int           `json:"min_qod,omitempty"`
+	Results     []ReportResult `json:"results"`
+	Warnings    []string       `json:"warnings,omitempty"`
+}
+
+// openVASScanRequest is the JSON input for the one-shot scan orchestration
+// endpoint.
+type openVASScanRequest struct {
+	Name     string `json:"name"`
+	Hosts    string `json:"hosts"`
+	ConfigID string `json:"config_id,omitempty"`
+	// ConfigName selects the config by name (e.g. "Full and fast") instead
+	// of by ID.
+	ConfigName string `json:"config_name,omitempty"`
+	PortRange  string `json:"port_range,omitempty"`
+	PortListID string `json:"port_list_id,omitempty"`
+	// Wait keeps the request open until the task has finished, for up to
+	// TimeoutSeconds (capped by OPENVAS_MAX_WAIT). ReportFormat (e.g. "pdf"
+	// or "csv") then returns the finished report in that format.
+	Wait           bool   `json:"wait,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	ReportFormat   string `json:"report_format,omitempty"`
+}
+
+// openVASScanResponse reports the resources used by an orchestrated scan. On
+// failure FailedStep names the step that broke and the IDs reached so far
+// are still returned so the caller can retry or clean up.
+type openVASScanResponse struct {
+	TargetID   string   `json:"target_id,omitempty"`
+	TaskID     string   `json:"task_id,omitempty"`
+	ReportID   string   `json:"report_id,omitempty"`
+	Existed    bool     `json:"existed"`
+	FailedStep string   `json:"failed_step,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+	// Status is the task's final status when the request waited for it.
+	Status string `json:"status,omitempty"`
+	// Report is the finished report when report_format was given.
+	Report *openVASFormattedReport `json:"report,omitempty"`
+}
+
+// openVASFormattedReport is a report rendered in a non-XML report format,
+// as exported by /openvas/reports/export or returned by /openvas/scan.
+// Content is base64-encoded in JSON.
+type openVASFormattedReport struct {
+	Format      string `json:"format"`
+	ContentType string `json:"content_type"`
+	Filename    string `json:"filename"`
+	Content     []byte `json:"content"`
+}
+
+// openVASCreateOverrideRequest is the JSON input for overriding the severity
+// of an NVT finding, e.g. to mark it as a false positive.
+type openVASCreateOverrideRequest struct {
+	NVTOID      string `json:"nvt_oid"`
+	Host        string `json:"host,omitempty"`
+	NewSeverity string `json:"new_severity"`
+	Text        string `json:"text"`
+}
+
+// openVASCreateOverrideResponse is returned once the override is stored.
+type openVASCreateOverrideResponse struct {
+	ID          string   `json:"id"`
+	NVTOID      string   `json:"nvt_oid"`
+	NewSeverity string   `json:"new_severity"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// nvtOIDPattern matches dotted numeric NVT OIDs such as
+// 1.3.6.1.4.1.25623.1.0.10330.
+var nvtOIDPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)+$`)
+
+// normalizeOverrideSeverity validates an override severity. It accepts a
+// score between 0 and 10, -1, or "false_positive" (mapped to -1).
+func normalizeOverrideSeverity(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.EqualFold(raw, "false_positive") {
+		raw = "-1"
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(v) || (v != -1 && (v < 0 || v > 10)) {
+		return "", fmt.Errorf("new_severity must be between 0 and 10, -1, or \"false_positive\"")
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64), nil
+}
+
+// openVASScanExistingRequest is the JSON input for launching a scan against a
+// target that already exists in GVM.
+type openVASScanExistingRequest struct {
+	Name     string `json:"name,omitempty"`
+	TargetID string `json:"target_id"`
+	ConfigID string `json:"config_id"`
+}
+
+// openVASScanExistingResponse is returned once the task has been created (or
+// reused) and started against the existing target.
+type openVASScanExistingResponse struct {
+	TargetID    string   `json:"target_id"`
+	TaskID      string   `json:"task_id"`
+	ReportID    string   `json:"report_id"`
+	TaskExisted bool     `json:"task_existed,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// openVASCreateUserRequest creates a gvmd user. Roles are IDs or names such
+// as "User", "Admin" or "Observer".
+type openVASCreateUserRequest struct {
+	Name     string   `json:"name"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles"`
+}
+
+type openVASCreateUserResponse struct {
+	UserID   string   `json:"user_id"`
+	Name     string   `json:"name"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type openVASUsersResponse struct {
+	Users    []User   `json:"users"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type openVASDashboardResponse struct {
+	Dashboard
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// openVASErrorStatus picks the HTTP status for an OpenVASService error:
+// errors listed in errorStatuses (malformed IDs, missing targets, ...) get
+// the status given there, a GMP error status from gvmd is passed on (404
+// for a missing task, and so on), and everything else is a 500.
+func openVASErrorStatus(err error) int {
+	if e, ok := lookupErrorStatus(err); ok {
+		return e.status
+	}
+	var gmpErr *GMPError
+	if errors.As(err, &gmpErr) {
+		return gmpHTTPStatus(gmpErr.Status)
+	}
+	return http.StatusInternalServerError
+}
+
+// gmpHTTPStatus maps a GMP response status to an HTTP status. GMP reuses
+// HTTP's codes for client errors; a failure inside gvmd is reported as a bad
+// gateway since this service is only relaying the request.
+func gmpHTTPStatus(status int) int {
+	switch {
+	case status == http.StatusBadRequest, status == http.StatusUnauthorized,
+		status == http.StatusForbidden, status == http.StatusNotFound,
+		status == http.StatusConflict:
+		return status
+	case status >= 400 && status < 500:
+		return http.StatusBadRequest
+	case status == http.StatusServiceUnavailable:
+		return http.StatusServiceUnavailable
+	case status >= 500:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// wantRefresh reports whether the request asked to bypass the listing cache
+// with ?refresh=true.
+func wantRefresh(r *http.Request) bool {
+	refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+	return refresh
 }
 
 // openVASVersionHandler is a modular HTTP handler that uses OpenVASService
-// to call <get_version/> and returns the raw XML in JSON.
+// to call <get_version/> and returns the parsed version, plus the raw XML
+// with ?raw=true.
 func openVASVersionHandler(svc *OpenVASService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		versionXML, err := svc.GetVersion(ctx)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS version", err)
+			return
+		}
+
+		version, err := parseVersion(versionXML)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to parse OpenVAS version", err)
+			return
+		}
+
+		resp := openVASVersionResponse{OpenVASVersion: version}
+		if raw, _ := strconv.ParseBool(r.URL.Query().Get("raw")); raw {
+			resp.VersionRaw = versionXML
+		}
+		resp.Warnings = warns.list()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("failed to encode OpenVAS version response: %v", err)
+		}
+	})
+}
+
+// openVASAuthCheckHandler verifies GVM_USERNAME/GVM_PASSWORD against gvmd,
+// answering 401 with gvmd's reason when they are rejected.
+func openVASAuthCheckHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
-		versionXML, err := svc.GetVersion(r.Context())
+		ctx, warns := withWarnings(r.Context())
+
+		err := svc.Authenticate(ctx)
+		if errors.Is(err, ErrAuthFailed) {
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
 		if err != nil {
-			log.Printf("failed to get OpenVAS version: %v", err)
-			http.Error(w, "failed to get OpenVAS version", http.StatusInternalServerError)
+			writeError(w, openVASErrorStatus(err), "failed to authenticate with OpenVAS", err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(openVASVersionResponse{
-			VersionRaw: versionXML,
+		if err := json.NewEncoder(w).Encode(openVASAuthCheckResponse{
+			Authenticated: true,
+			Username:      svc.Username,
+			Warnings:      warns.list(),
 		}); err != nil {
-			log.Printf("failed to encode OpenVAS version response: %v", err)
+			log.Printf("failed to encode OpenVAS auth check response: %v", err)
 		}
 	})
 }
 
 // openVASConfigsHandler returns all available scan configurations (profiles)
-// from OpenVAS/GVM in a simple, LLM-friendly JSON structure.
+// from OpenVAS/GVM in a simple, LLM-friendly JSON structure. The list is
+// cached; ?refresh=true fetches it again.
 func openVASConfigsHandler(svc *OpenVASService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
-		configsXML, err := svc.GetConfigs(r.Context())
+		ctx, warns := withWarnings(r.Context())
+
+		configs, fetchedAt, err := svc.CachedConfigs(ctx, wantRefresh(r))
 		if err != nil {
-			log.Printf("failed to get OpenVAS configs: %v", err)
-			http.Error(w, "failed to get OpenVAS configs", http.StatusInternalServerError)
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS configs", err)
+			return
+		}
+
+		resp := openVASConfigsResponse{
+			Configs:   configs,
+			FetchedAt: fetchedAt,
+			Warnings:  warns.list(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("failed to encode OpenVAS configs response: %v", err)
+		}
+	})
+}
+
+// openVASGetConfigHandler returns a scan config's enabled NVT families and
+// preferences, e.g. to check what a config will actually run before creating
+// a task with it.
+func openVASGetConfigHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
-		var parsed openVASGetConfigsXML
-		if err := xml.Unmarshal([]byte(configsXML), &parsed); err != nil {
-			log.Printf("failed to parse OpenVAS configs XML: %v", err)
-			http.Error(w, "failed to parse OpenVAS configs", http.StatusInternalServerError)
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASGetConfigRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
 			return
 		}
 
-		resp := openVASConfigsResponse{
-			Configs: make([]openVASConfig, 0, len(parsed.Configs)),
+		req.ConfigID = strings.TrimSpace(req.ConfigID)
+		if req.ConfigID == "" {
+			writeJSONError(w, http.StatusBadRequest, "config_id is required")
+			return
 		}
 
-		for _, c := range parsed.Configs {
-			resp.Configs = append(resp.Configs, openVASConfig{
-				ID:      c.ID,
-				Name:    strings.TrimSpace(c.Name),
-				Comment: strings.TrimSpace(c.Comment),
-			})
+		details, err := svc.GetConfigDetails(ctx, req.ConfigID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS config", err)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			log.Printf("failed to encode OpenVAS configs response: %v", err)
+		if err := json.NewEncoder(w).Encode(openVASGetConfigResponse{
+			ConfigDetails: details,
+			Warnings:      warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS get config response: %v", err)
+		}
+	})
+}
+
+// openVASScannersHandler lists the scanners a task can be created with,
+// cached like the configs listing.
+func openVASScannersHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		scanners, fetchedAt, err := svc.CachedScanners(ctx, wantRefresh(r))
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS scanners", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASScannersResponse{
+			Scanners:  scanners,
+			FetchedAt: fetchedAt,
+			Warnings:  warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS scanners response: %v", err)
 		}
 	})
 }
 
 // openVASCreateTargetHandler creates a new OpenVAS/GVM target in an
-// idempotent way. If a target with the same name and hosts already exists,
-// it returns that existing target ID instead of failing.
+// idempotent way. If a target with the same name, hosts and ports already
+// exists, it returns that existing target ID instead of failing; one with
+// other ports is reported as 409 Conflict.
 func openVASCreateTargetHandler(svc *OpenVASService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
+		ctx, warns := withWarnings(r.Context())
+
 		var req openVASCreateTargetRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
 			return
 		}
 
 		req.Name = strings.TrimSpace(req.Name)
 		req.Hosts = strings.TrimSpace(req.Hosts)
 		req.PortRange = strings.TrimSpace(req.PortRange)
+		req.PortListID = strings.TrimSpace(req.PortListID)
 
 		if req.Name == "" || req.Hosts == "" {
-			http.Error(w, "name and hosts are required", http.StatusBadRequest)
+			writeJSONError(w, http.StatusBadRequest, "name and hosts are required")
+			return
+		}
+		if req.PortRange != "" && req.PortListID != "" {
+			writeJSONError(w, http.StatusBadRequest, "port_range and port_list_id are mutually exclusive")
 			return
 		}
 
-		id, existed, err := svc.CreateTarget(r.Context(), req.Name, req.Hosts, req.PortRange)
+		id, existed, err := svc.CreateTarget(ctx, req.Name, req.Hosts, req.PortRange, req.PortListID)
 		if err != nil {
-			log.Printf("failed to create OpenVAS target: %v", err)
-			http.Error(w, "failed to create OpenVAS target", http.StatusInternalServerError)
+			writeError(w, openVASErrorStatus(err), "failed to create OpenVAS target", err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(openVASCreateTargetResponse{
-			ID:      id,
-			Existed: existed,
+			ID:       id,
+			Existed:  existed,
+			Warnings: warns.list(),
 		}); err != nil {
 			log.Printf("failed to encode OpenVAS create target response: %v", err)
 		}
 	})
 }
 
-// openVASCreateTaskHandler creates a new OpenVAS/GVM task in an idempotent
-// way. If a task with the same name, config ID and target ID already exists,
-// it returns that existing task ID instead of failing.
-func openVASCreateTaskHandler(svc *OpenVASService) http.Handler {
+// openVASPortListsHandler lists the port lists targets can reference.
+func openVASPortListsHandler(svc *OpenVASService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-
-		var req openVASCreateTaskRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
-		req.Name = strings.TrimSpace(req.Name)
-		req.ConfigID = strings.TrimSpace(req.ConfigID)
-		req.TargetID = strings.TrimSpace(req.TargetID)
-
-		if req.Name == "" || req.ConfigID == "" || req.TargetID == "" {
-			http.Error(w, "name, config_id and target_id are required", http.StatusBadRequest)
-			return
-		}
+		ctx, warns := withWarnings(r.Context())
 
-		id, existed, err := svc.CreateTask(r.Context(), req.Name, req.ConfigID, req.TargetID)
+		lists, err := svc.GetPortLists(ctx)
 		if err != nil {
-			log.Printf("failed to create OpenVAS task: %v", err)
-			http.Error(w, "failed to create OpenVAS task", http.StatusInternalServerError)
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS port lists", err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(openVASCreateTaskResponse{
-			ID:      id,
-			Existed: existed,
+		if err := json.NewEncoder(w).Encode(openVASPortListsResponse{
+			PortLists: lists,
+			Warnings:  warns.list(),
 		}); err != nil {
-			log.Printf("failed to encode OpenVAS create task response: %v", err)
+			log.Printf("failed to encode OpenVAS port lists response: %v", err)
 		}
 	})
 }
 
-// openVASStartTaskHandler starts an existing OpenVAS/GVM task by ID.
-func openVASStartTaskHandler(svc *OpenVASService) http.Handler {
+// openVASCreatePortListHandler creates a reusable port list.
+func openVASCreatePortListHandler(svc *OpenVASService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
-		var req openVASStartTaskRequest
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASCreatePortListRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
 			return
 		}
 
-		req.TaskID = strings.TrimSpace(req.TaskID)
-		if req.TaskID == "" {
-			http.Error(w, "task_id is required", http.StatusBadRequest)
+		req.Name = strings.TrimSpace(req.Name)
+		req.PortRange = strings.TrimSpace(req.PortRange)
+		if req.Name == "" || req.PortRange == "" {
+			writeJSONError(w, http.StatusBadRequest, "name and port_range are required")
 			return
 		}
 
-		raw, err := svc.StartTask(r.Context(), req.TaskID)
+		id, err := svc.CreatePortList(ctx, req.Name, req.PortRange)
 		if err != nil {
-			log.Printf("failed to start OpenVAS task: %v", err)
-			http.Error(w, "failed to start OpenVAS task", http.StatusInternalServerError)
+			writeError(w, openVASErrorStatus(err), "failed to create OpenVAS port list", err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(openVASStartTaskResponse{
-			TaskID:      req.TaskID,
-			ResponseRaw: raw,
+		if err := json.NewEncoder(w).Encode(openVASCreatePortListResponse{
+			ID:       id,
+			Warnings: warns.list(),
 		}); err != nil {
-			log.Printf("failed to encode OpenVAS start task response: %v", err)
+			log.Printf("failed to encode OpenVAS create port list response: %v", err)
 		}
 	})
 }
 
-// openVASTaskStatusHandler fetches the current status/details for an existing
-// OpenVAS/GVM task by ID.
-func openVASTaskStatusHandler(svc *OpenVASService) http.Handler {
+// openVASGetTargetHandler returns a target's name, hosts, port list and SSH
+// credential, e.g. to check which target CreateTarget reused before creating
+// a task against it.
+func openVASGetTargetHandler(svc *OpenVASService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
-		var req openVASTaskStatusRequest
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASGetTargetRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
 			return
 		}
 
-		req.TaskID = strings.TrimSpace(req.TaskID)
-		if req.TaskID == "" {
-			http.Error(w, "task_id is required", http.StatusBadRequest)
+		req.TargetID = strings.TrimSpace(req.TargetID)
+		if req.TargetID == "" {
+			writeJSONError(w, http.StatusBadRequest, "target_id is required")
 			return
 		}
 
-		raw, err := svc.GetTaskStatus(r.Context(), req.TaskID)
+		target, err := svc.GetTarget(ctx, req.TargetID)
 		if err != nil {
-			log.Printf("failed to get OpenVAS task status: %v", err)
-			http.Error(w, "failed to get OpenVAS task status", http.StatusInternalServerError)
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS target", err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(openVASTaskStatusResponse{
-			TaskID:      req.TaskID,
-			ResponseRaw: raw,
+		if err := json.NewEncoder(w).Encode(openVASGetTargetResponse{
+			Target:   target,
+			Warnings: warns.list(),
 		}); err != nil {
-			log.Printf("failed to encode OpenVAS task status response: %v", err)
+			log.Printf("failed to encode OpenVAS get target response: %v", err)
 		}
 	})
 }
 
-// openVASGetReportHandler fetches the final report for a given report ID.
-func openVASGetReportHandler(svc *OpenVASService) http.Handler {
+// openVASDeleteTargetHandler deletes an OpenVAS/GVM target by ID so test runs
+// don't accumulate orphaned targets. A target still referenced by a task is
+// reported with 409 Conflict so callers know to delete the task first.
+func openVASDeleteTargetHandler(svc *OpenVASService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
-		var req openVASGetReportRequest
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASDeleteTargetRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
 			return
 		}
 
-		req.ReportID = strings.TrimSpace(req.ReportID)
-		if req.ReportID == "" {
-			http.Error(w, "report_id is required", http.StatusBadRequest)
+		req.TargetID = strings.TrimSpace(req.TargetID)
+		if req.TargetID == "" {
+			writeJSONError(w, http.StatusBadRequest, "target_id is required")
 			return
 		}
 
-		raw, err := svc.GetReport(r.Context(), req.ReportID)
-		if err != nil {
-			log.Printf("failed to get OpenVAS report: %v", err)
-			http.Error(w, "failed to get OpenVAS report", http.StatusInternalServerError)
+		if err := svc.DeleteTarget(ctx, req.TargetID); err != nil {
+			if errors.Is(err, ErrTargetInUse) {
+				writeError(w, http.StatusConflict, "target is in use by a task; delete the task first", err)
+				return
+			}
+			writeError(w, openVASErrorStatus(err), "failed to delete OpenVAS target", err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(openVASGetReportResponse{
-			ReportID:    req.ReportID,
-			ResponseRaw: raw,
+		if err := json.NewEncoder(w).Encode(openVASDeleteTargetResponse{
+			TargetID: req.TargetID,
+			Deleted:  true,
+			Warnings: warns.list(),
 		}); err != nil {
-			log.Printf("failed to encode OpenVAS get report response: %v", err)
+			log.Printf("failed to encode OpenVAS delete target response: %v", err)
+		}
+	})
+}
+
+// openVASCreateTaskHandler creates a new OpenVAS/GVM task in an idempotent
+// way. If a task with the same name, config ID and target ID already exists,
+// it returns that existing task ID instead of failing.
+func openVASCreateTaskHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASCreateTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		req.ConfigID = strings.TrimSpace(req.ConfigID)
+		req.TargetID = strings.TrimSpace(req.TargetID)
+		req.ScannerID = strings.TrimSpace(req.ScannerID)
+		req.ScheduleID = strings.TrimSpace(req.ScheduleID)
+
+		if req.Name == "" || req.ConfigID == "" || req.TargetID == "" {
+			writeJSONError(w, http.StatusBadRequest, "name, config_id and target_id are required")
+			return
+		}
+
+		id, existed, err := svc.CreateTask(ctx, req.Name, req.ConfigID, req.TargetID, req.ScannerID, req.ScheduleID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to create OpenVAS task", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASCreateTaskResponse{
+			ID:       id,
+			Existed:  existed,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS create task response: %v", err)
+		}
+	})
+}
+
+// openVASModifyTaskHandler updates the name, config, target, scanner or
+// schedule of an existing task without recreating it.
+func openVASModifyTaskHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASModifyTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		if req.TaskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+		opts := ModifyTaskOptions{
+			Name:       strings.TrimSpace(req.Name),
+			ConfigID:   strings.TrimSpace(req.ConfigID),
+			TargetID:   strings.TrimSpace(req.TargetID),
+			ScannerID:  strings.TrimSpace(req.ScannerID),
+			ScheduleID: strings.TrimSpace(req.ScheduleID),
+		}
+		if opts == (ModifyTaskOptions{}) {
+			writeJSONError(w, http.StatusBadRequest, "at least one of name, config_id, target_id, scanner_id or schedule_id is required")
+			return
+		}
+
+		if err := svc.ModifyTask(ctx, req.TaskID, opts); err != nil {
+			if errors.Is(err, ErrTaskNotModifiable) {
+				writeError(w, http.StatusConflict, "task cannot be modified", err)
+				return
+			}
+			writeError(w, openVASErrorStatus(err), "failed to modify OpenVAS task", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASModifyTaskResponse{
+			TaskID:   req.TaskID,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS modify task response: %v", err)
+		}
+	})
+}
+
+// openVASCloneTaskHandler copies an existing task and, when name or
+// target_id are given, applies them to the copy.
+func openVASCloneTaskHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASCloneTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		req.Name = strings.TrimSpace(req.Name)
+		req.TargetID = strings.TrimSpace(req.TargetID)
+		if req.TaskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+		if req.TargetID != "" {
+			if err := validateGVMID("target_id", req.TargetID); err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		id, err := svc.CloneTask(ctx, req.TaskID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to clone OpenVAS task", err)
+			return
+		}
+
+		if req.Name != "" || req.TargetID != "" {
+			// The copy has never run, so gvmd accepts a new target.
+			if err := svc.ModifyTask(ctx, id, ModifyTaskOptions{Name: req.Name, TargetID: req.TargetID}); err != nil {
+				writeError(w, openVASErrorStatus(err), fmt.Sprintf("task cloned as %s but could not be updated", id), err)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASCloneTaskResponse{
+			ID:       id,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS clone task response: %v", err)
+		}
+	})
+}
+
+// openVASListTasksHandler lists existing tasks with their status and
+// progress. ?filter= takes a GMP filter for paging, sorting and matching,
+// e.g. "rows=20 first=21 sort=name".
+func openVASListTasksHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		filter := strings.TrimSpace(r.URL.Query().Get("filter"))
+		if filter == "" {
+			filter = defaultTaskListFilter
+		}
+
+		tasks, err := svc.ListTasks(ctx, filter)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to list OpenVAS tasks", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASListTasksResponse{
+			Tasks:    tasks,
+			Filter:   filter,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS list tasks response: %v", err)
+		}
+	})
+}
+
+// openVASCreateScheduleHandler creates a schedule from iCalendar data that
+// can then be attached to tasks for recurring scans.
+func openVASCreateScheduleHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASCreateScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		req.ICalendar = strings.TrimSpace(req.ICalendar)
+		if req.Name == "" || req.ICalendar == "" {
+			writeJSONError(w, http.StatusBadRequest, "name and icalendar are required")
+			return
+		}
+
+		id, err := svc.CreateSchedule(ctx, req.Name, req.ICalendar)
+		if errors.Is(err, ErrInvalidSchedule) {
+			// gvmd's explanation is what the caller needs to fix the input.
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to create OpenVAS schedule", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASCreateScheduleResponse{
+			ID:       id,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS create schedule response: %v", err)
+		}
+	})
+}
+
+// openVASStartTaskHandler starts an existing OpenVAS/GVM task by ID and
+// records it in the task registry.
+func openVASStartTaskHandler(svc *OpenVASService, tasks *taskRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASStartTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		if req.TaskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+
+		raw, err := svc.StartTask(ctx, req.TaskID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to start OpenVAS task", err)
+			return
+		}
+
+		reportID, err := parseStartTaskReportID(raw)
+		if err != nil {
+			log.Printf("failed to read report id for started task %s: %v", req.TaskID, err)
+			addWarning(ctx, "report id of the started run could not be determined")
+		}
+		tasks.track(req.TaskID, reportID)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASStartTaskResponse{
+			TaskID:      req.TaskID,
+			ResponseRaw: raw,
+			Warnings:    warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS start task response: %v", err)
+		}
+	})
+}
+
+// openVASStopTaskHandler stops a running OpenVAS/GVM task by ID. Stopping a
+// task that isn't running is reported as 409 Conflict with a clear message.
+func openVASStopTaskHandler(svc *OpenVASService, tasks *taskRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASStopTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		if req.TaskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+
+		raw, err := svc.StopTask(ctx, req.TaskID)
+		if err != nil {
+			if errors.Is(err, ErrTaskNotRunning) {
+				writeError(w, http.StatusConflict, "task is not running (already stopped or finished)", err)
+				return
+			}
+			writeError(w, openVASErrorStatus(err), "failed to stop OpenVAS task", err)
+			return
+		}
+
+		tasks.untrack(req.TaskID)
+
+		statusText := ""
+		if st, err := parseGMPStatus([]byte(raw)); err == nil {
+			statusText = st.StatusText
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASStopTaskResponse{
+			TaskID:      req.TaskID,
+			StatusText:  statusText,
+			ResponseRaw: raw,
+			Warnings:    warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS stop task response: %v", err)
+		}
+	})
+}
+
+// openVASResumeTaskHandler resumes a stopped or interrupted OpenVAS/GVM task
+// and records the resumed run in the task registry.
+func openVASResumeTaskHandler(svc *OpenVASService, tasks *taskRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASResumeTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		if req.TaskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+
+		raw, err := svc.ResumeTask(ctx, req.TaskID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to resume OpenVAS task", err)
+			return
+		}
+
+		reportID, err := parseTaskRunReportID(raw, "resume_task_response")
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to read OpenVAS report id", err)
+			return
+		}
+		tasks.track(req.TaskID, reportID)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASResumeTaskResponse{
+			TaskID:      req.TaskID,
+			ReportID:    reportID,
+			ResponseRaw: raw,
+			Warnings:    warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS resume task response: %v", err)
+		}
+	})
+}
+
+// openVASTaskStatusHandler fetches the current status/details for an existing
+// OpenVAS/GVM task by ID.
+func openVASTaskStatusHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASTaskStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		if req.TaskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+
+		raw, err := svc.GetTaskStatus(ctx, req.TaskID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS task status", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASTaskStatusResponse{
+			TaskID:      req.TaskID,
+			ResponseRaw: raw,
+			Warnings:    warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS task status response: %v", err)
+		}
+	})
+}
+
+// openVASTaskProgressHandler returns a task's status, progress percentage and
+// report ID as typed JSON so agents can poll for completion without parsing
+// XML.
+func openVASTaskProgressHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASTaskProgressRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		if req.TaskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+
+		progress, err := svc.GetTaskProgress(ctx, req.TaskID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS task progress", err)
+			return
+		}
+		if !progress.HasRun {
+			addWarning(ctx, "task has never been run; start it to get a report")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASTaskProgressResponse{
+			TaskProgress: progress,
+			Warnings:     warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS task progress response: %v", err)
+		}
+	})
+}
+
+// openVASWaitTaskHandler blocks until a task is finished and returns its
+// report ID. The wait is capped at maxWait regardless of what the caller
+// asks for, so a forgotten scan can't pin a connection forever.
+func openVASWaitTaskHandler(svc *OpenVASService, maxWait time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASWaitTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		if req.TaskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+
+		timeout := maxWait
+		if req.TimeoutSeconds > 0 {
+			timeout = time.Duration(req.TimeoutSeconds) * time.Second
+			if timeout > maxWait {
+				timeout = maxWait
+				addWarning(ctx, "timeout_seconds capped at the server maximum of %s", maxWait)
+			}
+		}
+		pollInterval := defaultTaskPollInterval
+		if req.PollIntervalSeconds > 0 {
+			pollInterval = time.Duration(req.PollIntervalSeconds) * time.Second
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		reportID, err := svc.WaitForTask(waitCtx, req.TaskID, pollInterval)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				writeError(w, http.StatusGatewayTimeout, "timed out waiting for OpenVAS task", err)
+			case errors.Is(err, ErrTaskStopped):
+				writeError(w, http.StatusConflict, "OpenVAS task ended without completing", err)
+			default:
+				writeError(w, openVASErrorStatus(err), "failed to wait for OpenVAS task", err)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASWaitTaskResponse{
+			TaskID:   req.TaskID,
+			ReportID: reportID,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS wait task response: %v", err)
+		}
+	})
+}
+
+// openVASGetReportHandler fetches the final report for a given report ID.
+func openVASGetReportHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASGetReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.ReportID = strings.TrimSpace(req.ReportID)
+		if req.ReportID == "" {
+			writeJSONError(w, http.StatusBadRequest, "report_id is required")
+			return
+		}
+		if req.MaxResults < 0 {
+			writeJSONError(w, http.StatusBadRequest, "max_results must be positive")
+			return
+		}
+
+		raw, err := svc.GetReport(ctx, req.ReportID, req.MaxResults)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS report", err)
+			return
+		}
+
+		results, err := parseReportResults(raw)
+		if err != nil {
+			// The raw XML is still useful on its own; just skip parsed results.
+			log.Printf("failed to parse OpenVAS report results: %v", err)
+			addWarning(ctx, "report results could not be parsed; only raw XML is returned")
+		}
+
+		resp := openVASGetReportResponse{
+			ReportID:    req.ReportID,
+			ResponseRaw: raw,
+			Results:     results,
+		}
+		if req.MaxResults > 0 {
+			// gvmd already picked the most severe; overrides can still
+			// leave them out of order.
+			sortResultsBySeverity(resp.Results)
+			if total, err := parseReportResultCount(raw); err != nil {
+				log.Printf("failed to parse OpenVAS report result count: %v", err)
+				addWarning(ctx, "total result count unavailable; the results may be truncated")
+			} else {
+				resp.Total = &total
+				resp.Truncated = total > len(results)
+			}
+		}
+		resp.Warnings = warns.list()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("failed to encode OpenVAS get report response: %v", err)
+		}
+	})
+}
+
+// openVASDeleteReportHandler deletes a report to free disk space on gvmd.
+func openVASDeleteReportHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASDeleteReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.ReportID = strings.TrimSpace(req.ReportID)
+		if req.ReportID == "" {
+			writeJSONError(w, http.StatusBadRequest, "report_id is required")
+			return
+		}
+
+		if err := svc.DeleteReport(ctx, req.ReportID); err != nil {
+			if errors.Is(err, ErrReportInUse) {
+				writeError(w, http.StatusConflict, "report is still in use by its task; wait for the task to finish or delete the task instead", err)
+				return
+			}
+			writeError(w, openVASErrorStatus(err), "failed to delete OpenVAS report", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASDeleteReportResponse{
+			ReportID: req.ReportID,
+			Deleted:  true,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS delete report response: %v", err)
+		}
+	})
+}
+
+// openVASReportCVEsHandler returns just the CVEs found in a report, a
+// compact input for cross-referencing against a vulnerability database.
+func openVASReportCVEsHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASReportCVEsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.ReportID = strings.TrimSpace(req.ReportID)
+		if req.ReportID == "" {
+			writeJSONError(w, http.StatusBadRequest, "report_id is required")
+			return
+		}
+
+		cves, err := svc.GetReportCVEs(ctx, req.ReportID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS report CVEs", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASReportCVEsResponse{
+			ReportID: req.ReportID,
+			CVEs:     cves,
+			Count:    len(cves),
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS report CVEs response: %v", err)
+		}
+	})
+}
+
+// openVASReportSummaryHandler returns per-severity counts and the most
+// severe findings of a report, a compact alternative to the full XML.
+func openVASReportSummaryHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASReportSummaryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.ReportID = strings.TrimSpace(req.ReportID)
+		if req.ReportID == "" {
+			writeJSONError(w, http.StatusBadRequest, "report_id is required")
+			return
+		}
+		if req.Top == 0 {
+			req.Top = defaultReportSummaryTop
+		}
+		if req.Top < 1 || req.Top > maxReportSummaryTop {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("top must be between 1 and %d", maxReportSummaryTop))
+			return
+		}
+
+		summary, err := svc.SummarizeReport(ctx, req.ReportID, req.Top)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to summarize OpenVAS report", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASReportSummaryResponse{
+			ReportSeveritySummary: summary,
+			Warnings:              warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS report summary response: %v", err)
+		}
+	})
+}
+
+// openVASListReportsHandler lists every report gvmd knows about, newest
+// first, so past scans can be browsed without tracking report IDs.
+func openVASListReportsHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		reports, err := svc.ListReports(ctx)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to list OpenVAS reports", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASListReportsResponse{
+			Reports:  reports,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS list reports response: %v", err)
+		}
+	})
+}
+
+// openVASGetResultsHandler returns the findings of a task with a severity
+// above min_severity and a quality of detection of at least min_qod as a
+// flat list, most severe first, without the rest of the report.
+func openVASGetResultsHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASGetResultsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		if req.TaskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+		if req.MinSeverity < 0 || req.MinSeverity > 10 {
+			writeJSONError(w, http.StatusBadRequest, "min_severity must be between 0 and 10")
+			return
+		}
+		if req.MinQOD != nil && (*req.MinQOD < 0 || *req.MinQOD > 100) {
+			writeJSONError(w, http.StatusBadRequest, "min_qod must be between 0 and 100")
+			return
+		}
+
+		raw, err := svc.GetResults(ctx, req.TaskID, req.MinSeverity, req.MinQOD)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS results", err)
+			return
+		}
+
+		results, err := parseResults(raw)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to parse OpenVAS results", err)
+			return
+		}
+		sortResultsBySeverity(results)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASGetResultsResponse{
+			TaskID:      req.TaskID,
+			MinSeverity: req.MinSeverity,
+			MinQOD:      req.MinQOD,
+			Results:     results,
+			Warnings:    warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS get results response: %v", err)
+		}
+	})
+}
+
+// openVASReportFormatsHandler lists the report formats gvmd can render,
+// whose names or extensions can be passed to /openvas/reports/export. The
+// list is cached; ?refresh=true fetches it again.
+func openVASReportFormatsHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		formats, fetchedAt, err := svc.CachedReportFormats(ctx, wantRefresh(r))
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS report formats", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASReportFormatsResponse{
+			ReportFormats: formats,
+			FetchedAt:     fetchedAt,
+			Warnings:      warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS report formats response: %v", err)
+		}
+	})
+}
+
+// openVASFeedsHandler reports each feed's version and sync state, adding a
+// warning for feeds that are syncing, stale or unavailable so callers know
+// a scan may run against outdated data.
+func openVASFeedsHandler(svc *OpenVASService, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		feeds, err := svc.GetFeeds(ctx, maxAge)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS feeds", err)
+			return
+		}
+		for _, f := range feeds {
+			switch f.Status {
+			case FeedStatusSyncing:
+				addWarning(ctx, "%s feed is currently syncing", f.Type)
+			case FeedStatusStale:
+				addWarning(ctx, "%s feed is stale (version %s)", f.Type, f.Version)
+			case FeedStatusNotAvailable:
+				addWarning(ctx, "%s feed sync is not available", f.Type)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASFeedsResponse{
+			Feeds:    feeds,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS feeds response: %v", err)
+		}
+	})
+}
+
+// openVASNVTFamiliesHandler lists the NVT families and their sizes.
+func openVASNVTFamiliesHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		families, err := svc.GetNVTFamilies(ctx)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS NVT families", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASNVTFamiliesResponse{
+			Families: families,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS NVT families response: %v", err)
+		}
+	})
+}
+
+// openVASNVTsHandler lists the NVTs of ?family=, a page at a time via
+// ?offset= and ?limit=.
+func openVASNVTsHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		q := r.URL.Query()
+		family := strings.TrimSpace(q.Get("family"))
+		if family == "" {
+			writeJSONError(w, http.StatusBadRequest, "family is required (see /openvas/nvt-families)")
+			return
+		}
+		offset := 0
+		if v := strings.TrimSpace(q.Get("offset")); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeJSONError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+				return
+			}
+			offset = n
+		}
+		limit := defaultNVTPageSize
+		if v := strings.TrimSpace(q.Get("limit")); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxNVTPageSize {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxNVTPageSize))
+				return
+			}
+			limit = n
+		}
+
+		nvts, err := svc.GetNVTs(ctx, family)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS NVTs", err)
+			return
+		}
+
+		total := len(nvts)
+		page := nvts[min(offset, total):min(offset+limit, total)]
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASNVTsResponse{
+			NVTs:     page,
+			Total:    total,
+			Offset:   offset,
+			Limit:    limit,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS NVTs response: %v", err)
+		}
+	})
+}
+
+// openVASExportReportHandler returns a report rendered in any installed
+// non-XML format, looked up by name or extension (pdf, csv, txt, ...). The
+// decoded file is written directly to the response with the
+// matching Content-Type so it can be handed straight to a user.
+func openVASExportReportHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx := r.Context()
+
+		var req openVASExportReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.ReportID = strings.TrimSpace(req.ReportID)
+		req.Format = strings.ToLower(strings.TrimSpace(req.Format))
+		if req.ReportID == "" || req.Format == "" {
+			writeJSONError(w, http.StatusBadRequest, "report_id and format are required")
+			return
+		}
+
+		format, ok := resolveExportFormat(ctx, w, svc, "format", req.Format)
+		if !ok {
+			return
+		}
+
+		report, msg, err := getFormattedReport(ctx, svc, req.ReportID, req.Format, format)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), msg, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", report.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", report.Filename))
+		if _, err := w.Write(report.Content); err != nil {
+			log.Printf("failed to write OpenVAS report export: %v", err)
+		}
+	})
+}
+
+// resolveExportFormat resolves the report format named by request field
+// field for an export. Unknown and XML formats are rejected with 400, since
+// XML reports are returned inline by /openvas/reports rather than
+// base64-encoded. It reports false once it has written an error response.
+func resolveExportFormat(ctx context.Context, w http.ResponseWriter, svc *OpenVASService, field, name string) (ReportFormat, bool) {
+	format, err := svc.ResolveReportFormat(ctx, name)
+	if errors.Is(err, ErrUnknownReportFormat) {
+		writeJSONError(w, http.StatusBadRequest, "unknown "+field+"; see /openvas/report-formats for the installed formats")
+		return ReportFormat{}, false
+	}
+	if err != nil {
+		writeError(w, openVASErrorStatus(err), "failed to resolve OpenVAS report format", err)
+		return ReportFormat{}, false
+	}
+	if strings.EqualFold(format.Extension, "xml") {
+		writeJSONError(w, http.StatusBadRequest, "XML reports are available from /openvas/reports")
+		return ReportFormat{}, false
+	}
+	return format, true
+}
+
+// getFormattedReport fetches report reportID rendered in format, which the
+// caller asked for by name, and decodes it. On failure it also returns the
+// message to report to the client.
+func getFormattedReport(ctx context.Context, svc *OpenVASService, reportID, name string, format ReportFormat) (*openVASFormattedReport, string, error) {
+	raw, err := svc.GetReportInFormat(ctx, reportID, format.ID)
+	if err != nil {
+		return nil, "failed to get OpenVAS report", err
+	}
+
+	content, contentType, err := decodeReportContent(raw)
+	if err != nil {
+		return nil, "failed to decode OpenVAS report", err
+	}
+	if contentType == "" {
+		contentType = format.ContentType
+	}
+	extension := format.Extension
+	if extension == "" {
+		extension = name
+	}
+
+	return &openVASFormattedReport{
+		Format:      name,
+		ContentType: contentType,
+		Filename:    "report-" + reportID + "." + extension,
+		Content:     content,
+	}, "", nil
+}
+
+// openVASScanExistingHandler creates and starts a task for a target that is
+// managed separately (e.g. with credentials or port lists preconfigured),
+// skipping target creation entirely.
+func openVASScanExistingHandler(svc *OpenVASService, tasks *taskRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASScanExistingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		req.TargetID = strings.TrimSpace(req.TargetID)
+		req.ConfigID = strings.TrimSpace(req.ConfigID)
+
+		if req.TargetID == "" || req.ConfigID == "" {
+			writeJSONError(w, http.StatusBadRequest, "target_id and config_id are required")
+			return
+		}
+		if req.Name == "" {
+			req.Name = "scan-" + req.TargetID
+		}
+
+		target, err := svc.GetTarget(ctx, req.TargetID)
+		if errors.Is(err, ErrTargetNotFound) {
+			writeJSONError(w, http.StatusNotFound, "target_id not found")
+			return
+		}
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to look up OpenVAS target", err)
+			return
+		}
+		// The target may have been created outside this service, so its
+		// hosts haven't been checked yet.
+		if err := checkTargetsScope(ctx, target.Hosts); err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to check OpenVAS target hosts", err)
+			return
+		}
+
+		taskID, existed, err := svc.CreateTask(ctx, req.Name, req.ConfigID, req.TargetID, "", "")
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to create OpenVAS task", err)
+			return
+		}
+
+		raw, err := svc.StartTask(ctx, taskID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to start OpenVAS task", err)
+			return
+		}
+
+		reportID, err := parseStartTaskReportID(raw)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to read OpenVAS report id", err)
+			return
+		}
+		tasks.track(taskID, reportID)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASScanExistingResponse{
+			TargetID:    req.TargetID,
+			TaskID:      taskID,
+			ReportID:    reportID,
+			TaskExisted: existed,
+			Warnings:    warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS scan existing response: %v", err)
+		}
+	})
+}
+
+// openVASCreateOverrideHandler stores a severity override (or false-positive
+// marking) for an NVT so the finding is re-scored in later report parsing.
+func openVASCreateOverrideHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASCreateOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.NVTOID = strings.TrimSpace(req.NVTOID)
+		req.Host = strings.TrimSpace(req.Host)
+		req.Text = strings.TrimSpace(req.Text)
+
+		if req.NVTOID == "" || req.Text == "" {
+			writeJSONError(w, http.StatusBadRequest, "nvt_oid, new_severity and text are required")
+			return
+		}
+		if !nvtOIDPattern.MatchString(req.NVTOID) {
+			writeJSONError(w, http.StatusBadRequest, "invalid nvt_oid")
+			return
+		}
+
+		severity, err := normalizeOverrideSeverity(req.NewSeverity)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		id, err := svc.CreateOverride(ctx, req.NVTOID, req.Host, severity, req.Text)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to create OpenVAS override", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASCreateOverrideResponse{
+			ID:          id,
+			NVTOID:      req.NVTOID,
+			NewSeverity: severity,
+			Warnings:    warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS create override response: %v", err)
+		}
+	})
+}
+
+// openVASScanHandler collapses target creation, task creation and task start
+// into a single idempotent call. Targets and tasks are reused when they
+// already exist, so retrying after a partial failure picks up where the
+// previous attempt stopped. A target created by this call is rolled back if
+// the task can't be created. With wait the call returns once the task is
+// done, along with the report in report_format if one was asked for.
+func openVASScanHandler(svc *OpenVASService, tasks *taskRegistry, maxWait time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASScanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		req.Hosts = strings.TrimSpace(req.Hosts)
+		req.ConfigID = strings.TrimSpace(req.ConfigID)
+		req.ConfigName = strings.TrimSpace(req.ConfigName)
+		req.PortRange = strings.TrimSpace(req.PortRange)
+		req.PortListID = strings.TrimSpace(req.PortListID)
+		req.ReportFormat = strings.ToLower(strings.TrimSpace(req.ReportFormat))
+
+		if req.Name == "" || req.Hosts == "" || (req.ConfigID == "" && req.ConfigName == "") {
+			writeJSONError(w, http.StatusBadRequest, "name, hosts and config_id or config_name are required")
+			return
+		}
+		if req.ConfigID != "" && req.ConfigName != "" {
+			writeJSONError(w, http.StatusBadRequest, "config_id and config_name are mutually exclusive")
+			return
+		}
+		if req.PortRange != "" && req.PortListID != "" {
+			writeJSONError(w, http.StatusBadRequest, "port_range and port_list_id are mutually exclusive")
+			return
+		}
+		if (req.ReportFormat != "" || req.TimeoutSeconds != 0) && !req.Wait {
+			writeJSONError(w, http.StatusBadRequest, "report_format and timeout_seconds require wait")
+			return
+		}
+		if req.TimeoutSeconds < 0 {
+			writeJSONError(w, http.StatusBadRequest, "timeout_seconds must be positive")
+			return
+		}
+
+		// Resolve the format before anything is created, so a bad format
+		// doesn't leave a running scan behind.
+		var format ReportFormat
+		if req.ReportFormat != "" {
+			var ok bool
+			if format, ok = resolveExportFormat(ctx, w, svc, "report_format", req.ReportFormat); !ok {
+				return
+			}
+		}
+
+		var resp openVASScanResponse
+		failStatus := func(status int, step, msg string, err error) {
+			if stopDryRun(w, err) {
+				return
+			}
+			log.Printf("OpenVAS scan %q failed at %s: %v", req.Name, step, err)
+			resp.FailedStep = step
+			resp.Error = msg
+			if verboseErrors && msg != err.Error() {
+				resp.Error = msg + ": " + err.Error()
+			}
+			resp.Warnings = warns.list()
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				log.Printf("failed to encode OpenVAS scan response: %v", err)
+			}
+		}
+		fail := func(step, msg string, err error) {
+			failStatus(openVASErrorStatus(err), step, msg, err)
+		}
+
+		if req.ConfigName != "" {
+			id, err := svc.ResolveConfigID(ctx, req.ConfigName)
+			if errors.Is(err, ErrUnknownConfig) {
+				// The list of valid names is safe to show and is what the
+				// caller needs to fix the request.
+				fail("resolve_config", err.Error(), err)
+				return
+			}
+			if err != nil {
+				fail("resolve_config", "failed to resolve OpenVAS config", err)
+				return
+			}
+			req.ConfigID = id
+		}
+
+		targetID, targetExisted, err := svc.CreateTarget(ctx, req.Name, req.Hosts, req.PortRange, req.PortListID)
+		if err != nil {
+			fail("create_target", "failed to create OpenVAS target", err)
+			return
+		}
+		resp.TargetID = targetID
+
+		taskID, taskExisted, err := svc.CreateTask(ctx, req.Name, req.ConfigID, targetID, "", "")
+		if err != nil {
+			if !targetExisted {
+				if delErr := svc.DeleteTarget(ctx, targetID); delErr != nil {
+					log.Printf("failed to roll back OpenVAS target %s: %v", targetID, delErr)
+					addWarning(ctx, "target %s was created but could not be rolled back", targetID)
+				} else {
+					resp.TargetID = ""
+				}
+			}
+			fail("create_task", "failed to create OpenVAS task", err)
+			return
+		}
+		resp.TaskID = taskID
+		resp.Existed = targetExisted && taskExisted
+
+		raw, err := svc.StartTask(ctx, taskID)
+		if err != nil {
+			fail("start_task", "failed to start OpenVAS task", err)
+			return
+		}
+
+		reportID, err := parseStartTaskReportID(raw)
+		if err != nil {
+			fail("start_task", "failed to read OpenVAS report id", err)
+			return
+		}
+		resp.ReportID = reportID
+		tasks.track(taskID, reportID)
+
+		if req.Wait {
+			timeout := maxWait
+			if req.TimeoutSeconds > 0 {
+				timeout = time.Duration(req.TimeoutSeconds) * time.Second
+				if timeout > maxWait {
+					timeout = maxWait
+					addWarning(ctx, "timeout_seconds capped at the server maximum of %s", maxWait)
+				}
+			}
+			waitCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			// The task keeps running in gvmd if the wait fails; the IDs
+			// already in resp let the caller pick it up again.
+			if _, err := svc.WaitForTask(waitCtx, taskID, defaultTaskPollInterval); err != nil {
+				switch {
+				case errors.Is(err, context.DeadlineExceeded):
+					failStatus(http.StatusGatewayTimeout, "wait_task", "timed out waiting for OpenVAS task", err)
+				case errors.Is(err, ErrTaskStopped):
+					failStatus(http.StatusConflict, "wait_task", "OpenVAS task ended without completing", err)
+				default:
+					fail("wait_task", "failed to wait for OpenVAS task", err)
+				}
+				return
+			}
+			resp.Status = "Done"
+
+			if req.ReportFormat != "" {
+				report, msg, err := getFormattedReport(ctx, svc, reportID, req.ReportFormat, format)
+				if err != nil {
+					fail("get_report", msg, err)
+					return
+				}
+				resp.Report = report
+			}
+		}
+
+		resp.Warnings = warns.list()
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("failed to encode OpenVAS scan response: %v", err)
+		}
+	})
+}
+
+// openVASUsersHandler lists gvmd's users and their roles.
+func openVASUsersHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		users, err := svc.GetUsers(ctx)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to list OpenVAS users", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASUsersResponse{
+			Users:    users,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS users response: %v", err)
+		}
+	})
+}
+
+// openVASCreateUserHandler creates a gvmd user. The password is passed
+// straight to gvmd and is never echoed back or logged.
+func openVASCreateUserHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req openVASCreateUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || req.Password == "" {
+			writeJSONError(w, http.StatusBadRequest, "name and password are required")
+			return
+		}
+		if len(req.Roles) == 0 {
+			addWarning(ctx, "no roles given; the user can't do anything until a role is assigned")
+		}
+
+		userID, err := svc.CreateUser(ctx, req.Name, req.Password, req.Roles)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to create OpenVAS user", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASCreateUserResponse{
+			UserID:   userID,
+			Name:     req.Name,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS create user response: %v", err)
+		}
+	})
+}
+
+// openVASDashboardHandler returns task counts, the number of targets and the
+// severity summary of the newest report in one response.
+func openVASDashboardHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		dash, err := svc.GetDashboard(ctx)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to load OpenVAS dashboard", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(openVASDashboardResponse{
+			Dashboard: dash,
+			Warnings:  warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode OpenVAS dashboard response: %v", err)
 		}
 	})
 }
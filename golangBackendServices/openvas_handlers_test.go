@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenVASScanExistingHandler(t *testing.T) {
+	const (
+		targetID = "b493b7a8-7489-11df-a3ec-002264764cea"
+		configID = "daba56c8-73ec-11df-a475-002264764cea"
+		taskID   = "8715c877-47a0-438d-98a3-27c7a6ab2196"
+		reportID = "d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663"
+	)
+	calls := filepath.Join(t.TempDir(), "calls")
+	svc := fakeGVMCLI(t, `for xml; do :; done
+echo "$xml" >> '`+calls+`'
+case "$xml" in
+*'<get_targets target_id="`+targetID+`"'*) echo '<get_targets_response status="200" status_text="OK"><target id="`+targetID+`"><name>managed</name><hosts>10.0.0.5</hosts></target></get_targets_response>' ;;
+*'<get_targets '*) echo 'Response Error 404. Failed to find target' >&2; exit 1 ;;
+*'<get_tasks'*) echo '<get_tasks_response status="200" status_text="OK"/>' ;;
+*'<create_task>'*) echo '<create_task_response status="201" status_text="OK, resource created" id="`+taskID+`"/>' ;;
+*'<start_task '*) echo '<start_task_response status="202" status_text="OK, request submitted"><report_id>`+reportID+`</report_id></start_task_response>' ;;
+*) echo "unexpected command $xml" >&2; exit 1 ;;
+esac
+`)
+	handler := openVASScanExistingHandler(svc, newTaskRegistry(filepath.Join(t.TempDir(), "openvas_tasks.json")))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"existing target", `{"target_id":"` + targetID + `","config_id":"` + configID + `"}`, http.StatusOK},
+		{"missing target", `{"target_id":"c4ac1f4c-1a6e-4b7e-9d3a-8f9e1b2c3d4e","config_id":"` + configID + `"}`, http.StatusNotFound},
+		{"no target", `{"config_id":"` + configID + `"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(calls)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/openvas/scan/existing", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			sent, _ := os.ReadFile(calls)
+			if strings.Contains(string(sent), "<create_target") {
+				t.Fatalf("handler created a target:\n%s", sent)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if strings.Contains(string(sent), "<create_task") {
+					t.Errorf("handler created a task after failing:\n%s", sent)
+				}
+				return
+			}
+
+			if !strings.Contains(string(sent), `<get_targets target_id="`+targetID+`"`) {
+				t.Errorf("target %s was not looked up:\n%s", targetID, sent)
+			}
+			if !strings.Contains(string(sent), `<target id="`+targetID+`">`) {
+				t.Errorf("create_task did not use target %s:\n%s", targetID, sent)
+			}
+			var resp openVASScanExistingResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.TargetID != targetID || resp.TaskID != taskID || resp.ReportID != reportID {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestNormalizeOverrideSeverity(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"5", "5.0", false},
+		{" 7.5 ", "7.5", false},
+		{"0", "0.0", false},
+		{"10", "10.0", false},
+		{"-1", "-1.0", false},
+		{"-1.0", "-1.0", false},
+		{"false_positive", "-1.0", false},
+		{"FALSE_POSITIVE", "-1.0", false},
+		{"10.1", "", true},
+		{"-0.5", "", true},
+		{"-2", "", true},
+		{"high", "", true},
+		{"", "", true},
+		{"NaN", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := normalizeOverrideSeverity(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeOverrideSeverity(%q) error = %v, want error: %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeOverrideSeverity(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
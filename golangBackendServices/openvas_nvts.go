@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pagination limits for GET /openvas/nvts. A single family can hold
+// thousands of NVTs.
+const (
+	defaultNVTPageSize = 100
+	maxNVTPageSize     = 1000
+)
+
+// NVTFamily is a group of Network Vulnerability Tests, e.g. "Web
+// application abuses".
+type NVTFamily struct {
+	Name     string `json:"name"`
+	NVTCount int    `json:"nvt_count"`
+}
+
+// NVT is a single Network Vulnerability Test.
+type NVT struct {
+	OID      string  `json:"oid"`
+	Name     string  `json:"name"`
+	Family   string  `json:"family"`
+	CVSSBase float64 `json:"cvss_base"`
+}
+
+// internal XML structs for parsing <get_nvt_families/> output.
+type openVASGetNVTFamiliesXML struct {
+	Families []struct {
+		Name        string `xml:"name"`
+		MaxNVTCount string `xml:"max_nvt_count"`
+	} `xml:"families>family"`
+}
+
+// parseNVTFamilies converts a raw get_nvt_families_response into NVTFamily
+// values.
+func parseNVTFamilies(raw string) ([]NVTFamily, error) {
+	var parsed openVASGetNVTFamiliesXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_nvt_families_response XML: %w", err)
+	}
+
+	families := make([]NVTFamily, 0, len(parsed.Families))
+	for _, f := range parsed.Families {
+		count, _ := strconv.Atoi(strings.TrimSpace(f.MaxNVTCount))
+		families = append(families, NVTFamily{
+			Name:     strings.TrimSpace(f.Name),
+			NVTCount: count,
+		})
+	}
+	return families, nil
+}
+
+// internal XML structs for parsing <get_nvts/> output.
+type openVASGetNVTsXML struct {
+	NVTs []struct {
+		OID      string `xml:"oid,attr"`
+		Name     string `xml:"name"`
+		Family   string `xml:"family"`
+		CVSSBase string `xml:"cvss_base"`
+	} `xml:"nvt"`
+}
+
+// parseNVTs converts a raw get_nvts_response into NVT values. NVTs without a
+// CVSS base score get 0.
+func parseNVTs(raw string) ([]NVT, error) {
+	var parsed openVASGetNVTsXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_nvts_response XML: %w", err)
+	}
+
+	nvts := make([]NVT, 0, len(parsed.NVTs))
+	for _, n := range parsed.NVTs {
+		cvss, _ := strconv.ParseFloat(strings.TrimSpace(n.CVSSBase), 64)
+		nvts = append(nvts, NVT{
+			OID:      strings.TrimSpace(n.OID),
+			Name:     strings.TrimSpace(n.Name),
+			Family:   strings.TrimSpace(n.Family),
+			CVSSBase: cvss,
+		})
+	}
+	return nvts, nil
+}
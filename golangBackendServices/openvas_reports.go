@@ -0,0 +1,428 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReportResult is a single finding from an OpenVAS report. Severity already
+// reflects any active override; OriginalSeverity keeps the scanner's score
+// when an override changed it.
+type ReportResult struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Host             string   `json:"host"`
+	Port             string   `json:"port,omitempty"`
+	NVTOID           string   `json:"nvt_oid,omitempty"`
+	Severity         float64  `json:"severity"`
+	OriginalSeverity *float64 `json:"original_severity,omitempty"`
+	Overridden       bool     `json:"overridden,omitempty"`
+	Description      string   `json:"description,omitempty"`
+	// QOD is the quality of detection, 0-100: how sure the scanner is that
+	// the finding is real.
+	QOD int `json:"qod,omitempty"`
+}
+
+// internal XML structs for parsing results out of <get_reports/> output.
+type openVASReportResponseXML struct {
+	Results []openVASResultXML `xml:"report>report>results>result"`
+	// ResultCount holds the number of results in the whole report (full)
+	// and matching the filter before paging (filtered).
+	ResultCount struct {
+		Full     string `xml:"full"`
+		Filtered string `xml:"filtered"`
+	} `xml:"report>report>result_count"`
+}
+
+type openVASResultXML struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name"`
+	Host string `xml:"host"`
+	Port string `xml:"port"`
+	NVT  struct {
+		OID string `xml:"oid,attr"`
+		// CVE is the comma-separated list older gvmd versions report;
+		// newer ones use refs of type "cve" instead.
+		CVE  string `xml:"cve"`
+		Refs []struct {
+			Type string `xml:"type,attr"`
+			ID   string `xml:"id,attr"`
+		} `xml:"refs>ref"`
+	} `xml:"nvt"`
+	Severity         string               `xml:"severity"`
+	OriginalSeverity string               `xml:"original_severity"`
+	QOD              string               `xml:"qod>value"`
+	Overrides        []openVASOverrideXML `xml:"overrides>override"`
+	Description      string               `xml:"description"`
+}
+
+// openVASGetResultsResponseXML is the <get_results_response/> envelope.
+type openVASGetResultsResponseXML struct {
+	Results []openVASResultXML `xml:"result"`
+}
+
+type openVASOverrideXML struct {
+	ID          string `xml:"id,attr"`
+	NewSeverity string `xml:"new_severity"`
+	Active      string `xml:"active"`
+}
+
+// parseReportResults extracts the findings from a raw get_reports_response.
+// When a result carries an active override its new severity wins, so false
+// positives and re-scored findings don't resurface in summaries.
+func parseReportResults(raw string) ([]ReportResult, error) {
+	var parsed openVASReportResponseXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_reports_response XML: %w", err)
+	}
+
+	return reportResultsFromXML(parsed.Results), nil
+}
+
+// parseReportResultCount returns how many results of a raw
+// get_reports_response matched its filter, ignoring rows= paging, so callers
+// can tell whether a capped report was truncated.
+func parseReportResultCount(raw string) (int, error) {
+	var parsed openVASReportResponseXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return 0, fmt.Errorf("failed to parse get_reports_response XML: %w", err)
+	}
+
+	count := strings.TrimSpace(parsed.ResultCount.Filtered)
+	if count == "" {
+		count = strings.TrimSpace(parsed.ResultCount.Full)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, fmt.Errorf("invalid result_count %q in get_reports_response", count)
+	}
+	return n, nil
+}
+
+// cvePattern matches a CVE identifier such as CVE-2021-44228.
+var cvePattern = regexp.MustCompile(`^CVE-[0-9]{4}-[0-9]{4,}$`)
+
+// parseReportCVEs returns the CVEs referenced by the NVTs of a raw
+// get_reports_response, deduplicated and sorted.
+func parseReportCVEs(raw string) ([]string, error) {
+	var parsed openVASReportResponseXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_reports_response XML: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	add := func(id string) {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if cvePattern.MatchString(id) {
+			seen[id] = true
+		}
+	}
+	for _, r := range parsed.Results {
+		for _, ref := range r.NVT.Refs {
+			if strings.EqualFold(ref.Type, "cve") {
+				add(ref.ID)
+			}
+		}
+		// "NOCVE" and other placeholders fail the pattern and are dropped.
+		for _, id := range strings.Split(r.NVT.CVE, ",") {
+			add(id)
+		}
+	}
+
+	cves := make([]string, 0, len(seen))
+	for id := range seen {
+		cves = append(cves, id)
+	}
+	sort.Strings(cves)
+	return cves, nil
+}
+
+// parseResults extracts the findings from a raw get_results_response.
+func parseResults(raw string) ([]ReportResult, error) {
+	var parsed openVASGetResultsResponseXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_results_response XML: %w", err)
+	}
+	return reportResultsFromXML(parsed.Results), nil
+}
+
+// sortResultsBySeverity orders results most severe first, keeping gvmd's
+// order among equal severities.
+func sortResultsBySeverity(results []ReportResult) {
+	sort.SliceStable(results, func(i, k int) bool {
+		return results[i].Severity > results[k].Severity
+	})
+}
+
+// reportResultsFromXML converts parsed <result/> elements, applying the first
+// active override to each.
+func reportResultsFromXML(parsed []openVASResultXML) []ReportResult {
+	results := make([]ReportResult, 0, len(parsed))
+	for _, r := range parsed {
+		res := ReportResult{
+			ID:          r.ID,
+			Name:        strings.TrimSpace(r.Name),
+			Host:        strings.TrimSpace(r.Host),
+			Port:        strings.TrimSpace(r.Port),
+			NVTOID:      strings.TrimSpace(r.NVT.OID),
+			Severity:    parseSeverity(r.Severity),
+			Description: strings.TrimSpace(r.Description),
+		}
+		res.QOD, _ = strconv.Atoi(strings.TrimSpace(r.QOD))
+
+		scanned := res.Severity
+		if orig := strings.TrimSpace(r.OriginalSeverity); orig != "" {
+			scanned = parseSeverity(orig)
+		}
+
+		for _, o := range r.Overrides {
+			if strings.TrimSpace(o.Active) == "0" || strings.TrimSpace(o.NewSeverity) == "" {
+				continue
+			}
+			res.Severity = parseSeverity(o.NewSeverity)
+			break
+		}
+
+		if res.Severity != scanned {
+			res.Overridden = true
+			res.OriginalSeverity = &scanned
+		}
+
+		results = append(results, res)
+	}
+	return results
+}
+
+// ReportSummary is the overview of a single report returned by ListReports.
+type ReportSummary struct {
+	ID          string  `json:"id"`
+	TaskID      string  `json:"task_id,omitempty"`
+	TaskName    string  `json:"task_name"`
+	ScanStart   string  `json:"scan_start,omitempty"`
+	ScanEnd     string  `json:"scan_end,omitempty"`
+	Severity    float64 `json:"severity"`
+	ResultCount int     `json:"result_count"`
+}
+
+// internal XML structs for parsing a <get_reports details='0'/> listing. The
+// outer <report> carries the task, the inner one the scan details.
+type openVASReportListXML struct {
+	Reports []openVASReportListEntryXML `xml:"report"`
+}
+
+type openVASReportListEntryXML struct {
+	ID   string `xml:"id,attr"`
+	Task struct {
+		ID   string `xml:"id,attr"`
+		Name string `xml:"name"`
+	} `xml:"task"`
+	Report struct {
+		ScanStart   string `xml:"scan_start"`
+		ScanEnd     string `xml:"scan_end"`
+		ResultCount struct {
+			Full string `xml:"full"`
+		} `xml:"result_count"`
+		Severity struct {
+			Full string `xml:"full"`
+		} `xml:"severity"`
+	} `xml:"report"`
+}
+
+// parseReportSummaries converts a raw get_reports_response into
+// ReportSummary values.
+func parseReportSummaries(raw string) ([]ReportSummary, error) {
+	var parsed openVASReportListXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_reports_response XML: %w", err)
+	}
+
+	reports := make([]ReportSummary, 0, len(parsed.Reports))
+	for _, r := range parsed.Reports {
+		count, _ := strconv.Atoi(strings.TrimSpace(r.Report.ResultCount.Full))
+		reports = append(reports, ReportSummary{
+			ID:          r.ID,
+			TaskID:      r.Task.ID,
+			TaskName:    strings.TrimSpace(r.Task.Name),
+			ScanStart:   strings.TrimSpace(r.Report.ScanStart),
+			ScanEnd:     strings.TrimSpace(r.Report.ScanEnd),
+			Severity:    parseSeverity(r.Report.Severity.Full),
+			ResultCount: count,
+		})
+	}
+	return reports, nil
+}
+
+// ReportSeveritySummary is a compact view of a report: how many results fall
+// into each GVM severity bucket and the most severe findings.
+type ReportSeveritySummary struct {
+	ReportID       string         `json:"report_id"`
+	Total          int            `json:"total"`
+	High           int            `json:"high"`
+	Medium         int            `json:"medium"`
+	Low            int            `json:"low"`
+	Log            int            `json:"log"`
+	FalsePositives int            `json:"false_positives,omitempty"`
+	TopFindings    []ReportResult `json:"top_findings"`
+}
+
+// severityBucket returns the GVM severity class of a CVSS score: High from
+// 7.0, Medium from 4.0, Low above 0, Log at 0 and "false_positive" for the
+// negative scores overrides use.
+func severityBucket(severity float64) string {
+	switch {
+	case severity >= 7.0:
+		return "high"
+	case severity >= 4.0:
+		return "medium"
+	case severity > 0:
+		return "low"
+	case severity == 0:
+		return "log"
+	default:
+		return "false_positive"
+	}
+}
+
+// summarizeResults counts results per severity bucket and returns the topN
+// highest-scoring findings, ignoring log-level and false positive results.
+func summarizeResults(results []ReportResult, topN int) ReportSeveritySummary {
+	summary := ReportSeveritySummary{TopFindings: []ReportResult{}}
+	var findings []ReportResult
+	for _, r := range results {
+		switch severityBucket(r.Severity) {
+		case "high":
+			summary.High++
+		case "medium":
+			summary.Medium++
+		case "low":
+			summary.Low++
+		case "log":
+			summary.Log++
+			continue
+		default:
+			summary.FalsePositives++
+			continue
+		}
+		findings = append(findings, r)
+	}
+	summary.Total = len(results)
+
+	sortResultsBySeverity(findings)
+	if topN >= 0 && len(findings) > topN {
+		findings = findings[:topN]
+	}
+	summary.TopFindings = append(summary.TopFindings, findings...)
+	return summary
+}
+
+// parseSeverity converts a GMP severity string to a float, treating anything
+// unparsable as 0 (log level).
+func parseSeverity(raw string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// reportFormats maps friendly export names to the well-known report format
+// UUIDs shipped with GVM, along with the Content-Type and file extension
+// used when serving the decoded report.
+var reportFormats = map[string]struct {
+	id          string
+	contentType string
+	extension   string
+}{
+	"pdf":  {id: "c402cc3e-b531-11e1-9163-406186ea4fc5", contentType: "application/pdf", extension: "pdf"},
+	"csv":  {id: "c1645568-627a-11e3-a660-406186ea4fc5", contentType: "text/csv", extension: "csv"},
+	"html": {id: "6c248850-1f62-11e1-b082-406186ea4fc5", contentType: "text/html", extension: "html"},
+	"txt":  {id: "a3810a62-1f62-11e1-9219-406186ea4fc5", contentType: "text/plain", extension: "txt"},
+}
+
+// ReportFormat is a report format installed in gvmd.
+type ReportFormat struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Extension   string `json:"extension"`
+	ContentType string `json:"content_type"`
+}
+
+// internal XML structs for parsing <get_report_formats/> output.
+type openVASGetReportFormatsXML struct {
+	ReportFormats []struct {
+		ID          string `xml:"id,attr"`
+		Name        string `xml:"name"`
+		Extension   string `xml:"extension"`
+		ContentType string `xml:"content_type"`
+	} `xml:"report_format"`
+}
+
+// parseReportFormats converts a raw get_report_formats_response into
+// ReportFormat values.
+func parseReportFormats(raw string) ([]ReportFormat, error) {
+	var parsed openVASGetReportFormatsXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_report_formats_response XML: %w", err)
+	}
+
+	formats := make([]ReportFormat, 0, len(parsed.ReportFormats))
+	for _, f := range parsed.ReportFormats {
+		formats = append(formats, ReportFormat{
+			ID:          f.ID,
+			Name:        strings.TrimSpace(f.Name),
+			Extension:   strings.TrimSpace(f.Extension),
+			ContentType: strings.TrimSpace(f.ContentType),
+		})
+	}
+	return formats, nil
+}
+
+// matchReportFormat finds the format whose name, or failing that whose file
+// extension, equals name (case-insensitively).
+func matchReportFormat(formats []ReportFormat, name string) (ReportFormat, bool) {
+	for _, f := range formats {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	for _, f := range formats {
+		if strings.EqualFold(f.Extension, name) {
+			return f, true
+		}
+	}
+	return ReportFormat{}, false
+}
+
+// decodeReportContent extracts and base64-decodes the rendered report from a
+// get_reports_response for a non-XML report format. It also returns the
+// content type gvmd advertised for the format, if any.
+func decodeReportContent(raw string) ([]byte, string, error) {
+	type formattedReportXML struct {
+		ContentType string `xml:"content_type,attr"`
+		Content     string `xml:",chardata"`
+	}
+	type formattedReportResponseXML struct {
+		Report formattedReportXML `xml:"report"`
+	}
+
+	var parsed formattedReportResponseXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, "", fmt.Errorf("failed to parse get_reports_response XML: %w", err)
+	}
+
+	encoded := strings.Join(strings.Fields(parsed.Report.Content), "")
+	if encoded == "" {
+		return nil, "", fmt.Errorf("report content is empty")
+	}
+
+	content, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode report content: %w", err)
+	}
+	return content, strings.TrimSpace(parsed.Report.ContentType), nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseReportResultsOverrides(t *testing.T) {
+	raw, err := os.ReadFile("testdata/get_reports_overrides.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := parseReportResults(string(raw))
+	if err != nil {
+		t.Fatalf("parseReportResults: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		severity   float64
+		original   float64
+		overridden bool
+	}{
+		{"OpenSSH Multiple Vulnerabilities", 9.8, 0, false},
+		// A false positive override replaces the severity with -1.
+		{"Apache HTTP Server Information Disclosure", -1, 7.5, true},
+		// Inactive overrides are skipped in favour of the active one.
+		{"TLS Weak Cipher Suites", 3.1, 5.0, true},
+		// gvmd already applied the override and kept the scanner's score.
+		{"MySQL Default Credentials", 4.3, 8.1, true},
+		{"SMB Signing Not Required", 6.4, 0, false},
+	}
+	if len(results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(results), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := results[i]
+			if r.Name != tt.name {
+				t.Fatalf("result %d is %q", i, r.Name)
+			}
+			if r.Severity != tt.severity {
+				t.Errorf("severity = %v, want %v", r.Severity, tt.severity)
+			}
+			if r.Overridden != tt.overridden {
+				t.Errorf("overridden = %v, want %v", r.Overridden, tt.overridden)
+			}
+			switch {
+			case !tt.overridden && r.OriginalSeverity != nil:
+				t.Errorf("original severity = %v, want none", *r.OriginalSeverity)
+			case tt.overridden && (r.OriginalSeverity == nil || *r.OriginalSeverity != tt.original):
+				t.Errorf("original severity = %v, want %v", r.OriginalSeverity, tt.original)
+			}
+		})
+	}
+
+	summary := summarizeResults(results, 10)
+	if summary.High != 1 || summary.Medium != 2 || summary.Low != 1 || summary.FalsePositives != 1 {
+		t.Errorf("summary = %+v, want 1 high, 2 medium, 1 low, 1 false positive", summary)
+	}
+	for _, f := range summary.TopFindings {
+		if f.Severity < 0 {
+			t.Errorf("false positive %q listed as a top finding", f.Name)
+		}
+	}
+}
@@ -1,35 +1,311 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // OpenVASService encapsulates calls to gvm-cli (OpenVAS/GVM).
 // We start with just GetVersion to keep the design modular and focused.
 type OpenVASService struct {
+	// RunMode selects where gvm-cli runs: GVMRunModeDocker (inside
+	// ContainerName via docker exec) or GVMRunModeLocal (on PATH).
+	RunMode       string
 	ContainerName string
 	Username      string
 	Password      string
 	Host          string
 	Port          string
+	// ConnectionMode selects how gvm-cli reaches gvmd: GVMConnectionTLS
+	// (Host and Port) or GVMConnectionSocket (SocketPath inside the
+	// container).
+	ConnectionMode string
+	SocketPath     string
+	// MaxAttempts and RetryDelay control how runGMP retries gvm-cli calls
+	// that fail to reach gvmd. The delay doubles after every attempt.
+	MaxAttempts int
+	RetryDelay  time.Duration
+	// CheckTaskRefs makes CreateTask confirm that the config and target
+	// exist before sending create_task, at the cost of two extra calls.
+	CheckTaskRefs bool
+
+	// caches holds configs, scanners and report formats between calls; nil
+	// disables caching.
+	caches *openVASListingCaches
+	// audit records every task start and resume; nil disables auditing.
+	audit *auditLog
 }
 
+// gvm-cli connection types supported by OpenVASService.
+const (
+	GVMConnectionTLS    = "tls"
+	GVMConnectionSocket = "socket"
+)
+
+// Where gvm-cli is run from. In docker mode it is run inside the OpenVAS
+// container with docker exec; in local mode it is installed on the host.
+const (
+	GVMRunModeDocker = "docker"
+	GVMRunModeLocal  = "local"
+)
+
+// commandPrefix returns the program and leading arguments that start
+// gvm-cli in the service's run mode.
+func (s *OpenVASService) commandPrefix() (string, []string) {
+	if s.RunMode == GVMRunModeLocal {
+		return "gvm-cli", nil
+	}
+	return "docker", []string{"exec", "-u", "gvm", s.ContainerName, "gvm-cli"}
+}
+
+// buildGVMArgs returns the program and arguments that run gvm-cli with the
+// service's credentials and connection, sending xmlBody as the GMP command.
+func (s *OpenVASService) buildGVMArgs(xmlBody string) (string, []string) {
+	name, args := s.commandPrefix()
+	args = append(args,
+		"--gmp-username", s.Username,
+		"--gmp-password", s.Password,
+	)
+	args = append(args, s.connectionArgs()...)
+	return name, append(args, "--xml", xmlBody)
+}
+
+// runGMP sends a single GMP command through gvm-cli and returns its combined
+// output. Callers wrap the error with the command name. During a dry run
+// the command is only recorded and answered by dryRunGMPResponse.
+//
+// Calls that fail because gvmd couldn't be reached (e.g. while it restarts)
+// are retried with exponential backoff; GMP-level errors such as a missing
+// resource are returned straight away as a *GMPError. Whether docker or
+// gvmd failed is decided from docker's exit status and its own stderr, so
+// a gvmd error message that happens to mention e.g. "is not running" is
+// still reported as a GMP error.
+func (s *OpenVASService) runGMP(ctx context.Context, xmlBody string) ([]byte, error) {
+	if d := gmpDryRunFromContext(ctx); d != nil {
+		d.record(xmlBody)
+		return dryRunGMPResponse(xmlBody)
+	}
+
+	name, args := s.buildGVMArgs(xmlBody)
+	attempts := max(s.MaxAttempts, 1)
+	delay := s.RetryDelay
+	for attempt := 1; ; attempt++ {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		out := append(stdout.Bytes(), stderr.Bytes()...)
+
+		if err != nil && s.RunMode != GVMRunModeLocal && isContainerUnavailable(err, stderr.Bytes()) {
+			return out, fmt.Errorf("%w: %s", ErrContainerUnavailable, strings.TrimSpace(stderr.String()))
+		}
+		gerr := gmpResponseError(out, err)
+		if err == nil || gerr != nil || attempt >= attempts || ctx.Err() != nil || !isTransientGVMError(stderr.Bytes()) {
+			if gerr != nil {
+				return out, gerr
+			}
+			return out, err
+		}
+
+		log.Printf("gvm-cli attempt %d/%d could not reach gvmd: %v; retrying in %s", attempt, attempts, err, delay)
+		select {
+		case <-ctx.Done():
+			return out, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// transientGVMErrors are fragments of gvm-cli's stderr that mean gvmd could
+// not be reached, as opposed to gvmd answering with an error.
+var transientGVMErrors = []string{
+	"connection refused",
+	"connection reset",
+	"timed out",
+	"timeout",
+	"broken pipe",
+	"temporarily unavailable",
+	"no such file or directory",
+}
+
+// containerUnavailableErrors are fragments of docker's own error message
+// that mean the OpenVAS container isn't there to exec into.
+var containerUnavailableErrors = []string{
+	"no such container",
+	"is not running",
+	"is paused",
+}
+
+// dockerErrorPrefixes start the errors the docker CLI itself prints, as
+// opposed to output from the command run inside the container.
+var dockerErrorPrefixes = []string{
+	"error response from daemon:",
+	"error: ",
+}
+
+// isContainerUnavailable reports whether docker exec, rather than gvm-cli
+// inside the container, failed because the OpenVAS container (or docker
+// itself) couldn't be reached. docker exits 125 when it can't run the
+// command at all; otherwise the first line of stderr must be docker's own
+// error naming the container's state.
+func isContainerUnavailable(runErr error, stderr []byte) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(runErr, &exitErr) {
+		return false
+	}
+	if exitErr.ExitCode() == 125 {
+		return true
+	}
+
+	line, _, _ := strings.Cut(strings.TrimSpace(string(stderr)), "\n")
+	line = strings.ToLower(strings.TrimSpace(line))
+	if strings.HasPrefix(line, "cannot connect to the docker daemon") {
+		return true
+	}
+	for _, prefix := range dockerErrorPrefixes {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		for _, frag := range containerUnavailableErrors {
+			if strings.Contains(line, frag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isTransientGVMError reports whether gvm-cli's stderr describes a
+// connection failure worth retrying. It is only consulted when gvmd didn't
+// answer with a GMP status.
+func isTransientGVMError(stderr []byte) bool {
+	text := strings.ToLower(string(stderr))
+	for _, frag := range transientGVMErrors {
+		if strings.Contains(text, frag) {
+			return true
+		}
+	}
+	return false
+}
+
+// connectionArgs returns the gvm-cli connection type and its options.
+func (s *OpenVASService) connectionArgs() []string {
+	if s.ConnectionMode == GVMConnectionSocket {
+		return []string{GVMConnectionSocket, "--socketpath", s.SocketPath}
+	}
+	return []string{GVMConnectionTLS, "--hostname", s.Host, "--port", s.Port}
+}
+
+// ErrTargetInUse is returned by DeleteTarget when gvmd refuses to delete a
+// target because a task still references it. Delete the task first.
+var ErrTargetInUse = errors.New("target is in use by a task")
+
+// ErrContainerUnavailable is returned by every gvm-cli call when docker
+// reports that the OpenVAS container doesn't exist or isn't running.
+var ErrContainerUnavailable = errors.New("OpenVAS container not available")
+
+// ErrTargetNotFound is returned by GetTarget when gvmd has no target with
+// the requested ID.
+var ErrTargetNotFound = errors.New("target not found")
+
+// ErrTargetPortsDiffer is returned by CreateTarget when a target with the
+// same name and hosts exists but scans other ports.
+var ErrTargetPortsDiffer = errors.New("target exists with different ports")
+
+// ErrConfigNotFound is returned by GetConfigDetails when gvmd has no scan
+// config with the requested ID.
+var ErrConfigNotFound = errors.New("config not found")
+
+// ErrReportInUse is returned by DeleteReport when gvmd refuses to delete a
+// report, typically because its task is still running or the report is the
+// task's only run.
+var ErrReportInUse = errors.New("report is in use by a task")
+
+// ErrTaskNotRunning is returned by StopTask when the task has already been
+// stopped or has finished, so there is nothing to stop.
+var ErrTaskNotRunning = errors.New("task is not running")
+
+// ErrTaskNotModifiable is returned by ModifyTask when gvmd refuses the
+// change, typically because the task has already run.
+var ErrTaskNotModifiable = errors.New("task cannot be modified")
+
+// ErrAuthFailed is returned by Authenticate when gvmd rejects the configured
+// credentials.
+var ErrAuthFailed = errors.New("GVM authentication failed")
+
+// ErrInvalidID is returned when a resource ID isn't a well-formed GVM UUID.
+// IDs are validated before being placed into GMP XML so that malformed
+// input can't break the request or inject elements.
+var ErrInvalidID = errors.New("invalid GVM ID")
+
+// ErrInvalidSchedule is returned by CreateSchedule when the iCalendar data is
+// malformed or gvmd rejects it.
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// ErrUnknownReportFormat is returned by ResolveReportFormat when gvmd has no
+// report format with the requested name or extension.
+var ErrUnknownReportFormat = errors.New("unknown report format")
+
+// ErrUnknownConfig is returned by ResolveConfigID when no scan config has the
+// requested name.
+var ErrUnknownConfig = errors.New("unknown scan config")
+
+// ErrTaskRefNotFound is returned by CreateTask, when CheckTaskRefs is set,
+// if the config or target it was given doesn't exist.
+var ErrTaskRefNotFound = errors.New("referenced resource not found")
+
+// gvmIDPattern matches the 8-4-4-4-12 hex UUIDs gvmd uses for resources.
+var gvmIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ErrTaskStopped is returned by WaitForTask when the task ended without
+// completing, e.g. because it was stopped or interrupted.
+var ErrTaskStopped = errors.New("task ended without completing")
+
+// minTaskPollInterval keeps WaitForTask from hammering gvmd.
+const minTaskPollInterval = time.Second
+
+// defaultTaskPollInterval is how often handlers that wait for a task poll it
+// unless told otherwise.
+const defaultTaskPollInterval = 10 * time.Second
+
 // NewOpenVASServiceFromEnv builds a service using environment variables.
 //
 // Required:
 //   - GVM_PASSWORD
 //
 // Optional (with defaults):
-//   - OPENVAS_CONTAINER_NAME (default: "openvas")
+//   - GVM_RUN_MODE          (default: "docker"; or "local" for gvm-cli on PATH)
+//   - OPENVAS_CONTAINER_NAME (default: "openvas"; docker mode only)
 //   - GVM_USERNAME          (default: "admin")
 //   - GVM_HOST              (default: "127.0.0.1")
 //   - GVM_PORT              (default: "9390")
+//   - GVM_CONNECTION        (default: "tls"; or "socket")
+//   - GVM_SOCKET_PATH       (default: "/run/gvmd/gvmd.sock")
+//   - GVM_MAX_ATTEMPTS      (default: 3; 1 disables retries)
+//   - GVM_RETRY_DELAY       (default: "1s")
+//   - OPENVAS_CACHE_TTL     (default: "5m")
 func NewOpenVASServiceFromEnv() *OpenVASService {
+	runMode := strings.ToLower(strings.TrimSpace(os.Getenv("GVM_RUN_MODE")))
+	switch runMode {
+	case "":
+		runMode = GVMRunModeDocker
+	case GVMRunModeDocker, GVMRunModeLocal:
+	default:
+		log.Printf("invalid GVM_RUN_MODE=%q, using %s", runMode, GVMRunModeDocker)
+		runMode = GVMRunModeDocker
+	}
+
 	container := os.Getenv("OPENVAS_CONTAINER_NAME")
 	if container == "" {
 		container = "openvas"
@@ -52,13 +328,81 @@ func NewOpenVASServiceFromEnv() *OpenVASService {
 		port = "9390"
 	}
 
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("GVM_CONNECTION")))
+	switch mode {
+	case "":
+		mode = GVMConnectionTLS
+	case GVMConnectionTLS, GVMConnectionSocket:
+	default:
+		log.Printf("invalid GVM_CONNECTION=%q, using %s", mode, GVMConnectionTLS)
+		mode = GVMConnectionTLS
+	}
+
+	socketPath := os.Getenv("GVM_SOCKET_PATH")
+	if socketPath == "" {
+		socketPath = "/run/gvmd/gvmd.sock"
+	}
+
 	return &OpenVASService{
-		ContainerName: container,
-		Username:      username,
-		Password:      password,
-		Host:          host,
-		Port:          port,
+		RunMode:        runMode,
+		ContainerName:  container,
+		Username:       username,
+		Password:       password,
+		Host:           host,
+		Port:           port,
+		ConnectionMode: mode,
+		SocketPath:     socketPath,
+		MaxAttempts:    envInt("GVM_MAX_ATTEMPTS", 3),
+		RetryDelay:     envDuration("GVM_RETRY_DELAY", time.Second),
+		CheckTaskRefs:  envBool("GVM_CHECK_TASK_REFS", false),
+		caches:         newOpenVASListingCaches(envDuration("OPENVAS_CACHE_TTL", defaultOpenVASCacheTTL)),
+	}
+}
+
+// Authenticate checks the configured username and password against gvmd
+// using <authenticate>. Rejected credentials return an error wrapping
+// ErrAuthFailed with gvmd's status_text.
+func (s *OpenVASService) Authenticate(ctx context.Context) error {
+	if s.Password == "" {
+		return fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	type credentialsXML struct {
+		Username string `xml:"username"`
+		Password string `xml:"password"`
+	}
+	type authenticateXML struct {
+		XMLName     xml.Name       `xml:"authenticate"`
+		Credentials credentialsXML `xml:"credentials"`
+	}
+
+	xmlBody, err := marshalGMP(authenticateXML{Credentials: credentialsXML{Username: s.Username, Password: s.Password}})
+	if err != nil {
+		return err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+
+	st, parseErr := parseGMPStatus(out)
+	if parseErr != nil {
+		// gvm-cli authenticates before sending the command, so bad
+		// credentials usually surface as its own error message.
+		if err != nil && strings.Contains(strings.ToLower(string(out)), "authentication failed") {
+			return fmt.Errorf("%w: %s", ErrAuthFailed, strings.TrimSpace(string(out)))
+		}
+		if err != nil {
+			return fmt.Errorf("gvm-cli authenticate failed: %w; output: %s", err, string(out))
+		}
+		return parseErr
 	}
+	if st.Status == "400" {
+		return fmt.Errorf("%w: %s", ErrAuthFailed, st.StatusText)
+	}
+	if !strings.HasPrefix(st.Status, "2") {
+		return fmt.Errorf("authenticate failed: %w", newGMPError(st))
+	}
+
+	return nil
 }
 
 // GetVersion calls gvm-cli with <get_version/> and returns the raw XML
@@ -68,21 +412,7 @@ func (s *OpenVASService) GetVersion(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("GVM_PASSWORD is not set")
 	}
 
-	args := []string{
-		"exec",
-		"-u", "gvm",
-		s.ContainerName,
-		"gvm-cli",
-		"--gmp-username", s.Username,
-		"--gmp-password", s.Password,
-		"tls",
-		"--hostname", s.Host,
-		"--port", s.Port,
-		"--xml", "<get_version/>",
-	}
-
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	out, err := cmd.CombinedOutput()
+	out, err := s.runGMP(ctx, "<get_version/>")
 	if err != nil {
 		return "", fmt.Errorf("gvm-cli get_version failed: %w; output: %s", err, string(out))
 	}
@@ -90,6 +420,34 @@ func (s *OpenVASService) GetVersion(ctx context.Context) (string, error) {
 	return string(out), nil
 }
 
+// OpenVASVersion is the parsed <get_version_response/>.
+type OpenVASVersion struct {
+	Version    string `json:"version"`
+	Status     string `json:"status"`
+	StatusText string `json:"status_text,omitempty"`
+}
+
+// parseVersion extracts the GMP version and response status from a raw
+// get_version_response.
+func parseVersion(raw string) (OpenVASVersion, error) {
+	type getVersionResponseXML struct {
+		XMLName    xml.Name `xml:"get_version_response"`
+		Status     string   `xml:"status,attr"`
+		StatusText string   `xml:"status_text,attr"`
+		Version    string   `xml:"version"`
+	}
+
+	var parsed getVersionResponseXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return OpenVASVersion{}, fmt.Errorf("failed to parse get_version_response XML: %w", err)
+	}
+	return OpenVASVersion{
+		Version:    strings.TrimSpace(parsed.Version),
+		Status:     parsed.Status,
+		StatusText: parsed.StatusText,
+	}, nil
+}
+
 // GetConfigs calls gvm-cli with <get_configs/> and returns the raw XML
 // response listing all available scan configurations.
 func (s *OpenVASService) GetConfigs(ctx context.Context) (string, error) {
@@ -97,21 +455,7 @@ func (s *OpenVASService) GetConfigs(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("GVM_PASSWORD is not set")
 	}
 
-	args := []string{
-		"exec",
-		"-u", "gvm",
-		s.ContainerName,
-		"gvm-cli",
-		"--gmp-username", s.Username,
-		"--gmp-password", s.Password,
-		"tls",
-		"--hostname", s.Host,
-		"--port", s.Port,
-		"--xml", "<get_configs/>",
-	}
-
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	out, err := cmd.CombinedOutput()
+	out, err := s.runGMP(ctx, "<get_configs/>")
 	if err != nil {
 		return "", fmt.Errorf("gvm-cli get_configs failed: %w; output: %s", err, string(out))
 	}
@@ -119,23 +463,184 @@ func (s *OpenVASService) GetConfigs(ctx context.Context) (string, error) {
 	return string(out), nil
 }
 
+// ListConfigs returns all available scan configurations as typed structs,
+// parsed from the <get_configs/> response.
+func (s *OpenVASService) ListConfigs(ctx context.Context) ([]OpenVASConfig, error) {
+	raw, err := s.GetConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigs(raw)
+}
+
+// ResolveConfigID returns the ID of the scan config whose name matches name,
+// ignoring case, e.g. "Full and fast". When nothing matches the error wraps
+// ErrUnknownConfig and lists the available names.
+func (s *OpenVASService) ResolveConfigID(ctx context.Context, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("config name is required")
+	}
+
+	configs, _, err := s.CachedConfigs(ctx, false)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(configs))
+	for _, c := range configs {
+		if strings.EqualFold(c.Name, name) {
+			return c.ID, nil
+		}
+		names = append(names, strconv.Quote(c.Name))
+	}
+	return "", fmt.Errorf("%w %q; available configs: %s", ErrUnknownConfig, name, strings.Join(names, ", "))
+}
+
+// GetConfigDetails returns scan config configID with its enabled NVT
+// families and preferences, using <get_configs config_id='...' families='1'
+// preferences='1'/>. It returns ErrConfigNotFound if gvmd has no such config.
+func (s *OpenVASService) GetConfigDetails(ctx context.Context, configID string) (ConfigDetails, error) {
+	if s.Password == "" {
+		return ConfigDetails{}, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	configID = strings.TrimSpace(configID)
+	if configID == "" {
+		return ConfigDetails{}, fmt.Errorf("configID is required")
+	}
+	if err := validateGVMID("configID", configID); err != nil {
+		return ConfigDetails{}, err
+	}
+
+	xmlBody, err := marshalGMP(gmpGetConfigDetailsXML{ConfigID: configID, Families: 1, Preferences: 1})
+	if err != nil {
+		return ConfigDetails{}, err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		var gmpErr *GMPError
+		if errors.As(err, &gmpErr) && gmpErr.Status == 404 {
+			return ConfigDetails{}, fmt.Errorf("%w: %s", ErrConfigNotFound, configID)
+		}
+		return ConfigDetails{}, fmt.Errorf("gvm-cli get_configs failed: %w; output: %s", err, string(out))
+	}
+
+	return parseConfigDetails(string(out), configID)
+}
+
+// DefaultScannerID is the UUID of the built-in "OpenVAS Default" scanner,
+// used by CreateTask when no scanner is given.
+const DefaultScannerID = "08b69003-5fc2-4037-a479-93b440211c73"
+
+// OpenVASScanner is a scanner gvmd can run tasks with, e.g. the default
+// OpenVAS scanner or the CVE scanner.
+type OpenVASScanner struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// gvmScannerTypes names the numeric scanner types reported by gvmd.
+var gvmScannerTypes = map[string]string{
+	"1": "osp",
+	"2": "openvas",
+	"3": "cve",
+	"4": "gmp",
+	"5": "osp-sensor",
+}
+
+// internal XML structs for parsing <get_scanners/> output.
+type openVASGetScannersXML struct {
+	Scanners []struct {
+		ID   string `xml:"id,attr"`
+		Name string `xml:"name"`
+		Type string `xml:"type"`
+	} `xml:"scanner"`
+}
+
+// GetScanners lists the scanners available to gvmd using <get_scanners/>.
+func (s *OpenVASService) GetScanners(ctx context.Context) ([]OpenVASScanner, error) {
+	if s.Password == "" {
+		return nil, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	out, err := s.runGMP(ctx, "<get_scanners/>")
+	if err != nil {
+		return nil, fmt.Errorf("gvm-cli get_scanners failed: %w; output: %s", err, string(out))
+	}
+
+	return parseScanners(string(out))
+}
+
+// parseScanners converts a raw get_scanners_response into OpenVASScanner
+// values. Unknown scanner types are reported by their number.
+func parseScanners(raw string) ([]OpenVASScanner, error) {
+	var parsed openVASGetScannersXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_scanners_response XML: %w", err)
+	}
+
+	scanners := make([]OpenVASScanner, 0, len(parsed.Scanners))
+	for _, sc := range parsed.Scanners {
+		typ := strings.TrimSpace(sc.Type)
+		if name, ok := gvmScannerTypes[typ]; ok {
+			typ = name
+		}
+		scanners = append(scanners, OpenVASScanner{
+			ID:   sc.ID,
+			Name: strings.TrimSpace(sc.Name),
+			Type: typ,
+		})
+	}
+	return scanners, nil
+}
+
 // internal XML structs for working with targets.
 type openVASTargetsXML struct {
 	Targets []openVASTargetXML `xml:"target"`
 }
 
 type openVASTargetXML struct {
-	ID       string `xml:"id,attr"`
-	Name     string `xml:"name"`
-	HostsRaw string `xml:"hosts"`
+	ID       string                `xml:"id,attr"`
+	Name     string                `xml:"name"`
+	HostsRaw string                `xml:"hosts"`
+	PortList openVASResourceRefXML `xml:"port_list"`
+}
+
+// CountTargets returns how many targets gvmd has, using
+// <get_targets filter="rows=-1"/> so all of them are counted rather than
+// gvmd's default page.
+func (s *OpenVASService) CountTargets(ctx context.Context) (int, error) {
+	if s.Password == "" {
+		return 0, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	out, err := s.runGMP(ctx, `<get_targets filter="rows=-1"/>`)
+	if err != nil {
+		return 0, fmt.Errorf("gvm-cli get_targets failed: %w; output: %s", err, string(out))
+	}
+
+	var parsed openVASTargetsXML
+	if err := xml.Unmarshal(out, &parsed); err != nil {
+		return 0, fmt.Errorf("failed to parse get_targets_response XML: %w; output: %s", err, string(out))
+	}
+	return len(parsed.Targets), nil
 }
 
 // CreateTarget ensures idempotent target creation:
-//   - If a target with the same name and hosts already exists, it returns
-//     the existing target ID and existed=true.
+//   - If a target with the same name and hosts already exists, and uses the
+//     requested ports, it returns the existing target ID and existed=true.
+//   - If it exists with other ports, ErrTargetPortsDiffer is returned, since
+//     gvmd doesn't allow a second target with the same name.
 //   - Otherwise it creates a new target via <create_target> and returns
 //     the new target ID and existed=false.
-func (s *OpenVASService) CreateTarget(ctx context.Context, name, hosts, portRange string) (id string, existed bool, err error) {
+//
+// Ports come from either an inline portRange or an existing portListID, not
+// both; with neither gvmd uses its default port list and any existing
+// target's ports are accepted.
+func (s *OpenVASService) CreateTarget(ctx context.Context, name, hosts, portRange, portListID string) (id string, existed bool, err error) {
 	if s.Password == "" {
 		return "", false, fmt.Errorf("GVM_PASSWORD is not set")
 	}
@@ -143,55 +648,62 @@ func (s *OpenVASService) CreateTarget(ctx context.Context, name, hosts, portRang
 	name = strings.TrimSpace(name)
 	hosts = strings.TrimSpace(hosts)
 	portRange = strings.TrimSpace(portRange)
+	portListID = strings.TrimSpace(portListID)
 
 	if name == "" || hosts == "" {
 		return "", false, fmt.Errorf("name and hosts are required")
 	}
-
-	// First: check for an existing target with the same name and hosts.
-	getTargetsArgs := []string{
-		"exec",
-		"-u", "gvm",
-		s.ContainerName,
-		"gvm-cli",
-		"--gmp-username", s.Username,
-		"--gmp-password", s.Password,
-		"tls",
-		"--hostname", s.Host,
-		"--port", s.Port,
-		"--xml", "<get_targets/>",
+	if portRange != "" && portListID != "" {
+		return "", false, fmt.Errorf("portRange and portListID are mutually exclusive")
+	}
+	if portListID != "" {
+		if err := validateGVMID("portListID", portListID); err != nil {
+			return "", false, err
+		}
+	}
+	if err := checkTargetsScope(ctx, splitTargetList(hosts)); err != nil {
+		return "", false, err
 	}
 
-	getTargetsCmd := exec.CommandContext(ctx, "docker", getTargetsArgs...)
-	targetsOut, getTargetsErr := getTargetsCmd.CombinedOutput()
-	if getTargetsErr == nil {
+	// First: check for an existing target with the same name and hosts.
+	targetsOut, getTargetsErr := s.runGMP(ctx, "<get_targets/>")
+	if getTargetsErr != nil {
+		addWarning(ctx, "could not list existing targets; a duplicate target may have been created")
+	} else {
 		var parsed openVASTargetsXML
 		if err := xml.Unmarshal(targetsOut, &parsed); err == nil {
-			wantName := strings.TrimSpace(name)
-			wantHosts := strings.TrimSpace(hosts)
-
 			for _, t := range parsed.Targets {
-				if strings.TrimSpace(t.Name) != wantName {
+				if strings.TrimSpace(t.Name) != name {
 					continue
 				}
 
-				// Hosts can be represented in different ways in XML; perform
-				// a relaxed match against the raw hosts XML/text.
-				hostsLower := strings.ToLower(wantHosts)
-				rawLower := strings.ToLower(t.HostsRaw)
-				if hostsLower != "" && strings.Contains(rawLower, hostsLower) {
-					return t.ID, true, nil
+				// Compare host sets exactly so that 10.0.0.1 doesn't match
+				// a target for 10.0.0.10, while a reordered list still does.
+				if !sameHostSet(t.HostsRaw, hosts) {
+					continue
+				}
+				same, err := s.targetHasPorts(ctx, t, portRange, portListID)
+				if err != nil {
+					return "", false, err
 				}
+				if !same {
+					return "", false, fmt.Errorf("%w: target %s (%s) uses port list %q", ErrTargetPortsDiffer, t.ID, name, strings.TrimSpace(t.PortList.Name))
+				}
+				return t.ID, true, nil
 			}
 		}
 	}
 
 	// If we didn't find an existing target (or get_targets failed), create one.
+	type portListRefXML struct {
+		ID string `xml:"id,attr"`
+	}
 	type createTargetXML struct {
-		XMLName   xml.Name `xml:"create_target"`
-		Name      string   `xml:"name"`
-		Hosts     string   `xml:"hosts"`
-		PortRange string   `xml:"port_range,omitempty"`
+		XMLName   xml.Name        `xml:"create_target"`
+		Name      string          `xml:"name"`
+		Hosts     string          `xml:"hosts"`
+		PortRange string          `xml:"port_range,omitempty"`
+		PortList  *portListRefXML `xml:"port_list,omitempty"`
 	}
 
 	payload := createTargetXML{
@@ -201,27 +713,16 @@ func (s *OpenVASService) CreateTarget(ctx context.Context, name, hosts, portRang
 	if portRange != "" {
 		payload.PortRange = portRange
 	}
+	if portListID != "" {
+		payload.PortList = &portListRefXML{ID: portListID}
+	}
 
 	xmlBody, err := xml.Marshal(&payload)
 	if err != nil {
 		return "", false, fmt.Errorf("failed to marshal create_target XML: %w", err)
 	}
 
-	createArgs := []string{
-		"exec",
-		"-u", "gvm",
-		s.ContainerName,
-		"gvm-cli",
-		"--gmp-username", s.Username,
-		"--gmp-password", s.Password,
-		"tls",
-		"--hostname", s.Host,
-		"--port", s.Port,
-		"--xml", string(xmlBody),
-	}
-
-	createCmd := exec.CommandContext(ctx, "docker", createArgs...)
-	createOut, createErr := createCmd.CombinedOutput()
+	createOut, createErr := s.runGMP(ctx, string(xmlBody))
 	if createErr != nil {
 		return "", false, fmt.Errorf("gvm-cli create_target failed: %w; output: %s", createErr, string(createOut))
 	}
@@ -242,16 +743,117 @@ func (s *OpenVASService) CreateTarget(ctx context.Context, name, hosts, portRang
 	return strings.TrimSpace(resp.ID), false, nil
 }
 
+// PortList is a reusable set of ports that targets can reference.
+type PortList struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Comment   string `json:"comment,omitempty"`
+	PortCount int    `json:"port_count"`
+}
+
+// internal XML structs for parsing <get_port_lists/> output.
+type openVASGetPortListsXML struct {
+	PortLists []struct {
+		ID        string `xml:"id,attr"`
+		Name      string `xml:"name"`
+		Comment   string `xml:"comment"`
+		PortCount struct {
+			All string `xml:"all"`
+		} `xml:"port_count"`
+	} `xml:"port_list"`
+}
+
+// GetPortLists lists the port lists known to gvmd using <get_port_lists/>.
+func (s *OpenVASService) GetPortLists(ctx context.Context) ([]PortList, error) {
+	if s.Password == "" {
+		return nil, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	out, err := s.runGMP(ctx, "<get_port_lists/>")
+	if err != nil {
+		return nil, fmt.Errorf("gvm-cli get_port_lists failed: %w; output: %s", err, string(out))
+	}
+
+	return parsePortLists(string(out))
+}
+
+// parsePortLists converts a raw get_port_lists_response into PortList values.
+func parsePortLists(raw string) ([]PortList, error) {
+	var parsed openVASGetPortListsXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_port_lists_response XML: %w", err)
+	}
+
+	lists := make([]PortList, 0, len(parsed.PortLists))
+	for _, pl := range parsed.PortLists {
+		count, _ := strconv.Atoi(strings.TrimSpace(pl.PortCount.All))
+		lists = append(lists, PortList{
+			ID:        pl.ID,
+			Name:      strings.TrimSpace(pl.Name),
+			Comment:   strings.TrimSpace(pl.Comment),
+			PortCount: count,
+		})
+	}
+	return lists, nil
+}
+
+// CreatePortList creates a port list from a GMP port range such as
+// "T:1-1024,U:53" using <create_port_list/> and returns its ID.
+func (s *OpenVASService) CreatePortList(ctx context.Context, name, portRange string) (string, error) {
+	if s.Password == "" {
+		return "", fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	name = strings.TrimSpace(name)
+	portRange = strings.TrimSpace(portRange)
+	if name == "" || portRange == "" {
+		return "", fmt.Errorf("name and portRange are required")
+	}
+
+	type createPortListXML struct {
+		XMLName   xml.Name `xml:"create_port_list"`
+		Name      string   `xml:"name"`
+		PortRange string   `xml:"port_range"`
+	}
+
+	xmlBody, err := marshalGMP(createPortListXML{Name: name, PortRange: portRange})
+	if err != nil {
+		return "", err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return "", fmt.Errorf("gvm-cli create_port_list failed: %w; output: %s", err, string(out))
+	}
+
+	type createPortListResponseXML struct {
+		XMLName xml.Name `xml:"create_port_list_response"`
+		ID      string   `xml:"id,attr"`
+	}
+
+	var resp createPortListResponseXML
+	if err := xml.Unmarshal(out, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse create_port_list_response XML: %w; output: %s", err, string(out))
+	}
+	if strings.TrimSpace(resp.ID) == "" {
+		return "", fmt.Errorf("empty port list id in create_port_list_response; output: %s", string(out))
+	}
+
+	return strings.TrimSpace(resp.ID), nil
+}
+
 // internal XML structs for working with tasks.
 type openVASTasksXML struct {
 	Tasks []openVASTaskXML `xml:"task"`
 }
 
 type openVASTaskXML struct {
-	ID     string               `xml:"id,attr"`
-	Name   string               `xml:"name"`
-	Config openVASTaskConfigXML `xml:"config"`
-	Target openVASTaskTargetXML `xml:"target"`
+	ID       string                 `xml:"id,attr"`
+	Name     string                 `xml:"name"`
+	Config   openVASTaskConfigXML   `xml:"config"`
+	Target   openVASTaskTargetXML   `xml:"target"`
+	Scanner  openVASTaskScannerXML  `xml:"scanner"`
+	Schedule openVASTaskScheduleXML `xml:"schedule"`
 }
 
 type openVASTaskConfigXML struct {
@@ -262,12 +864,55 @@ type openVASTaskTargetXML struct {
 	ID string `xml:"id,attr"`
 }
 
+type openVASTaskScannerXML struct {
+	ID string `xml:"id,attr"`
+}
+
+type openVASTaskScheduleXML struct {
+	ID string `xml:"id,attr"`
+}
+
+// checkTaskRefs confirms that configID and targetID exist, so a typo is
+// reported as such instead of as gvmd's create_task error. Dry runs skip the
+// lookups, as they would only show up as extra commands.
+func (s *OpenVASService) checkTaskRefs(ctx context.Context, configID, targetID string) error {
+	if gmpDryRunFromContext(ctx) != nil {
+		return nil
+	}
+
+	configs, err := s.ListConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, c := range configs {
+		if strings.EqualFold(c.ID, configID) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: config_id %s does not exist", ErrTaskRefNotFound, configID)
+	}
+
+	if _, err := s.GetTarget(ctx, targetID); err != nil {
+		if errors.Is(err, ErrTargetNotFound) {
+			return fmt.Errorf("%w: target_id %s does not exist", ErrTaskRefNotFound, targetID)
+		}
+		return err
+	}
+	return nil
+}
+
 // CreateTask ensures idempotent task creation:
-//   - If a task with the same name, config ID, and target ID already exists,
-//     it returns the existing task ID and existed=true.
+//   - If a task with the same name, config ID, target ID and scanner already
+//     exists, it returns the existing task ID and existed=true.
 //   - Otherwise it creates a new task via <create_task> and returns the new
 //     task ID and existed=false.
-func (s *OpenVASService) CreateTask(ctx context.Context, name, configID, targetID string) (id string, existed bool, err error) {
+//
+// An empty scannerID selects the default OpenVAS scanner; a scheduleID from
+// CreateSchedule makes the task recur.
+func (s *OpenVASService) CreateTask(ctx context.Context, name, configID, targetID, scannerID, scheduleID string) (id string, existed bool, err error) {
 	if s.Password == "" {
 		return "", false, fmt.Errorf("GVM_PASSWORD is not set")
 	}
@@ -275,28 +920,40 @@ func (s *OpenVASService) CreateTask(ctx context.Context, name, configID, targetI
 	name = strings.TrimSpace(name)
 	configID = strings.TrimSpace(configID)
 	targetID = strings.TrimSpace(targetID)
+	scannerID = strings.TrimSpace(scannerID)
+	if scannerID == "" {
+		scannerID = DefaultScannerID
+	}
+	scheduleID = strings.TrimSpace(scheduleID)
 
 	if name == "" || configID == "" || targetID == "" {
 		return "", false, fmt.Errorf("name, configID, and targetID are required")
 	}
+	if err := validateGVMID("configID", configID); err != nil {
+		return "", false, err
+	}
+	if err := validateGVMID("targetID", targetID); err != nil {
+		return "", false, err
+	}
+	if err := validateGVMID("scannerID", scannerID); err != nil {
+		return "", false, err
+	}
+	if scheduleID != "" {
+		if err := validateGVMID("scheduleID", scheduleID); err != nil {
+			return "", false, err
+		}
+	}
+	if s.CheckTaskRefs {
+		if err := s.checkTaskRefs(ctx, configID, targetID); err != nil {
+			return "", false, err
+		}
+	}
 
 	// First: check for an existing task with the same name, config, and target.
-	getTasksArgs := []string{
-		"exec",
-		"-u", "gvm",
-		s.ContainerName,
-		"gvm-cli",
-		"--gmp-username", s.Username,
-		"--gmp-password", s.Password,
-		"tls",
-		"--hostname", s.Host,
-		"--port", s.Port,
-		"--xml", "<get_tasks/>",
-	}
-
-	getTasksCmd := exec.CommandContext(ctx, "docker", getTasksArgs...)
-	tasksOut, getTasksErr := getTasksCmd.CombinedOutput()
-	if getTasksErr == nil {
+	tasksOut, getTasksErr := s.runGMP(ctx, "<get_tasks/>")
+	if getTasksErr != nil {
+		addWarning(ctx, "could not list existing tasks; a duplicate task may have been created")
+	} else {
 		var parsed openVASTasksXML
 		if err := xml.Unmarshal(tasksOut, &parsed); err == nil {
 			wantName := strings.TrimSpace(name)
@@ -313,6 +970,12 @@ func (s *OpenVASService) CreateTask(ctx context.Context, name, configID, targetI
 				if strings.TrimSpace(t.Target.ID) != wantTarget {
 					continue
 				}
+				if strings.TrimSpace(t.Scanner.ID) != scannerID {
+					continue
+				}
+				if strings.TrimSpace(t.Schedule.ID) != scheduleID {
+					continue
+				}
 				return t.ID, true, nil
 			}
 		}
@@ -320,10 +983,12 @@ func (s *OpenVASService) CreateTask(ctx context.Context, name, configID, targetI
 
 	// If we didn't find an existing task (or get_tasks failed), create one.
 	type createTaskXML struct {
-		XMLName xml.Name             `xml:"create_task"`
-		Name    string               `xml:"name"`
-		Config  openVASTaskConfigXML `xml:"config"`
-		Target  openVASTaskTargetXML `xml:"target"`
+		XMLName  xml.Name                `xml:"create_task"`
+		Name     string                  `xml:"name"`
+		Config   openVASTaskConfigXML    `xml:"config"`
+		Target   openVASTaskTargetXML    `xml:"target"`
+		Scanner  openVASTaskScannerXML   `xml:"scanner"`
+		Schedule *openVASTaskScheduleXML `xml:"schedule,omitempty"`
 	}
 
 	payload := createTaskXML{
@@ -334,6 +999,12 @@ func (s *OpenVASService) CreateTask(ctx context.Context, name, configID, targetI
 		Target: openVASTaskTargetXML{
 			ID: targetID,
 		},
+		Scanner: openVASTaskScannerXML{
+			ID: scannerID,
+		},
+	}
+	if scheduleID != "" {
+		payload.Schedule = &openVASTaskScheduleXML{ID: scheduleID}
 	}
 
 	xmlBody, err := xml.Marshal(&payload)
@@ -341,21 +1012,7 @@ func (s *OpenVASService) CreateTask(ctx context.Context, name, configID, targetI
 		return "", false, fmt.Errorf("failed to marshal create_task XML: %w", err)
 	}
 
-	createArgs := []string{
-		"exec",
-		"-u", "gvm",
-		s.ContainerName,
-		"gvm-cli",
-		"--gmp-username", s.Username,
-		"--gmp-password", s.Password,
-		"tls",
-		"--hostname", s.Host,
-		"--port", s.Port,
-		"--xml", string(xmlBody),
-	}
-
-	createCmd := exec.CommandContext(ctx, "docker", createArgs...)
-	createOut, createErr := createCmd.CombinedOutput()
+	createOut, createErr := s.runGMP(ctx, string(xmlBody))
 	if createErr != nil {
 		return "", false, fmt.Errorf("gvm-cli create_task failed: %w; output: %s", createErr, string(createOut))
 	}
@@ -376,6 +1033,157 @@ func (s *OpenVASService) CreateTask(ctx context.Context, name, configID, targetI
 	return strings.TrimSpace(resp.ID), false, nil
 }
 
+// ModifyTaskOptions lists the task fields ModifyTask may change. Empty fields
+// are left as they are.
+type ModifyTaskOptions struct {
+	Name       string
+	ConfigID   string
+	TargetID   string
+	ScannerID  string
+	ScheduleID string
+}
+
+// ModifyTask updates an existing task in place using
+// <modify_task task_id='...'>, sending only the fields set in opts. This keeps
+// the task's report history, which deleting and recreating it would orphan.
+// gvmd only lets the config, target and scanner change while the task has
+// never run; when it refuses a change the error wraps ErrTaskNotModifiable.
+func (s *OpenVASService) ModifyTask(ctx context.Context, taskID string, opts ModifyTaskOptions) error {
+	if s.Password == "" {
+		return fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	taskID = strings.TrimSpace(taskID)
+	if taskID == "" {
+		return fmt.Errorf("taskID is required")
+	}
+	if err := validateGVMID("taskID", taskID); err != nil {
+		return err
+	}
+
+	type modifyTaskXML struct {
+		XMLName  xml.Name                `xml:"modify_task"`
+		TaskID   string                  `xml:"task_id,attr"`
+		Name     string                  `xml:"name,omitempty"`
+		Config   *openVASTaskConfigXML   `xml:"config,omitempty"`
+		Target   *openVASTaskTargetXML   `xml:"target,omitempty"`
+		Scanner  *openVASTaskScannerXML  `xml:"scanner,omitempty"`
+		Schedule *openVASTaskScheduleXML `xml:"schedule,omitempty"`
+	}
+
+	payload := modifyTaskXML{TaskID: taskID, Name: strings.TrimSpace(opts.Name)}
+	if id := strings.TrimSpace(opts.ConfigID); id != "" {
+		if err := validateGVMID("configID", id); err != nil {
+			return err
+		}
+		payload.Config = &openVASTaskConfigXML{ID: id}
+	}
+	if id := strings.TrimSpace(opts.TargetID); id != "" {
+		if err := validateGVMID("targetID", id); err != nil {
+			return err
+		}
+		payload.Target = &openVASTaskTargetXML{ID: id}
+	}
+	if id := strings.TrimSpace(opts.ScannerID); id != "" {
+		if err := validateGVMID("scannerID", id); err != nil {
+			return err
+		}
+		payload.Scanner = &openVASTaskScannerXML{ID: id}
+	}
+	if id := strings.TrimSpace(opts.ScheduleID); id != "" {
+		if err := validateGVMID("scheduleID", id); err != nil {
+			return err
+		}
+		payload.Schedule = &openVASTaskScheduleXML{ID: id}
+	}
+	if payload.Name == "" && payload.Config == nil && payload.Target == nil && payload.Scanner == nil && payload.Schedule == nil {
+		return fmt.Errorf("at least one of name, configID, targetID, scannerID or scheduleID is required")
+	}
+
+	xmlBody, err := marshalGMP(payload)
+	if err != nil {
+		return err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	var gmpErr *GMPError
+	if errors.As(err, &gmpErr) {
+		if gmpErr.Status == 400 {
+			return fmt.Errorf("%w: %s", ErrTaskNotModifiable, gmpErr.StatusText)
+		}
+		return fmt.Errorf("modify_task failed: %w", gmpErr)
+	}
+
+	st, parseErr := parseGMPStatus(out)
+	if parseErr != nil {
+		if err != nil {
+			return fmt.Errorf("gvm-cli modify_task failed: %w; output: %s", err, string(out))
+		}
+		return parseErr
+	}
+
+	if st.Status == "400" {
+		return fmt.Errorf("%w: %s", ErrTaskNotModifiable, st.StatusText)
+	}
+	if !strings.HasPrefix(st.Status, "2") {
+		return fmt.Errorf("modify_task failed: %w", newGMPError(st))
+	}
+
+	return nil
+}
+
+// CloneTask duplicates an existing task using <create_task><copy>, returning
+// the new task's ID. The copy has the same config, target, scanner and
+// schedule; follow up with ModifyTask to point it at a different target.
+func (s *OpenVASService) CloneTask(ctx context.Context, taskID string) (string, error) {
+	if s.Password == "" {
+		return "", fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	taskID = strings.TrimSpace(taskID)
+	if taskID == "" {
+		return "", fmt.Errorf("taskID is required")
+	}
+	if err := validateGVMID("taskID", taskID); err != nil {
+		return "", err
+	}
+
+	type cloneTaskXML struct {
+		XMLName xml.Name `xml:"create_task"`
+		Copy    string   `xml:"copy"`
+	}
+
+	xmlBody, err := marshalGMP(cloneTaskXML{Copy: taskID})
+	if err != nil {
+		return "", err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return "", fmt.Errorf("gvm-cli create_task failed: %w; output: %s", err, string(out))
+	}
+
+	type createTaskResponseXML struct {
+		XMLName    xml.Name `xml:"create_task_response"`
+		ID         string   `xml:"id,attr"`
+		Status     string   `xml:"status,attr"`
+		StatusText string   `xml:"status_text,attr"`
+	}
+
+	var resp createTaskResponseXML
+	if err := xml.Unmarshal(out, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse create_task_response XML: %w; output: %s", err, string(out))
+	}
+	if !strings.HasPrefix(resp.Status, "2") {
+		return "", fmt.Errorf("create_task failed: %w", newGMPError(gmpStatusXML{Status: resp.Status, StatusText: resp.StatusText}))
+	}
+	if strings.TrimSpace(resp.ID) == "" {
+		return "", fmt.Errorf("empty task id in create_task_response; output: %s", string(out))
+	}
+
+	return strings.TrimSpace(resp.ID), nil
+}
+
 // StartTask starts an existing OpenVAS/GVM task by ID and returns the raw XML
 // response from gvmd. Callers can inspect the XML for status details.
 func (s *OpenVASService) StartTask(ctx context.Context, taskID string) (string, error) {
@@ -387,24 +1195,19 @@ func (s *OpenVASService) StartTask(ctx context.Context, taskID string) (string,
 	if taskID == "" {
 		return "", fmt.Errorf("taskID is required")
 	}
+	if err := validateGVMID("taskID", taskID); err != nil {
+		return "", err
+	}
 
-	xmlBody := fmt.Sprintf("<start_task task_id='%s'/>", taskID)
-
-	args := []string{
-		"exec",
-		"-u", "gvm",
-		s.ContainerName,
-		"gvm-cli",
-		"--gmp-username", s.Username,
-		"--gmp-password", s.Password,
-		"tls",
-		"--hostname", s.Host,
-		"--port", s.Port,
-		"--xml", xmlBody,
+	xmlBody, err := marshalGMP(gmpTaskCommandXML{XMLName: xml.Name{Local: "start_task"}, TaskID: taskID})
+	if err != nil {
+		return "", err
+	}
+	if err := s.auditTask(ctx, auditOpenVASStartTask, taskID, xmlBody); err != nil {
+		return "", err
 	}
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	out, err := cmd.CombinedOutput()
+	out, err := s.runGMP(ctx, xmlBody)
 	if err != nil {
 		return "", fmt.Errorf("gvm-cli start_task failed: %w; output: %s", err, string(out))
 	}
@@ -412,6 +1215,32 @@ func (s *OpenVASService) StartTask(ctx context.Context, taskID string) (string,
 	return string(out), nil
 }
 
+// auditTask writes the audit entry for a task command before it is sent.
+// The target is looked up from the task on a best-effort basis; the task ID
+// alone still identifies it. Dry runs issue nothing and aren't audited.
+func (s *OpenVASService) auditTask(ctx context.Context, action, taskID, xmlBody string) error {
+	if s.audit == nil || gmpDryRunFromContext(ctx) != nil {
+		return nil
+	}
+
+	target := ""
+	raw, err := s.GetTaskStatus(ctx, taskID)
+	if err == nil {
+		var tasks []TaskSummary
+		if tasks, err = parseTaskList(raw); err == nil && len(tasks) > 0 {
+			target = tasks[0].TargetName
+			if tasks[0].TargetID != "" {
+				target = fmt.Sprintf("%s (%s)", tasks[0].TargetName, tasks[0].TargetID)
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("failed to look up target of task %s for the audit log: %v", taskID, err)
+	}
+
+	return s.audit.record(ctx, auditEntry{Action: action, Target: target, TaskID: taskID, GMP: xmlBody})
+}
+
 // GetTaskStatus fetches the current status/details for an existing OpenVAS/GVM
 // task by ID using <get_tasks task_id='...' details='1'/> and returns the raw
 // XML response from gvmd.
@@ -424,24 +1253,16 @@ func (s *OpenVASService) GetTaskStatus(ctx context.Context, taskID string) (stri
 	if taskID == "" {
 		return "", fmt.Errorf("taskID is required")
 	}
+	if err := validateGVMID("taskID", taskID); err != nil {
+		return "", err
+	}
 
-	xmlBody := fmt.Sprintf("<get_tasks task_id='%s' details='1'/>", taskID)
-
-	args := []string{
-		"exec",
-		"-u", "gvm",
-		s.ContainerName,
-		"gvm-cli",
-		"--gmp-username", s.Username,
-		"--gmp-password", s.Password,
-		"tls",
-		"--hostname", s.Host,
-		"--port", s.Port,
-		"--xml", xmlBody,
+	xmlBody, err := marshalGMP(gmpTaskCommandXML{XMLName: xml.Name{Local: "get_tasks"}, TaskID: taskID, Details: "1"})
+	if err != nil {
+		return "", err
 	}
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	out, err := cmd.CombinedOutput()
+	out, err := s.runGMP(ctx, xmlBody)
 	if err != nil {
 		return "", fmt.Errorf("gvm-cli get_tasks failed: %w; output: %s", err, string(out))
 	}
@@ -450,9 +1271,24 @@ func (s *OpenVASService) GetTaskStatus(ctx context.Context, taskID string) (stri
 }
 
 // GetReport fetches the final report for a given report ID using
-// <get_reports report_id='...' details='1'/> and returns the raw XML
-// response from gvmd.
-func (s *OpenVASService) GetReport(ctx context.Context, reportID string) (string, error) {
+// <get_reports report_id='...' details='1'/> with overrides applied and
+// returns the raw XML response from gvmd. A positive maxResults keeps only
+// the maxResults most severe results (rows=N sorted by severity); the
+// response's result_count still gives the total, see
+// parseReportResultCount.
+func (s *OpenVASService) GetReport(ctx context.Context, reportID string, maxResults int) (string, error) {
+	// apply_overrides makes gvmd report severities with analyst overrides
+	// (e.g. false positives) already applied.
+	filter := "apply_overrides=1"
+	if maxResults > 0 {
+		filter += fmt.Sprintf(" first=1 rows=%d sort-reverse=severity", maxResults)
+	}
+	return s.getReportWithFilter(ctx, reportID, filter)
+}
+
+// getReportWithFilter fetches a report with details, selecting its results
+// with the given GMP filter.
+func (s *OpenVASService) getReportWithFilter(ctx context.Context, reportID, filter string) (string, error) {
 	if s.Password == "" {
 		return "", fmt.Errorf("GVM_PASSWORD is not set")
 	}
@@ -461,27 +1297,1301 @@ func (s *OpenVASService) GetReport(ctx context.Context, reportID string) (string
 	if reportID == "" {
 		return "", fmt.Errorf("reportID is required")
 	}
+	if err := validateGVMID("reportID", reportID); err != nil {
+		return "", err
+	}
 
-	xmlBody := fmt.Sprintf("<get_reports report_id='%s' details='1'/>", reportID)
-
-	args := []string{
-		"exec",
-		"-u", "gvm",
-		s.ContainerName,
-		"gvm-cli",
-		"--gmp-username", s.Username,
-		"--gmp-password", s.Password,
-		"tls",
-		"--hostname", s.Host,
-		"--port", s.Port,
-		"--xml", xmlBody,
+	xmlBody, err := marshalGMP(gmpGetReportsXML{ReportID: reportID, Details: "1", Filter: filter})
+	if err != nil {
+		return "", err
 	}
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	out, err := cmd.CombinedOutput()
+	out, err := s.runGMP(ctx, xmlBody)
 	if err != nil {
 		return "", fmt.Errorf("gvm-cli get_reports failed: %w; output: %s", err, string(out))
 	}
 
 	return string(out), nil
 }
+
+// DeleteReport deletes a report using <delete_report report_id='...'/>. If
+// gvmd refuses because the report still belongs to an active or last task
+// run, the error wraps ErrReportInUse with gvmd's explanation.
+func (s *OpenVASService) DeleteReport(ctx context.Context, reportID string) error {
+	if s.Password == "" {
+		return fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	reportID = strings.TrimSpace(reportID)
+	if reportID == "" {
+		return fmt.Errorf("reportID is required")
+	}
+	if err := validateGVMID("reportID", reportID); err != nil {
+		return err
+	}
+
+	type deleteReportXML struct {
+		XMLName  xml.Name `xml:"delete_report"`
+		ReportID string   `xml:"report_id,attr"`
+	}
+
+	xmlBody, err := marshalGMP(deleteReportXML{ReportID: reportID})
+	if err != nil {
+		return err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	var gmpErr *GMPError
+	if !errors.As(err, &gmpErr) {
+		st, parseErr := parseGMPStatus(out)
+		if parseErr != nil {
+			if err != nil {
+				return fmt.Errorf("gvm-cli delete_report failed: %w; output: %s", err, string(out))
+			}
+			return parseErr
+		}
+		if st.Status == "200" {
+			return nil
+		}
+		gmpErr = newGMPError(st)
+	}
+
+	// gvmd answers 400 for malformed requests too, so only its "in use"
+	// refusal is reported as ErrReportInUse.
+	if gmpErr.Status == 400 && strings.Contains(strings.ToLower(gmpErr.StatusText), "in use") {
+		return fmt.Errorf("%w: %s", ErrReportInUse, gmpErr.StatusText)
+	}
+	return fmt.Errorf("delete_report failed: %w", gmpErr)
+}
+
+// ResourceRef is a reference from one gvmd resource to another, such as a
+// target's port list.
+type ResourceRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// Target is a gvmd target as returned by GetTarget.
+type Target struct {
+	ID            string       `json:"id"`
+	Name          string       `json:"name"`
+	Hosts         []string     `json:"hosts"`
+	PortList      *ResourceRef `json:"port_list,omitempty"`
+	SSHCredential *ResourceRef `json:"ssh_credential,omitempty"`
+}
+
+// internal XML structs for parsing a single target from get_targets.
+type openVASResourceRefXML struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name"`
+}
+
+type openVASTargetDetailsXML struct {
+	Targets []struct {
+		ID            string                `xml:"id,attr"`
+		Name          string                `xml:"name"`
+		Hosts         string                `xml:"hosts"`
+		PortList      openVASResourceRefXML `xml:"port_list"`
+		SSHCredential openVASResourceRefXML `xml:"ssh_credential"`
+	} `xml:"target"`
+}
+
+// GetTarget returns the target with the given ID using
+// <get_targets target_id='...'/>. It returns ErrTargetNotFound if gvmd has
+// no such target.
+func (s *OpenVASService) GetTarget(ctx context.Context, targetID string) (Target, error) {
+	if s.Password == "" {
+		return Target{}, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	targetID = strings.TrimSpace(targetID)
+	if targetID == "" {
+		return Target{}, fmt.Errorf("targetID is required")
+	}
+	if err := validateGVMID("targetID", targetID); err != nil {
+		return Target{}, err
+	}
+
+	xmlBody, err := marshalGMP(gmpTargetCommandXML{XMLName: xml.Name{Local: "get_targets"}, TargetID: targetID})
+	if err != nil {
+		return Target{}, err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		var gmpErr *GMPError
+		if errors.As(err, &gmpErr) && gmpErr.Status == 404 {
+			return Target{}, fmt.Errorf("%w: %s", ErrTargetNotFound, targetID)
+		}
+		return Target{}, fmt.Errorf("gvm-cli get_targets failed: %w; output: %s", err, string(out))
+	}
+
+	return parseTargetDetails(string(out), targetID)
+}
+
+// parseTargetDetails extracts target targetID from a raw
+// get_targets_response.
+func parseTargetDetails(raw, targetID string) (Target, error) {
+	var parsed openVASTargetDetailsXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return Target{}, fmt.Errorf("failed to parse get_targets_response XML: %w; output: %s", err, raw)
+	}
+
+	ref := func(r openVASResourceRefXML) *ResourceRef {
+		if strings.TrimSpace(r.ID) == "" {
+			return nil
+		}
+		return &ResourceRef{ID: strings.TrimSpace(r.ID), Name: strings.TrimSpace(r.Name)}
+	}
+
+	for _, t := range parsed.Targets {
+		if strings.TrimSpace(t.ID) != targetID {
+			continue
+		}
+		return Target{
+			ID:            targetID,
+			Name:          strings.TrimSpace(t.Name),
+			Hosts:         splitGVMHosts(t.Hosts),
+			PortList:      ref(t.PortList),
+			SSHCredential: ref(t.SSHCredential),
+		}, nil
+	}
+	return Target{}, fmt.Errorf("%w: %s", ErrTargetNotFound, targetID)
+}
+
+// targetHasPorts reports whether target t scans the ports CreateTarget was
+// asked for: the portListID itself, or a port list with exactly the ranges
+// in portRange. Without either, any ports will do.
+func (s *OpenVASService) targetHasPorts(ctx context.Context, t openVASTargetXML, portRange, portListID string) (bool, error) {
+	listID := strings.TrimSpace(t.PortList.ID)
+	switch {
+	case portListID != "":
+		return listID == portListID, nil
+	case portRange == "":
+		return true, nil
+	case listID == "":
+		return false, nil
+	}
+
+	if err := validateGVMID("portListID", listID); err != nil {
+		return false, err
+	}
+	xmlBody, err := marshalGMP(gmpGetPortListXML{PortListID: listID, Details: 1})
+	if err != nil {
+		return false, err
+	}
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return false, fmt.Errorf("gvm-cli get_port_lists failed: %w; output: %s", err, string(out))
+	}
+
+	var parsed openVASPortRangesXML
+	if err := xml.Unmarshal(out, &parsed); err != nil {
+		return false, fmt.Errorf("failed to parse get_port_lists_response XML: %w; output: %s", err, string(out))
+	}
+	have := make(map[string]bool)
+	for _, r := range parsed.Ranges {
+		have[portRangeKey(r.Type, r.Start, r.End)] = true
+	}
+	want := parsePortRangeSet(portRange)
+	if len(want) != len(have) {
+		return false, nil
+	}
+	for k := range want {
+		if !have[k] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// internal XML structs for parsing the ranges of a single port list.
+type openVASPortRangesXML struct {
+	Ranges []struct {
+		Start string `xml:"start"`
+		End   string `xml:"end"`
+		Type  string `xml:"type"`
+	} `xml:"port_list>port_ranges>port_range"`
+}
+
+// parsePortRangeSet turns a gvmd port range such as "T:1-1024,8080,U:53"
+// into the set of ranges it describes, keyed by portRangeKey. Ranges
+// without a T: or U: prefix take the protocol of the one before, TCP at
+// the start.
+func parsePortRangeSet(portRange string) map[string]bool {
+	set := make(map[string]bool)
+	proto := "tcp"
+	for _, part := range strings.Split(portRange, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(strings.ToUpper(part), "T:"):
+			proto, part = "tcp", strings.TrimSpace(part[2:])
+		case strings.HasPrefix(strings.ToUpper(part), "U:"):
+			proto, part = "udp", strings.TrimSpace(part[2:])
+		}
+		if part == "" {
+			continue
+		}
+		start, end, found := strings.Cut(part, "-")
+		if !found {
+			end = start
+		}
+		set[portRangeKey(proto, start, end)] = true
+	}
+	return set
+}
+
+// portRangeKey identifies one port range, e.g. "tcp:1-1024".
+func portRangeKey(proto, start, end string) string {
+	return strings.ToLower(strings.TrimSpace(proto)) + ":" + strings.TrimSpace(start) + "-" + strings.TrimSpace(end)
+}
+
+// sameHostSet reports whether two gvmd hosts values list the same hosts,
+// ignoring order, case, whitespace and repeats.
+func sameHostSet(a, b string) bool {
+	set := func(hosts string) map[string]bool {
+		m := make(map[string]bool)
+		for _, h := range splitGVMHosts(hosts) {
+			m[strings.ToLower(h)] = true
+		}
+		return m
+	}
+	as, bs := set(a), set(b)
+	if len(as) == 0 || len(as) != len(bs) {
+		return false
+	}
+	for h := range as {
+		if !bs[h] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitGVMHosts splits a gvmd hosts value, a comma-separated list of
+// addresses, ranges and hostnames, into its trimmed entries.
+func splitGVMHosts(hosts string) []string {
+	var out []string
+	for _, h := range strings.Split(hosts, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// parseStartTaskReportID extracts the report ID of the run created by a
+// <start_task/> call from the raw start_task_response XML.
+func parseStartTaskReportID(raw string) (string, error) {
+	return parseTaskRunReportID(raw, "start_task_response")
+}
+
+// parseTaskRunReportID extracts the <report_id> child of a response element
+// such as start_task_response or resume_task_response.
+func parseTaskRunReportID(raw, element string) (string, error) {
+	type taskRunResponseXML struct {
+		XMLName  xml.Name
+		ReportID string `xml:"report_id"`
+	}
+
+	var resp taskRunResponseXML
+	if err := xml.Unmarshal([]byte(raw), &resp); err != nil {
+		return "", fmt.Errorf("failed to parse %s XML: %w; output: %s", element, err, raw)
+	}
+	if resp.XMLName.Local != element {
+		return "", fmt.Errorf("unexpected <%s> element, want <%s>; output: %s", resp.XMLName.Local, element, raw)
+	}
+	if strings.TrimSpace(resp.ReportID) == "" {
+		return "", fmt.Errorf("empty report id in %s; output: %s", element, raw)
+	}
+	return strings.TrimSpace(resp.ReportID), nil
+}
+
+// validateGVMID returns an error wrapping ErrInvalidID unless id is a GVM
+// UUID. field names the offending parameter in the message.
+func validateGVMID(field, id string) error {
+	if !gvmIDPattern.MatchString(id) {
+		return fmt.Errorf("%w: %s %q is not a UUID", ErrInvalidID, field, id)
+	}
+	return nil
+}
+
+// gmpTaskCommandXML is a GMP command addressing a single task, such as
+// <start_task/>, <stop_task/> or <get_tasks/>. XMLName selects the command.
+type gmpTaskCommandXML struct {
+	XMLName xml.Name
+	TaskID  string `xml:"task_id,attr"`
+	Details string `xml:"details,attr,omitempty"`
+}
+
+// gmpTargetCommandXML is a GMP command addressing a single target, such as
+// <get_targets/> or <delete_target/>.
+type gmpTargetCommandXML struct {
+	XMLName  xml.Name
+	TargetID string `xml:"target_id,attr"`
+}
+
+// gmpGetConfigDetailsXML is the <get_configs/> command for a single config,
+// including its families and preferences.
+type gmpGetConfigDetailsXML struct {
+	XMLName     xml.Name `xml:"get_configs"`
+	ConfigID    string   `xml:"config_id,attr"`
+	Families    int      `xml:"families,attr"`
+	Preferences int      `xml:"preferences,attr"`
+}
+
+// gmpGetPortListXML is the <get_port_lists/> command for a single port
+// list.
+type gmpGetPortListXML struct {
+	XMLName    xml.Name `xml:"get_port_lists"`
+	PortListID string   `xml:"port_list_id,attr"`
+	Details    int      `xml:"details,attr"`
+}
+
+// gmpGetReportsXML is the <get_reports/> command.
+type gmpGetReportsXML struct {
+	XMLName  xml.Name `xml:"get_reports"`
+	ReportID string   `xml:"report_id,attr,omitempty"`
+	FormatID string   `xml:"format_id,attr,omitempty"`
+	Details  string   `xml:"details,attr,omitempty"`
+	// Filter selects the results inside each report; ReportFilter selects
+	// the reports themselves.
+	Filter       string `xml:"filter,attr,omitempty"`
+	ReportFilter string `xml:"report_filter,attr,omitempty"`
+}
+
+// gmpGetResultsXML is the <get_results/> command.
+type gmpGetResultsXML struct {
+	XMLName xml.Name `xml:"get_results"`
+	TaskID  string   `xml:"task_id,attr,omitempty"`
+	Details string   `xml:"details,attr,omitempty"`
+	Filter  string   `xml:"filter,attr,omitempty"`
+}
+
+// marshalGMP renders a GMP command struct to XML. encoding/xml escapes every
+// attribute and text value, so callers never build XML by hand.
+func marshalGMP(v interface{}) (string, error) {
+	out, err := xml.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal GMP command: %w", err)
+	}
+	return string(out), nil
+}
+
+// gmpStatusXML captures the status attributes gvmd sets on every
+// *_response element.
+type gmpStatusXML struct {
+	Status     string `xml:"status,attr"`
+	StatusText string `xml:"status_text,attr"`
+}
+
+// parseGMPStatus reads the status and status_text attributes from the root
+// element of a raw GMP response.
+func parseGMPStatus(raw []byte) (gmpStatusXML, error) {
+	var st gmpStatusXML
+	if err := xml.Unmarshal(raw, &st); err != nil {
+		return st, fmt.Errorf("failed to parse GMP response status: %w; output: %s", err, string(raw))
+	}
+	return st, nil
+}
+
+// GMPError is a non-2xx status returned by gvmd, e.g. 404 when a task
+// doesn't exist. openVASErrorStatus maps it to the matching HTTP status.
+type GMPError struct {
+	Status     int
+	StatusText string
+	// Err is the gvm-cli error, if it exited non-zero.
+	Err error
+}
+
+func (e *GMPError) Error() string {
+	if e.StatusText == "" {
+		return fmt.Sprintf("gvmd returned status %d", e.Status)
+	}
+	return fmt.Sprintf("gvmd returned status %d: %s", e.Status, e.StatusText)
+}
+
+func (e *GMPError) Unwrap() error {
+	return e.Err
+}
+
+// newGMPError builds a GMPError from a parsed response status.
+func newGMPError(st gmpStatusXML) *GMPError {
+	code, _ := strconv.Atoi(st.Status)
+	return &GMPError{Status: code, StatusText: st.StatusText}
+}
+
+// gvmCLIResponseError matches the message gvm-cli prints when gvmd answers
+// with an error status, e.g. "Response Error 404. Failed to find task".
+var gvmCLIResponseError = regexp.MustCompile(`Response Error (\d{3})\.?\s*(.*)`)
+
+// gmpResponseError extracts the GMP status from gvm-cli output, either from
+// the status attribute of the response element or from gvm-cli's own error
+// message. It returns a *GMPError for non-2xx statuses and nil otherwise.
+func gmpResponseError(out []byte, runErr error) error {
+	if st, ok := gmpRootStatus(out); ok {
+		gerr := newGMPError(st)
+		if gerr.Status == 0 || (gerr.Status >= 200 && gerr.Status < 300) {
+			return nil
+		}
+		gerr.Err = runErr
+		return gerr
+	}
+	if runErr == nil {
+		return nil
+	}
+	if m := gvmCLIResponseError.FindSubmatch(out); m != nil {
+		code, _ := strconv.Atoi(string(m[1]))
+		return &GMPError{Status: code, StatusText: strings.TrimSpace(string(m[2])), Err: runErr}
+	}
+	return nil
+}
+
+// gmpRootStatus reads the status attributes of the first element in out
+// without decoding the rest of a possibly large response.
+func gmpRootStatus(out []byte) (gmpStatusXML, bool) {
+	dec := xml.NewDecoder(bytes.NewReader(out))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return gmpStatusXML{}, false
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var st gmpStatusXML
+		for _, a := range start.Attr {
+			switch a.Name.Local {
+			case "status":
+				st.Status = a.Value
+			case "status_text":
+				st.StatusText = a.Value
+			}
+		}
+		return st, st.Status != ""
+	}
+}
+
+// DeleteTarget removes a target using <delete_target target_id='...'/>.
+// It returns ErrTargetInUse when a task still references the target.
+func (s *OpenVASService) DeleteTarget(ctx context.Context, targetID string) error {
+	if s.Password == "" {
+		return fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	targetID = strings.TrimSpace(targetID)
+	if targetID == "" {
+		return fmt.Errorf("targetID is required")
+	}
+	if err := validateGVMID("targetID", targetID); err != nil {
+		return err
+	}
+
+	xmlBody, err := marshalGMP(gmpTargetCommandXML{XMLName: xml.Name{Local: "delete_target"}, TargetID: targetID})
+	if err != nil {
+		return err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	var gmpErr *GMPError
+	if errors.As(err, &gmpErr) {
+		if gmpErr.Status == 400 {
+			return fmt.Errorf("%w: %s", ErrTargetInUse, gmpErr.StatusText)
+		}
+		return fmt.Errorf("delete_target failed: %w", gmpErr)
+	}
+
+	st, parseErr := parseGMPStatus(out)
+	if parseErr != nil {
+		if err != nil {
+			return fmt.Errorf("gvm-cli delete_target failed: %w; output: %s", err, string(out))
+		}
+		return parseErr
+	}
+
+	if st.Status != "200" {
+		if strings.Contains(strings.ToLower(st.StatusText), "in use") {
+			return fmt.Errorf("%w: %s", ErrTargetInUse, st.StatusText)
+		}
+		return fmt.Errorf("delete_target failed: %w", newGMPError(st))
+	}
+
+	return nil
+}
+
+// ErrUnknownRole is returned by CreateUser when a role name doesn't match
+// any role in gvmd.
+var ErrUnknownRole = errors.New("unknown role")
+
+// GetUsers lists gvmd's user accounts and their roles using <get_users/>.
+func (s *OpenVASService) GetUsers(ctx context.Context) ([]User, error) {
+	if s.Password == "" {
+		return nil, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	out, err := s.runGMP(ctx, "<get_users/>")
+	if err != nil {
+		return nil, fmt.Errorf("gvm-cli get_users failed: %w; output: %s", err, string(out))
+	}
+	return parseUsers(string(out))
+}
+
+// resolveRoleIDs maps each role, given as an ID or a name such as "User" or
+// "Observer", to its ID. gvmd is only asked for its roles when a name needs
+// resolving.
+func (s *OpenVASService) resolveRoleIDs(ctx context.Context, roles []string) ([]string, error) {
+	var known []ResourceRef
+	ids := make([]string, 0, len(roles))
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			continue
+		}
+		if gvmIDPattern.MatchString(role) {
+			ids = append(ids, role)
+			continue
+		}
+
+		if known == nil {
+			out, err := s.runGMP(ctx, "<get_roles/>")
+			if err != nil {
+				return nil, fmt.Errorf("gvm-cli get_roles failed: %w; output: %s", err, string(out))
+			}
+			if known, err = parseRoles(string(out)); err != nil {
+				return nil, err
+			}
+		}
+
+		id := ""
+		for _, r := range known {
+			if strings.EqualFold(r.Name, role) {
+				id = r.ID
+				break
+			}
+		}
+		if id == "" {
+			names := make([]string, 0, len(known))
+			for _, r := range known {
+				names = append(names, r.Name)
+			}
+			return nil, fmt.Errorf("%w %q; available: %s", ErrUnknownRole, role, strings.Join(names, ", "))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// CreateUser creates a gvmd user with the given password and roles (IDs or
+// names) using <create_user> and returns its ID. Without roles gvmd assigns
+// none, so the user can't do anything until one is added. The password is
+// only ever placed in the GMP command, never in errors or logs.
+func (s *OpenVASService) CreateUser(ctx context.Context, name, password string, roles []string) (string, error) {
+	if s.Password == "" {
+		return "", fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || password == "" {
+		return "", fmt.Errorf("name and password are required")
+	}
+
+	roleIDs, err := s.resolveRoleIDs(ctx, roles)
+	if err != nil {
+		return "", err
+	}
+
+	type roleRefXML struct {
+		ID string `xml:"id,attr"`
+	}
+	type createUserXML struct {
+		XMLName  xml.Name     `xml:"create_user"`
+		Name     string       `xml:"name"`
+		Password string       `xml:"password"`
+		Roles    []roleRefXML `xml:"role"`
+	}
+
+	payload := createUserXML{Name: name, Password: password}
+	for _, id := range roleIDs {
+		payload.Roles = append(payload.Roles, roleRefXML{ID: id})
+	}
+
+	xmlBody, err := marshalGMP(payload)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return "", fmt.Errorf("gvm-cli create_user failed: %w; output: %s", err, string(out))
+	}
+
+	type createUserResponseXML struct {
+		XMLName xml.Name `xml:"create_user_response"`
+		ID      string   `xml:"id,attr"`
+	}
+
+	var resp createUserResponseXML
+	if err := xml.Unmarshal(out, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse create_user_response XML: %w; output: %s", err, string(out))
+	}
+	if strings.TrimSpace(resp.ID) == "" {
+		return "", fmt.Errorf("empty user id in create_user_response; output: %s", string(out))
+	}
+
+	return strings.TrimSpace(resp.ID), nil
+}
+
+// validateICalendar performs a minimal sanity check of an iCalendar string
+// before it is sent to gvmd, which does the full parsing.
+func validateICalendar(ical string) error {
+	upper := strings.ToUpper(ical)
+	for _, want := range []string{"BEGIN:VCALENDAR", "BEGIN:VEVENT", "DTSTART", "END:VEVENT", "END:VCALENDAR"} {
+		if !strings.Contains(upper, want) {
+			return fmt.Errorf("%w: icalendar is missing %s", ErrInvalidSchedule, want)
+		}
+	}
+	return nil
+}
+
+// CreateSchedule creates a schedule from an iCalendar VEVENT (e.g. with a
+// weekly RRULE) using <create_schedule> and returns its ID. Schedules are
+// evaluated in UTC. If gvmd rejects the schedule the error wraps
+// ErrInvalidSchedule with gvmd's explanation.
+func (s *OpenVASService) CreateSchedule(ctx context.Context, name, iCalendar string) (string, error) {
+	if s.Password == "" {
+		return "", fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	name = strings.TrimSpace(name)
+	iCalendar = strings.TrimSpace(iCalendar)
+	if name == "" || iCalendar == "" {
+		return "", fmt.Errorf("name and iCalendar are required")
+	}
+	if err := validateICalendar(iCalendar); err != nil {
+		return "", err
+	}
+
+	type createScheduleXML struct {
+		XMLName   xml.Name `xml:"create_schedule"`
+		Name      string   `xml:"name"`
+		ICalendar string   `xml:"icalendar"`
+		Timezone  string   `xml:"timezone"`
+	}
+
+	xmlBody, err := marshalGMP(createScheduleXML{Name: name, ICalendar: iCalendar, Timezone: "UTC"})
+	if err != nil {
+		return "", err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	var gmpErr *GMPError
+	if errors.As(err, &gmpErr) {
+		if gmpErr.Status >= 400 && gmpErr.Status < 500 {
+			return "", fmt.Errorf("%w: %s", ErrInvalidSchedule, gmpErr.StatusText)
+		}
+		return "", fmt.Errorf("create_schedule failed: %w", gmpErr)
+	}
+
+	type createScheduleResponseXML struct {
+		XMLName    xml.Name `xml:"create_schedule_response"`
+		ID         string   `xml:"id,attr"`
+		Status     string   `xml:"status,attr"`
+		StatusText string   `xml:"status_text,attr"`
+	}
+
+	var resp createScheduleResponseXML
+	if parseErr := xml.Unmarshal(out, &resp); parseErr != nil {
+		if err != nil {
+			return "", fmt.Errorf("gvm-cli create_schedule failed: %w; output: %s", err, string(out))
+		}
+		return "", fmt.Errorf("failed to parse create_schedule_response XML: %w; output: %s", parseErr, string(out))
+	}
+	if strings.HasPrefix(resp.Status, "4") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidSchedule, resp.StatusText)
+	}
+	if resp.Status != "201" || strings.TrimSpace(resp.ID) == "" {
+		return "", fmt.Errorf("create_schedule failed: %w", newGMPError(gmpStatusXML{Status: resp.Status, StatusText: resp.StatusText}))
+	}
+
+	return strings.TrimSpace(resp.ID), nil
+}
+
+// CreateOverride records an analyst override for an NVT on a host using
+// <create_override>. newSeverity is a CVSS score between 0 and 10, or -1 to
+// mark the finding as a false positive. It returns the new override ID.
+func (s *OpenVASService) CreateOverride(ctx context.Context, nvtOID, host, newSeverity string, text string) (string, error) {
+	if s.Password == "" {
+		return "", fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	nvtOID = strings.TrimSpace(nvtOID)
+	host = strings.TrimSpace(host)
+	newSeverity = strings.TrimSpace(newSeverity)
+	text = strings.TrimSpace(text)
+
+	if nvtOID == "" || newSeverity == "" || text == "" {
+		return "", fmt.Errorf("nvtOID, newSeverity and text are required")
+	}
+
+	type overrideNVTXML struct {
+		OID string `xml:"oid,attr"`
+	}
+
+	type createOverrideXML struct {
+		XMLName     xml.Name       `xml:"create_override"`
+		Text        string         `xml:"text"`
+		NVT         overrideNVTXML `xml:"nvt"`
+		Hosts       string         `xml:"hosts,omitempty"`
+		NewSeverity string         `xml:"new_severity"`
+	}
+
+	payload := createOverrideXML{
+		Text:        text,
+		NVT:         overrideNVTXML{OID: nvtOID},
+		Hosts:       host,
+		NewSeverity: newSeverity,
+	}
+
+	xmlBody, err := xml.Marshal(&payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal create_override XML: %w", err)
+	}
+
+	out, err := s.runGMP(ctx, string(xmlBody))
+	if err != nil {
+		return "", fmt.Errorf("gvm-cli create_override failed: %w; output: %s", err, string(out))
+	}
+
+	type createOverrideResponseXML struct {
+		XMLName xml.Name `xml:"create_override_response"`
+		ID      string   `xml:"id,attr"`
+	}
+
+	var resp createOverrideResponseXML
+	if err := xml.Unmarshal(out, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse create_override_response XML: %w; output: %s", err, string(out))
+	}
+	if strings.TrimSpace(resp.ID) == "" {
+		return "", fmt.Errorf("empty override id in create_override_response; output: %s", string(out))
+	}
+
+	return strings.TrimSpace(resp.ID), nil
+}
+
+// StopTask stops a running OpenVAS/GVM task using <stop_task task_id='...'/>
+// and returns the raw XML response from gvmd. When gvmd rejects the request
+// because the task isn't running, the error wraps ErrTaskNotRunning.
+func (s *OpenVASService) StopTask(ctx context.Context, taskID string) (string, error) {
+	if s.Password == "" {
+		return "", fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	taskID = strings.TrimSpace(taskID)
+	if taskID == "" {
+		return "", fmt.Errorf("taskID is required")
+	}
+	if err := validateGVMID("taskID", taskID); err != nil {
+		return "", err
+	}
+
+	xmlBody, err := marshalGMP(gmpTaskCommandXML{XMLName: xml.Name{Local: "stop_task"}, TaskID: taskID})
+	if err != nil {
+		return "", err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+
+	// gvmd answers 400 when the task is in a state that can't be stopped
+	// (already stopped, done, or never started).
+	var gmpErr *GMPError
+	if errors.As(err, &gmpErr) {
+		if gmpErr.Status == 400 {
+			return string(out), fmt.Errorf("%w: %s", ErrTaskNotRunning, gmpErr.StatusText)
+		}
+		return string(out), fmt.Errorf("stop_task failed: %w", gmpErr)
+	}
+
+	st, parseErr := parseGMPStatus(out)
+	if parseErr != nil {
+		if err != nil {
+			return "", fmt.Errorf("gvm-cli stop_task failed: %w; output: %s", err, string(out))
+		}
+		return "", parseErr
+	}
+	if st.Status == "400" {
+		return string(out), fmt.Errorf("%w: %s", ErrTaskNotRunning, st.StatusText)
+	}
+	if !strings.HasPrefix(st.Status, "2") {
+		return string(out), fmt.Errorf("stop_task failed: %w", newGMPError(st))
+	}
+
+	return string(out), nil
+}
+
+// ResumeTask resumes a stopped or interrupted OpenVAS/GVM task using
+// <resume_task task_id='...'/> so large scans don't restart from zero. It
+// returns the raw XML response from gvmd.
+func (s *OpenVASService) ResumeTask(ctx context.Context, taskID string) (string, error) {
+	if s.Password == "" {
+		return "", fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	taskID = strings.TrimSpace(taskID)
+	if taskID == "" {
+		return "", fmt.Errorf("taskID is required")
+	}
+	if err := validateGVMID("taskID", taskID); err != nil {
+		return "", err
+	}
+
+	xmlBody, err := marshalGMP(gmpTaskCommandXML{XMLName: xml.Name{Local: "resume_task"}, TaskID: taskID})
+	if err != nil {
+		return "", err
+	}
+	if err := s.auditTask(ctx, auditOpenVASResumeTask, taskID, xmlBody); err != nil {
+		return "", err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return "", fmt.Errorf("gvm-cli resume_task failed: %w; output: %s", err, string(out))
+	}
+
+	return string(out), nil
+}
+
+// TaskProgress is the typed view of a task's state that an agent needs to
+// poll for completion.
+type TaskProgress struct {
+	TaskID string `json:"task_id"`
+	Name   string `json:"name,omitempty"`
+	// Status is gvmd's task status, e.g. New, Requested, Running, Stopped,
+	// Done or Interrupted.
+	Status string `json:"status"`
+	// Progress is the completion percentage of the current run (0-100).
+	Progress int `json:"progress"`
+	// ReportID is the report of the task's last run; empty if the task has
+	// never been run.
+	ReportID string `json:"report_id,omitempty"`
+	// CurrentReportID is the report of the run in progress, if any.
+	CurrentReportID string `json:"current_report_id,omitempty"`
+	HasRun          bool   `json:"has_run"`
+}
+
+// internal XML structs for parsing task progress from <get_tasks/> output.
+type openVASTaskProgressResponseXML struct {
+	Tasks []openVASTaskProgressXML `xml:"task"`
+}
+
+type openVASTaskProgressXML struct {
+	ID         string `xml:"id,attr"`
+	Name       string `xml:"name"`
+	Status     string `xml:"status"`
+	Progress   string `xml:"progress"`
+	LastReport struct {
+		Report struct {
+			ID string `xml:"id,attr"`
+		} `xml:"report"`
+	} `xml:"last_report"`
+	CurrentReport struct {
+		Report struct {
+			ID string `xml:"id,attr"`
+		} `xml:"report"`
+	} `xml:"current_report"`
+}
+
+// GetTaskProgress fetches a task via GetTaskStatus and extracts its status,
+// progress percentage and report IDs.
+func (s *OpenVASService) GetTaskProgress(ctx context.Context, taskID string) (TaskProgress, error) {
+	raw, err := s.GetTaskStatus(ctx, taskID)
+	if err != nil {
+		return TaskProgress{}, err
+	}
+	return parseTaskProgress(raw, strings.TrimSpace(taskID))
+}
+
+// parseTaskProgress converts a raw get_tasks_response into a TaskProgress for
+// the given task.
+func parseTaskProgress(raw, taskID string) (TaskProgress, error) {
+	var parsed openVASTaskProgressResponseXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return TaskProgress{}, fmt.Errorf("failed to parse get_tasks_response XML: %w; output: %s", err, raw)
+	}
+
+	for _, t := range parsed.Tasks {
+		if strings.TrimSpace(t.ID) != taskID {
+			continue
+		}
+
+		p := TaskProgress{
+			TaskID:          taskID,
+			Name:            strings.TrimSpace(t.Name),
+			Status:          strings.TrimSpace(t.Status),
+			ReportID:        strings.TrimSpace(t.LastReport.Report.ID),
+			CurrentReportID: strings.TrimSpace(t.CurrentReport.Report.ID),
+		}
+		p.HasRun = p.ReportID != "" || p.CurrentReportID != ""
+		p.Progress = taskPercent(p.Status, t.Progress)
+		return p, nil
+	}
+
+	return TaskProgress{}, fmt.Errorf("task %s not found in get_tasks_response; output: %s", taskID, raw)
+}
+
+// taskPercent converts gvmd's progress value into a 0-100 percentage. gvmd
+// reports -1 for tasks that aren't running, and Done tasks are always 100.
+func taskPercent(status, progress string) int {
+	if status == "Done" {
+		return 100
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(progress))
+	if err != nil || v < 0 {
+		return 0
+	}
+	return min(v, 100)
+}
+
+// defaultTaskListFilter is the GMP filter ListTasks uses when none is given:
+// the first 20 tasks sorted by name.
+const defaultTaskListFilter = "first=1 rows=20 sort=name"
+
+// TaskSummary is one task in a ListTasks listing.
+type TaskSummary struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Progress   int    `json:"progress"`
+	TargetID   string `json:"target_id,omitempty"`
+	TargetName string `json:"target_name,omitempty"`
+}
+
+// internal XML structs for parsing a <get_tasks/> listing.
+type openVASTaskListXML struct {
+	Tasks []struct {
+		ID       string `xml:"id,attr"`
+		Name     string `xml:"name"`
+		Status   string `xml:"status"`
+		Progress string `xml:"progress"`
+		Target   struct {
+			ID   string `xml:"id,attr"`
+			Name string `xml:"name"`
+		} `xml:"target"`
+	} `xml:"task"`
+}
+
+// ListTasks lists tasks using <get_tasks filter='...'/>. filter is a GMP
+// filter string such as "rows=20 first=21 sort=name status=Running"; an
+// empty filter returns the first page of defaultTaskListFilter.
+func (s *OpenVASService) ListTasks(ctx context.Context, filter string) ([]TaskSummary, error) {
+	if s.Password == "" {
+		return nil, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		filter = defaultTaskListFilter
+	}
+
+	type getTasksXML struct {
+		XMLName xml.Name `xml:"get_tasks"`
+		Filter  string   `xml:"filter,attr"`
+		Details string   `xml:"details,attr"`
+	}
+
+	xmlBody, err := marshalGMP(getTasksXML{Filter: filter, Details: "0"})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return nil, fmt.Errorf("gvm-cli get_tasks failed: %w; output: %s", err, string(out))
+	}
+
+	return parseTaskList(string(out))
+}
+
+// parseTaskList converts a raw get_tasks_response into TaskSummary values.
+func parseTaskList(raw string) ([]TaskSummary, error) {
+	var parsed openVASTaskListXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_tasks_response XML: %w", err)
+	}
+
+	tasks := make([]TaskSummary, 0, len(parsed.Tasks))
+	for _, t := range parsed.Tasks {
+		status := strings.TrimSpace(t.Status)
+		tasks = append(tasks, TaskSummary{
+			ID:         strings.TrimSpace(t.ID),
+			Name:       strings.TrimSpace(t.Name),
+			Status:     status,
+			Progress:   taskPercent(status, t.Progress),
+			TargetID:   strings.TrimSpace(t.Target.ID),
+			TargetName: strings.TrimSpace(t.Target.Name),
+		})
+	}
+	return tasks, nil
+}
+
+// GetReportInFormat fetches a report rendered by the given report format
+// using <get_reports report_id='...' format_id='...'/> and returns the raw
+// XML response. Non-XML formats come back base64-encoded inside the
+// <report> element; see decodeReportContent.
+func (s *OpenVASService) GetReportInFormat(ctx context.Context, reportID, formatID string) (string, error) {
+	if s.Password == "" {
+		return "", fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	reportID = strings.TrimSpace(reportID)
+	formatID = strings.TrimSpace(formatID)
+	if reportID == "" || formatID == "" {
+		return "", fmt.Errorf("reportID and formatID are required")
+	}
+	if err := validateGVMID("reportID", reportID); err != nil {
+		return "", err
+	}
+	if err := validateGVMID("formatID", formatID); err != nil {
+		return "", err
+	}
+
+	xmlBody, err := marshalGMP(gmpGetReportsXML{ReportID: reportID, FormatID: formatID, Details: "1", Filter: "apply_overrides=1"})
+	if err != nil {
+		return "", err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return "", fmt.Errorf("gvm-cli get_reports failed: %w; output: %s", err, string(out))
+	}
+
+	return string(out), nil
+}
+
+// GetReportFormats lists the report formats installed in gvmd using
+// <get_report_formats/>.
+func (s *OpenVASService) GetReportFormats(ctx context.Context) ([]ReportFormat, error) {
+	if s.Password == "" {
+		return nil, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	out, err := s.runGMP(ctx, "<get_report_formats/>")
+	if err != nil {
+		return nil, fmt.Errorf("gvm-cli get_report_formats failed: %w; output: %s", err, string(out))
+	}
+
+	return parseReportFormats(string(out))
+}
+
+// GetFeeds reports the version and sync state of each gvmd feed using
+// <get_feeds/>. Feeds older than maxAge are marked stale.
+func (s *OpenVASService) GetFeeds(ctx context.Context, maxAge time.Duration) ([]Feed, error) {
+	if s.Password == "" {
+		return nil, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	out, err := s.runGMP(ctx, "<get_feeds/>")
+	if err != nil {
+		return nil, fmt.Errorf("gvm-cli get_feeds failed: %w; output: %s", err, string(out))
+	}
+
+	return parseFeeds(string(out), maxAge, time.Now().UTC())
+}
+
+// GetNVTFamilies lists the NVT families known to gvmd with the number of
+// NVTs in each, using <get_nvt_families/>.
+func (s *OpenVASService) GetNVTFamilies(ctx context.Context) ([]NVTFamily, error) {
+	if s.Password == "" {
+		return nil, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	out, err := s.runGMP(ctx, "<get_nvt_families/>")
+	if err != nil {
+		return nil, fmt.Errorf("gvm-cli get_nvt_families failed: %w; output: %s", err, string(out))
+	}
+
+	return parseNVTFamilies(string(out))
+}
+
+// GetNVTs lists the NVTs in family using <get_nvts family='...'
+// details='1'/>. An empty family lists every NVT, which can be tens of
+// thousands of entries.
+func (s *OpenVASService) GetNVTs(ctx context.Context, family string) ([]NVT, error) {
+	if s.Password == "" {
+		return nil, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	type getNVTsXML struct {
+		XMLName xml.Name `xml:"get_nvts"`
+		Family  string   `xml:"family,attr,omitempty"`
+		Details string   `xml:"details,attr"`
+	}
+
+	xmlBody, err := marshalGMP(getNVTsXML{Family: strings.TrimSpace(family), Details: "1"})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return nil, fmt.Errorf("gvm-cli get_nvts failed: %w; output: %s", err, string(out))
+	}
+
+	return parseNVTs(string(out))
+}
+
+// ResolveReportFormat maps a friendly format name such as "pdf" to a report
+// format installed in gvmd, matching its name first and then its file
+// extension. If the formats can't be listed it falls back to the well-known
+// UUIDs shipped with GVM.
+func (s *OpenVASService) ResolveReportFormat(ctx context.Context, name string) (ReportFormat, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	formats, _, err := s.CachedReportFormats(ctx, false)
+	if err != nil {
+		builtin, ok := reportFormats[name]
+		if !ok {
+			return ReportFormat{}, err
+		}
+		log.Printf("failed to list report formats, using built-in %s format: %v", name, err)
+		return ReportFormat{ID: builtin.id, Name: name, Extension: builtin.extension, ContentType: builtin.contentType}, nil
+	}
+
+	if f, ok := matchReportFormat(formats, name); ok {
+		return f, nil
+	}
+	return ReportFormat{}, fmt.Errorf("%w: %q", ErrUnknownReportFormat, name)
+}
+
+// ListReports returns a summary of every report known to gvmd, newest
+// first, using <get_reports details='0'/>.
+func (s *OpenVASService) ListReports(ctx context.Context) ([]ReportSummary, error) {
+	if s.Password == "" {
+		return nil, fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	// Without rows=-1 gvmd only returns its default page of reports.
+	xmlBody, err := marshalGMP(gmpGetReportsXML{
+		Details:      "0",
+		Filter:       "apply_overrides=1",
+		ReportFilter: "rows=-1 sort-reverse=date",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return nil, fmt.Errorf("gvm-cli get_reports failed: %w; output: %s", err, string(out))
+	}
+
+	return parseReportSummaries(string(out))
+}
+
+// SummarizeReport fetches every result of a report and condenses it into
+// per-severity counts plus the topN most severe findings.
+func (s *OpenVASService) SummarizeReport(ctx context.Context, reportID string, topN int) (ReportSeveritySummary, error) {
+	// rows=-1 so the counts cover the whole report rather than gvmd's
+	// default page of results.
+	raw, err := s.getReportWithFilter(ctx, reportID, "apply_overrides=1 rows=-1")
+	if err != nil {
+		return ReportSeveritySummary{}, err
+	}
+	results, err := parseReportResults(raw)
+	if err != nil {
+		return ReportSeveritySummary{}, err
+	}
+	summary := summarizeResults(results, topN)
+	summary.ReportID = strings.TrimSpace(reportID)
+	return summary, nil
+}
+
+// GetReportCVEs returns the sorted, deduplicated CVEs referenced by the
+// findings of a report.
+func (s *OpenVASService) GetReportCVEs(ctx context.Context, reportID string) ([]string, error) {
+	// rows=-1 so findings past gvmd's default page are included too.
+	raw, err := s.getReportWithFilter(ctx, reportID, "apply_overrides=1 rows=-1")
+	if err != nil {
+		return nil, err
+	}
+	return parseReportCVEs(raw)
+}
+
+// GetResults fetches the findings of a task with a severity strictly above
+// minSeverity using <get_results task_id='...' filter='severity>X'/> and
+// returns the raw XML response; see parseResults. A non-nil minQOD also drops
+// findings whose quality of detection is below it; otherwise gvmd applies
+// its default of 70.
+func (s *OpenVASService) GetResults(ctx context.Context, taskID string, minSeverity float64, minQOD *int) (string, error) {
+	if s.Password == "" {
+		return "", fmt.Errorf("GVM_PASSWORD is not set")
+	}
+
+	taskID = strings.TrimSpace(taskID)
+	if taskID == "" {
+		return "", fmt.Errorf("taskID is required")
+	}
+	if err := validateGVMID("taskID", taskID); err != nil {
+		return "", err
+	}
+
+	// rows=-1 disables gvmd's default page size so every matching result is
+	// returned.
+	filter := fmt.Sprintf("severity>%s apply_overrides=1 rows=-1", strconv.FormatFloat(minSeverity, 'f', -1, 64))
+	if minQOD != nil {
+		filter += fmt.Sprintf(" min_qod=%d", *minQOD)
+	}
+	xmlBody, err := marshalGMP(gmpGetResultsXML{TaskID: taskID, Details: "1", Filter: filter})
+	if err != nil {
+		return "", err
+	}
+
+	out, err := s.runGMP(ctx, xmlBody)
+	if err != nil {
+		return "", fmt.Errorf("gvm-cli get_results failed: %w; output: %s", err, string(out))
+	}
+
+	return string(out), nil
+}
+
+// WaitForTask polls a task every pollInterval until it reaches a terminal
+// state and returns the report ID of its run. It checks immediately, so an
+// already finished task returns without waiting, and gives up as soon as ctx
+// is cancelled. Stopped or interrupted tasks return their report ID along
+// with an error wrapping ErrTaskStopped.
+func (s *OpenVASService) WaitForTask(ctx context.Context, taskID string, pollInterval time.Duration) (reportID string, err error) {
+	if pollInterval < minTaskPollInterval {
+		pollInterval = minTaskPollInterval
+	}
+
+	for {
+		progress, err := s.GetTaskProgress(ctx, taskID)
+		if err != nil {
+			return "", err
+		}
+
+		reportID := progress.CurrentReportID
+		if reportID == "" {
+			reportID = progress.ReportID
+		}
+
+		switch progress.Status {
+		case "Done":
+			return progress.ReportID, nil
+		case "Stopped", "Interrupted":
+			return reportID, fmt.Errorf("%w: status %s", ErrTaskStopped, progress.Status)
+		case "New":
+			if !progress.HasRun {
+				return "", fmt.Errorf("task %s has never been started", taskID)
+			}
+		}
+
+		timer := time.NewTimer(pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return reportID, fmt.Errorf("waiting for task %s (last status %s): %w", taskID, progress.Status, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,570 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeGVMCLI puts a gvm-cli shell script with the given body on PATH and
+// returns a service that runs it in local mode.
+func fakeGVMCLI(t *testing.T, script string) *OpenVASService {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "gvm-cli"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return &OpenVASService{RunMode: GVMRunModeLocal, Password: "secret", MaxAttempts: 1}
+}
+
+// injectionPayloads try to close the surrounding attribute or element and
+// smuggle in a second GMP command.
+var injectionPayloads = []string{
+	`"><delete_task task_id="daba56c8-73ec-11df-a475-002264764cea"/>`,
+	`'/><delete_task task_id='daba56c8-73ec-11df-a475-002264764cea'/>`,
+	`daba56c8-73ec-11df-a475-002264764cea"/><delete_target target_id="x`,
+	`</name><delete_user name="admin"/><name>`,
+	`<![CDATA[<delete_task/>]]>`,
+	`&lt;delete_task/&gt;`,
+}
+
+func TestValidateGVMID(t *testing.T) {
+	type test struct {
+		name string
+		id   string
+		ok   bool
+	}
+	tests := []test{
+		{"lower case", "daba56c8-73ec-11df-a475-002264764cea", true},
+		{"upper case", "DABA56C8-73EC-11DF-A475-002264764CEA", true},
+		{"empty", "", false},
+		{"no dashes", "daba56c873ec11dfa475002264764cea", false},
+		{"too short", "daba56c8-73ec-11df-a475-00226476", false},
+		{"non hex", "zaba56c8-73ec-11df-a475-002264764cea", false},
+		{"surrounding space", " daba56c8-73ec-11df-a475-002264764cea ", false},
+		{"trailing newline", "daba56c8-73ec-11df-a475-002264764cea\n", false},
+	}
+	for i, p := range injectionPayloads {
+		tests = append(tests, test{fmt.Sprintf("injection %d", i), p, false})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGVMID("taskID", tt.id)
+			if tt.ok && err != nil {
+				t.Fatalf("validateGVMID(%q) = %v, want nil", tt.id, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidID) {
+				t.Fatalf("validateGVMID(%q) = %v, want ErrInvalidID", tt.id, err)
+			}
+		})
+	}
+}
+
+// gmpElements returns the names of every element in a GMP command, and the
+// value of attr and the text of element text wherever they appear.
+func gmpElements(t *testing.T, command, attr, text string) (names []string, attrValue, textValue string) {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(command))
+	var in string
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return names, attrValue, textValue
+		}
+		if err != nil {
+			t.Fatalf("GMP command %s is not well-formed: %v", command, err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			names = append(names, tok.Name.Local)
+			in = tok.Name.Local
+			for _, a := range tok.Attr {
+				if a.Name.Local == attr {
+					attrValue = a.Value
+				}
+			}
+		case xml.CharData:
+			if in == text {
+				textValue += string(tok)
+			}
+		case xml.EndElement:
+			in = ""
+		}
+	}
+}
+
+func TestMarshalGMPEscapesAttributes(t *testing.T) {
+	for _, p := range injectionPayloads {
+		t.Run(p, func(t *testing.T) {
+			command, err := marshalGMP(gmpTaskCommandXML{XMLName: xml.Name{Local: "start_task"}, TaskID: p})
+			if err != nil {
+				t.Fatalf("marshalGMP: %v", err)
+			}
+
+			names, got, _ := gmpElements(t, command, "task_id", "")
+			if len(names) != 1 || names[0] != "start_task" {
+				t.Fatalf("command %s has elements %v, want only start_task", command, names)
+			}
+			if got != p {
+				t.Fatalf("task_id = %q, want %q", got, p)
+			}
+		})
+	}
+}
+
+func TestCreateTargetEscapesNameAndHosts(t *testing.T) {
+	svc := &OpenVASService{Password: "secret"}
+
+	for _, p := range injectionPayloads {
+		t.Run(p, func(t *testing.T) {
+			ctx, d := withGMPDryRun(context.Background())
+			ctx, _ = withWarnings(ctx)
+			if _, _, err := svc.CreateTarget(ctx, p, "10.0.0.1", "", ""); err != nil {
+				t.Fatalf("CreateTarget: %v", err)
+			}
+
+			commands := d.list()
+			if len(commands) != 2 {
+				t.Fatalf("recorded %d commands, want get_targets and create_target: %q", len(commands), commands)
+			}
+			names, _, name := gmpElements(t, commands[1], "", "name")
+			if want := []string{"create_target", "name", "hosts"}; strings.Join(names, ",") != strings.Join(want, ",") {
+				t.Fatalf("command %s has elements %v, want %v", commands[1], names, want)
+			}
+			if name != p {
+				t.Fatalf("name = %q, want %q", name, p)
+			}
+		})
+	}
+}
+
+func TestCreateTargetWarnsWithoutTargetList(t *testing.T) {
+	svc := fakeGVMCLI(t, `case "$*" in
+*get_targets*) echo 'Failed to connect to gvmd' >&2; exit 1 ;;
+*create_target*) echo '<create_target_response status="201" status_text="OK, resource created" id="d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663"/>' ;;
+esac
+`)
+	ctx, w := withWarnings(context.Background())
+
+	id, existed, err := svc.CreateTarget(ctx, "lab", "10.0.0.1", "", "")
+	if err != nil {
+		t.Fatalf("CreateTarget: %v", err)
+	}
+	if id != "d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663" || existed {
+		t.Errorf("CreateTarget = %s, existed %v", id, existed)
+	}
+	want := "could not list existing targets; a duplicate target may have been created"
+	if got := w.list(); !slices.Equal(got, []string{want}) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+}
+
+func TestServiceMethodsRejectInjectedIDs(t *testing.T) {
+	const id = "daba56c8-73ec-11df-a475-002264764cea"
+	svc := &OpenVASService{Password: "secret"}
+	methods := []struct {
+		name string
+		call func(ctx context.Context, bad string) error
+	}{
+		{"StartTask", func(ctx context.Context, bad string) error { _, err := svc.StartTask(ctx, bad); return err }},
+		{"StopTask", func(ctx context.Context, bad string) error { _, err := svc.StopTask(ctx, bad); return err }},
+		{"ResumeTask", func(ctx context.Context, bad string) error { _, err := svc.ResumeTask(ctx, bad); return err }},
+		{"CloneTask", func(ctx context.Context, bad string) error { _, err := svc.CloneTask(ctx, bad); return err }},
+		{"GetTaskStatus", func(ctx context.Context, bad string) error { _, err := svc.GetTaskStatus(ctx, bad); return err }},
+		{"GetTaskProgress", func(ctx context.Context, bad string) error { _, err := svc.GetTaskProgress(ctx, bad); return err }},
+		{"GetResults", func(ctx context.Context, bad string) error { _, err := svc.GetResults(ctx, bad, 0, nil); return err }},
+		{"GetReport", func(ctx context.Context, bad string) error { _, err := svc.GetReport(ctx, bad, 0); return err }},
+		{"GetReportCVEs", func(ctx context.Context, bad string) error { _, err := svc.GetReportCVEs(ctx, bad); return err }},
+		{"DeleteReport", func(ctx context.Context, bad string) error { return svc.DeleteReport(ctx, bad) }},
+		{"GetReportInFormat report", func(ctx context.Context, bad string) error { _, err := svc.GetReportInFormat(ctx, bad, id); return err }},
+		{"GetReportInFormat format", func(ctx context.Context, bad string) error { _, err := svc.GetReportInFormat(ctx, id, bad); return err }},
+		{"GetTarget", func(ctx context.Context, bad string) error { _, err := svc.GetTarget(ctx, bad); return err }},
+		{"DeleteTarget", func(ctx context.Context, bad string) error { return svc.DeleteTarget(ctx, bad) }},
+		{"GetConfigDetails", func(ctx context.Context, bad string) error { _, err := svc.GetConfigDetails(ctx, bad); return err }},
+		{"CreateTask config", func(ctx context.Context, bad string) error {
+			_, _, err := svc.CreateTask(ctx, "lab", bad, id, id, "")
+			return err
+		}},
+		{"CreateTask schedule", func(ctx context.Context, bad string) error {
+			_, _, err := svc.CreateTask(ctx, "lab", id, id, id, bad)
+			return err
+		}},
+		{"CreateTarget port list", func(ctx context.Context, bad string) error {
+			_, _, err := svc.CreateTarget(ctx, "lab", "10.0.0.1", "", bad)
+			return err
+		}},
+	}
+
+	for _, m := range methods {
+		for i, p := range injectionPayloads {
+			t.Run(fmt.Sprintf("%s/%d", m.name, i), func(t *testing.T) {
+				ctx, d := withGMPDryRun(context.Background())
+				ctx, _ = withWarnings(ctx)
+
+				if err := m.call(ctx, p); !errors.Is(err, ErrInvalidID) {
+					t.Fatalf("err = %v, want ErrInvalidID", err)
+				}
+				if commands := d.list(); len(commands) != 0 {
+					t.Fatalf("recorded %q, want no GMP commands", commands)
+				}
+			})
+		}
+	}
+}
+
+func TestBuildGVMArgs(t *testing.T) {
+	const body = "<get_version/>"
+	tests := []struct {
+		name     string
+		svc      OpenVASService
+		wantName string
+		wantArgs []string
+	}{
+		{
+			name:     "docker tls",
+			svc:      OpenVASService{RunMode: GVMRunModeDocker, ContainerName: "openvas", Username: "admin", Password: "secret", ConnectionMode: GVMConnectionTLS, Host: "127.0.0.1", Port: "9390"},
+			wantName: "docker",
+			wantArgs: []string{"exec", "-u", "gvm", "openvas", "gvm-cli", "--gmp-username", "admin", "--gmp-password", "secret", "tls", "--hostname", "127.0.0.1", "--port", "9390", "--xml", body},
+		},
+		{
+			name:     "docker socket",
+			svc:      OpenVASService{RunMode: GVMRunModeDocker, ContainerName: "openvas", Username: "admin", Password: "secret", ConnectionMode: GVMConnectionSocket, SocketPath: "/run/gvmd/gvmd.sock"},
+			wantName: "docker",
+			wantArgs: []string{"exec", "-u", "gvm", "openvas", "gvm-cli", "--gmp-username", "admin", "--gmp-password", "secret", "socket", "--socketpath", "/run/gvmd/gvmd.sock", "--xml", body},
+		},
+		{
+			name:     "local tls",
+			svc:      OpenVASService{RunMode: GVMRunModeLocal, Username: "admin", Password: "secret", ConnectionMode: GVMConnectionTLS, Host: "gvmd.lan", Port: "9390"},
+			wantName: "gvm-cli",
+			wantArgs: []string{"--gmp-username", "admin", "--gmp-password", "secret", "tls", "--hostname", "gvmd.lan", "--port", "9390", "--xml", body},
+		},
+		{
+			name:     "local socket",
+			svc:      OpenVASService{RunMode: GVMRunModeLocal, Username: "admin", Password: "secret", ConnectionMode: GVMConnectionSocket, SocketPath: "/run/gvmd/gvmd.sock"},
+			wantName: "gvm-cli",
+			wantArgs: []string{"--gmp-username", "admin", "--gmp-password", "secret", "socket", "--socketpath", "/run/gvmd/gvmd.sock", "--xml", body},
+		},
+		{
+			name:     "defaults to docker and tls",
+			svc:      OpenVASService{ContainerName: "openvas", Username: "admin", Password: "secret", Host: "127.0.0.1", Port: "9390"},
+			wantName: "docker",
+			wantArgs: []string{"exec", "-u", "gvm", "openvas", "gvm-cli", "--gmp-username", "admin", "--gmp-password", "secret", "tls", "--hostname", "127.0.0.1", "--port", "9390", "--xml", body},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, args := tt.svc.buildGVMArgs(body)
+			if name != tt.wantName {
+				t.Errorf("program = %q, want %q", name, tt.wantName)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("args =\n%q\nwant\n%q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestSameHostSet(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"identical", "10.0.0.1", "10.0.0.1", true},
+		{"prefix of another address", "10.0.0.1", "10.0.0.10", false},
+		{"prefix the other way", "10.0.0.10", "10.0.0.1", false},
+		{"reordered", "10.0.0.1, 10.0.0.2, 10.0.0.3", "10.0.0.3,10.0.0.1,10.0.0.2", true},
+		{"repeated", "10.0.0.1,10.0.0.1,10.0.0.2", "10.0.0.2, 10.0.0.1", true},
+		{"case and whitespace", " Web01.Lab , 10.0.0.0/24", "10.0.0.0/24,web01.lab", true},
+		{"subset", "10.0.0.1,10.0.0.2", "10.0.0.1", false},
+		{"same size, different hosts", "10.0.0.1,10.0.0.2", "10.0.0.1,10.0.0.3", false},
+		{"cidr vs range", "10.0.0.0/30", "10.0.0.0-3", false},
+		{"both empty", "", " , ", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameHostSet(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameHostSet(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePortRangeSet(t *testing.T) {
+	tests := []struct {
+		portRange string
+		want      []string
+	}{
+		{"22", []string{"tcp:22-22"}},
+		{"1-1024, 8080", []string{"tcp:1-1024", "tcp:8080-8080"}},
+		{"T:22,80,U:53,161", []string{"tcp:22-22", "tcp:80-80", "udp:53-53", "udp:161-161"}},
+		{"u:53, T: 443", []string{"udp:53-53", "tcp:443-443"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.portRange, func(t *testing.T) {
+			got := parsePortRangeSet(tt.portRange)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parsePortRangeSet(%q) = %v, want %q", tt.portRange, got, tt.want)
+			}
+			for _, key := range tt.want {
+				if !got[key] {
+					t.Errorf("parsePortRangeSet(%q) = %v, missing %s", tt.portRange, got, key)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateTargetReusesOnlySameHosts(t *testing.T) {
+	svc := fakeGVMCLI(t, `case "$*" in
+*get_targets*) echo '<get_targets_response status="200" status_text="OK"><target id="8715c877-47a0-438d-98a3-27c7a6ab2196"><name>lab</name><hosts>10.0.0.2, 10.0.0.1</hosts><port_list id="33d0cd82-57c6-11e1-8ed1-406186ea4fc5"><name>All IANA assigned TCP</name></port_list></target></get_targets_response>' ;;
+*create_target*) echo '<create_target_response status="201" status_text="OK, resource created" id="d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663"/>' ;;
+esac
+`)
+
+	tests := []struct {
+		name        string
+		hosts       string
+		wantID      string
+		wantExisted bool
+	}{
+		{"reordered hosts reuse", "10.0.0.1,10.0.0.2", "8715c877-47a0-438d-98a3-27c7a6ab2196", true},
+		{"repeated hosts reuse", "10.0.0.1, 10.0.0.2, 10.0.0.1", "8715c877-47a0-438d-98a3-27c7a6ab2196", true},
+		{"similar address creates", "10.0.0.10,10.0.0.2", "d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663", false},
+		{"subset creates", "10.0.0.1", "d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, existed, err := svc.CreateTarget(context.Background(), "lab", tt.hosts, "", "")
+			if err != nil {
+				t.Fatalf("CreateTarget: %v", err)
+			}
+			if id != tt.wantID || existed != tt.wantExisted {
+				t.Errorf("CreateTarget = %s, existed %v; want %s, existed %v", id, existed, tt.wantID, tt.wantExisted)
+			}
+		})
+	}
+}
+
+func TestDeleteTargetInUse(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"gvm-cli error", "echo 'Response Error 400. Target is in use' >&2\nexit 1\n"},
+		{"response status", `echo '<delete_target_response status="400" status_text="Target is in use"/>'` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := fakeGVMCLI(t, tt.script)
+			err := svc.DeleteTarget(context.Background(), "daba56c8-73ec-11df-a475-002264764cea")
+			if !errors.Is(err, ErrTargetInUse) {
+				t.Fatalf("err = %v, want ErrTargetInUse", err)
+			}
+			if !strings.Contains(err.Error(), "Target is in use") {
+				t.Errorf("err = %v, want gvmd's status text", err)
+			}
+		})
+	}
+}
+
+func TestDeleteTargetErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		wantStatus int
+	}{
+		{"deleted", `echo '<delete_target_response status="200" status_text="OK"/>'` + "\n", 0},
+		{"not found", "echo 'Response Error 404. Failed to find target' >&2\nexit 1\n", 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := fakeGVMCLI(t, tt.script)
+			err := svc.DeleteTarget(context.Background(), "daba56c8-73ec-11df-a475-002264764cea")
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("DeleteTarget: %v", err)
+				}
+				return
+			}
+			var gmpErr *GMPError
+			if !errors.As(err, &gmpErr) || gmpErr.Status != tt.wantStatus {
+				t.Fatalf("err = %v, want a GMP %d", err, tt.wantStatus)
+			}
+			if errors.Is(err, ErrTargetInUse) {
+				t.Errorf("err = %v wraps ErrTargetInUse", err)
+			}
+		})
+	}
+}
+
+func TestStopTaskNotRunning(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"gvm-cli error", "echo 'Response Error 400. Task is not running' >&2\nexit 1\n"},
+		{"response status", `echo '<stop_task_response status="400" status_text="Task is not running"/>'` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := fakeGVMCLI(t, tt.script)
+			_, err := svc.StopTask(context.Background(), "daba56c8-73ec-11df-a475-002264764cea")
+			if !errors.Is(err, ErrTaskNotRunning) {
+				t.Fatalf("err = %v, want ErrTaskNotRunning", err)
+			}
+		})
+	}
+}
+
+func TestStopTask(t *testing.T) {
+	svc := fakeGVMCLI(t, `echo '<stop_task_response status="202" status_text="OK, request submitted"/>'`+"\n")
+	out, err := svc.StopTask(context.Background(), "daba56c8-73ec-11df-a475-002264764cea")
+	if err != nil {
+		t.Fatalf("StopTask: %v", err)
+	}
+	if !strings.Contains(out, `status="202"`) {
+		t.Errorf("StopTask returned %q, want the raw response", out)
+	}
+
+	svc = fakeGVMCLI(t, "echo 'Response Error 404. Failed to find task' >&2\nexit 1\n")
+	_, err = svc.StopTask(context.Background(), "daba56c8-73ec-11df-a475-002264764cea")
+	var gmpErr *GMPError
+	if !errors.As(err, &gmpErr) || gmpErr.Status != 404 || errors.Is(err, ErrTaskNotRunning) {
+		t.Fatalf("err = %v, want a GMP 404", err)
+	}
+}
+
+func TestDeleteReportErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		wantInUse  bool
+		wantStatus int
+	}{
+		{"deleted", `echo '<delete_report_response status="200" status_text="OK"/>'` + "\n", false, 0},
+		{"in use from gvm-cli error", "echo 'Response Error 400. Attempt to delete report in use' >&2\nexit 1\n", true, 400},
+		{"in use from response status", `echo '<delete_report_response status="400" status_text="Attempt to delete report in use"/>'` + "\n", true, 400},
+		{"other 400", "echo 'Response Error 400. Bogus report_id' >&2\nexit 1\n", false, 400},
+		{"not found", "echo 'Response Error 404. Failed to find report' >&2\nexit 1\n", false, 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := fakeGVMCLI(t, tt.script)
+			err := svc.DeleteReport(context.Background(), "daba56c8-73ec-11df-a475-002264764cea")
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("DeleteReport: %v", err)
+				}
+				return
+			}
+			if errors.Is(err, ErrReportInUse) != tt.wantInUse {
+				t.Fatalf("err = %v, want ErrReportInUse: %v", err, tt.wantInUse)
+			}
+			if !tt.wantInUse {
+				var gmpErr *GMPError
+				if !errors.As(err, &gmpErr) || gmpErr.Status != tt.wantStatus {
+					t.Fatalf("err = %v, want a GMP %d", err, tt.wantStatus)
+				}
+			}
+		})
+	}
+}
+
+func TestModifyTaskNotModifiable(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"gvm-cli error", "echo 'Response Error 400. Status must be New to edit scanner' >&2\nexit 1\n"},
+		{"response status", `echo '<modify_task_response status="400" status_text="Status must be New to edit scanner"/>'` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := fakeGVMCLI(t, tt.script)
+			err := svc.ModifyTask(context.Background(), "daba56c8-73ec-11df-a475-002264764cea", ModifyTaskOptions{ScannerID: "08b69003-5fc2-4037-a479-93b440211c73"})
+			if !errors.Is(err, ErrTaskNotModifiable) {
+				t.Fatalf("err = %v, want ErrTaskNotModifiable", err)
+			}
+			if !strings.Contains(err.Error(), "Status must be New") {
+				t.Errorf("err = %v, want gvmd's status text", err)
+			}
+		})
+	}
+}
+
+func TestModifyTask(t *testing.T) {
+	svc := fakeGVMCLI(t, `echo '<modify_task_response status="200" status_text="OK"/>'`+"\n")
+	if err := svc.ModifyTask(context.Background(), "daba56c8-73ec-11df-a475-002264764cea", ModifyTaskOptions{Name: "weekly"}); err != nil {
+		t.Fatalf("ModifyTask: %v", err)
+	}
+}
+
+const weeklySchedule = "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240101T020000Z\nRRULE:FREQ=WEEKLY\nEND:VEVENT\nEND:VCALENDAR"
+
+func TestCreateSchedule(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		wantID      string
+		wantInvalid bool
+	}{
+		{
+			name:   "created",
+			script: `echo '<create_schedule_response status="201" status_text="OK, resource created" id="bd3e9d2f-a5df-4d67-8cf6-b2a6a1e1d0b3"/>'` + "\n",
+			wantID: "bd3e9d2f-a5df-4d67-8cf6-b2a6a1e1d0b3",
+		},
+		{
+			name:        "rejected by gvm-cli error",
+			script:      "echo 'Response Error 400. Invalid iCalendar data' >&2\nexit 1\n",
+			wantInvalid: true,
+		},
+		{
+			name:        "rejected by response status",
+			script:      `echo '<create_schedule_response status="400" status_text="Invalid iCalendar data"/>'` + "\n",
+			wantInvalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := fakeGVMCLI(t, tt.script)
+			id, err := svc.CreateSchedule(context.Background(), "weekly", weeklySchedule)
+			if tt.wantInvalid {
+				if !errors.Is(err, ErrInvalidSchedule) {
+					t.Fatalf("err = %v, want ErrInvalidSchedule", err)
+				}
+				if got := statusForError(err, http.StatusInternalServerError); got != http.StatusBadRequest {
+					t.Errorf("status = %d, want %d", got, http.StatusBadRequest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateSchedule: %v", err)
+			}
+			if id != tt.wantID {
+				t.Errorf("id = %q, want %q", id, tt.wantID)
+			}
+		})
+	}
+}
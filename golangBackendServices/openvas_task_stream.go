@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTaskStreamInterval is how often /openvas/task-stream polls gvmd
+// unless ?interval= says otherwise.
+const defaultTaskStreamInterval = 5 * time.Second
+
+// taskStreamDone is the final event of a task stream.
+type taskStreamDone struct {
+	TaskID   string `json:"task_id"`
+	Status   string `json:"status"`
+	ReportID string `json:"report_id,omitempty"`
+}
+
+// taskFinished reports whether gvmd will not change a task's status on its
+// own any more.
+func taskFinished(p TaskProgress) bool {
+	switch p.Status {
+	case "Done", "Stopped", "Interrupted":
+		return true
+	case "New":
+		return !p.HasRun
+	}
+	return false
+}
+
+// openVASTaskStreamHandler polls a task's progress and streams it as
+// Server-Sent Events: a "progress" event with the TaskProgress whenever
+// status or percentage changes, then a "done" event with the report ID once
+// the task has finished. A failed poll ends the stream with an "error"
+// event. ?interval= sets the poll interval in seconds. Disconnecting stops
+// the polling.
+func openVASTaskStreamHandler(svc *OpenVASService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx := r.Context()
+
+		taskID := strings.TrimSpace(r.URL.Query().Get("task_id"))
+		if taskID == "" {
+			writeJSONError(w, http.StatusBadRequest, "task_id is required")
+			return
+		}
+		interval := defaultTaskStreamInterval
+		if v := strings.TrimSpace(r.URL.Query().Get("interval")); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				writeJSONError(w, http.StatusBadRequest, "interval must be a positive number of seconds")
+				return
+			}
+			interval = time.Duration(n) * time.Second
+		}
+
+		// Poll once before switching to SSE so an unknown task or an
+		// unreachable gvmd is still reported with a proper status code.
+		progress, err := svc.GetTaskProgress(ctx, taskID)
+		if err != nil {
+			writeError(w, openVASErrorStatus(err), "failed to get OpenVAS task progress", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		rc := http.NewResponseController(w)
+		sse := &sseWriter{w: w, rc: rc}
+
+		send := func(name string, v any) bool {
+			body, err := json.Marshal(v)
+			if err != nil {
+				log.Printf("failed to encode task stream %s event: %v", name, err)
+				return false
+			}
+			// A write error means the client went away.
+			return sse.event(name, string(body)) == nil
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last TaskProgress
+		for first := true; ; first = false {
+			if first || progress.Status != last.Status || progress.Progress != last.Progress {
+				if !send("progress", progress) {
+					return
+				}
+				last = progress
+			}
+			if taskFinished(progress) {
+				reportID := progress.ReportID
+				if progress.Status != "Done" && progress.CurrentReportID != "" {
+					reportID = progress.CurrentReportID
+				}
+				send("done", taskStreamDone{TaskID: taskID, Status: progress.Status, ReportID: reportID})
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			progress, err = svc.GetTaskProgress(ctx, taskID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("failed to get OpenVAS task progress for %s: %v", taskID, err)
+				resp := errorResponse{
+					Error: "failed to get OpenVAS task progress",
+					Code:  errorCode(openVASErrorStatus(err)),
+				}
+				if verboseErrors {
+					resp.Details = err.Error()
+				}
+				send("error", resp)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// User is a gvmd user account.
+type User struct {
+	ID    string        `json:"id"`
+	Name  string        `json:"name"`
+	Roles []ResourceRef `json:"roles"`
+}
+
+// internal XML structs for parsing <get_users/> and <get_roles/> output.
+type openVASGetUsersXML struct {
+	Users []struct {
+		ID    string                  `xml:"id,attr"`
+		Name  string                  `xml:"name"`
+		Roles []openVASResourceRefXML `xml:"role"`
+	} `xml:"user"`
+}
+
+type openVASGetRolesXML struct {
+	Roles []openVASResourceRefXML `xml:"role"`
+}
+
+// parseUsers converts a raw get_users_response into User values.
+func parseUsers(raw string) ([]User, error) {
+	var parsed openVASGetUsersXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_users_response XML: %w", err)
+	}
+
+	users := make([]User, 0, len(parsed.Users))
+	for _, u := range parsed.Users {
+		user := User{ID: strings.TrimSpace(u.ID), Name: strings.TrimSpace(u.Name), Roles: []ResourceRef{}}
+		for _, r := range u.Roles {
+			user.Roles = append(user.Roles, ResourceRef{ID: strings.TrimSpace(r.ID), Name: strings.TrimSpace(r.Name)})
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// parseRoles converts a raw get_roles_response into references to each role.
+func parseRoles(raw string) ([]ResourceRef, error) {
+	var parsed openVASGetRolesXML
+	if err := xml.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse get_roles_response XML: %w", err)
+	}
+
+	roles := make([]ResourceRef, 0, len(parsed.Roles))
+	for _, r := range parsed.Roles {
+		roles = append(roles, ResourceRef{ID: strings.TrimSpace(r.ID), Name: strings.TrimSpace(r.Name)})
+	}
+	return roles, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// reconFullRequest is the JSON input for /recon/full. Target is typically a
+// CIDR; only the hosts that answer the ping sweep are handed to OpenVAS.
+type reconFullRequest struct {
+	Target     string `json:"target"`
+	Name       string `json:"name,omitempty"`
+	ConfigID   string `json:"config_id,omitempty"`
+	ConfigName string `json:"config_name,omitempty"`
+	Timing     string `json:"timing,omitempty"`
+	Exclude    string `json:"exclude,omitempty"`
+	PortRange  string `json:"port_range,omitempty"`
+	PortListID string `json:"port_list_id,omitempty"`
+}
+
+// reconFullResponse reports how far the workflow got. On failure
+// FailedPhase names the phase that broke and the IDs reached so far are
+// still returned.
+type reconFullResponse struct {
+	Target      string           `json:"target"`
+	LiveHosts   []discoveredHost `json:"live_hosts"`
+	HistoryID   string           `json:"history_id,omitempty"`
+	TargetID    string           `json:"target_id,omitempty"`
+	TaskID      string           `json:"task_id,omitempty"`
+	ReportID    string           `json:"report_id,omitempty"`
+	FailedPhase string           `json:"failed_phase,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	Warnings    []string         `json:"warnings,omitempty"`
+}
+
+// errNoLiveHosts stops /recon/full when the ping sweep found nothing to
+// hand to OpenVAS.
+var errNoLiveHosts = errors.New("no live hosts found")
+
+// reconFullHandler chains host discovery and an OpenVAS scan: it ping-sweeps
+// the target, creates an OpenVAS target from the live hosts, then creates
+// and starts a task with the requested config. Every phase runs under the
+// request context, so a client disconnect stops the workflow between (or
+// during) phases.
+func reconFullHandler(scanner *nmapScanner, svc *OpenVASService, tasks *taskRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		var req reconFullRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", err)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		req.ConfigID = strings.TrimSpace(req.ConfigID)
+		req.ConfigName = strings.TrimSpace(req.ConfigName)
+		req.PortRange = strings.TrimSpace(req.PortRange)
+		req.PortListID = strings.TrimSpace(req.PortListID)
+
+		scanReq := discoverHostsRequest{Target: req.Target, Timing: req.Timing, Exclude: req.Exclude}.scanRequest()
+		if scanReq.Target == "" {
+			writeJSONError(w, http.StatusBadRequest, "target is required")
+			return
+		}
+		if req.ConfigID == "" && req.ConfigName == "" {
+			writeJSONError(w, http.StatusBadRequest, "config_id or config_name is required")
+			return
+		}
+		if req.ConfigID != "" && req.ConfigName != "" {
+			writeJSONError(w, http.StatusBadRequest, "config_id and config_name are mutually exclusive")
+			return
+		}
+		if req.PortRange != "" && req.PortListID != "" {
+			writeJSONError(w, http.StatusBadRequest, "port_range and port_list_id are mutually exclusive")
+			return
+		}
+		if req.Name == "" {
+			req.Name = "recon " + scanReq.Target
+		}
+
+		cmdArgs, err := buildNmapArgs(ctx, scanReq)
+		var reqErr *scanRequestError
+		if errors.As(err, &reqErr) {
+			writeScanRequestError(w, reqErr)
+			return
+		}
+		if err != nil {
+			writeJSONError(w, statusForError(err, http.StatusBadRequest), err.Error())
+			return
+		}
+
+		resp := reconFullResponse{Target: scanReq.Target, LiveHosts: []discoveredHost{}}
+		fail := func(phase string, status int, msg string, err error) {
+			log.Printf("recon of %s failed at %s: %v", scanReq.Target, phase, err)
+			resp.FailedPhase = phase
+			resp.Error = msg
+			if verboseErrors && msg != err.Error() {
+				resp.Error = msg + ": " + err.Error()
+			}
+			resp.Warnings = warns.list()
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				log.Printf("failed to encode recon response: %v", err)
+			}
+		}
+
+		// Phase 1: host discovery. The scan slot is only held while nmap
+		// runs; the OpenVAS phases don't need it.
+		if !scanner.limiter.tryAcquire() {
+			writeTooManyScans(w)
+			return
+		}
+		scan, hosts, err := scanner.discover(ctx, scanReq, cmdArgs)
+		scanner.limiter.release()
+		resp.LiveHosts = hosts
+		resp.HistoryID = scan.HistoryID
+		if err != nil {
+			fail("discover_hosts", http.StatusInternalServerError, "host discovery failed", err)
+			return
+		}
+		if len(hosts) == 0 {
+			fail("discover_hosts", http.StatusUnprocessableEntity, errNoLiveHosts.Error(), errNoLiveHosts)
+			return
+		}
+
+		// Phase 2: resolve the scan config.
+		configID := req.ConfigID
+		if req.ConfigName != "" {
+			id, err := svc.ResolveConfigID(ctx, req.ConfigName)
+			if errors.Is(err, ErrUnknownConfig) {
+				fail("resolve_config", http.StatusBadRequest, err.Error(), err)
+				return
+			}
+			if err != nil {
+				fail("resolve_config", openVASErrorStatus(err), "failed to resolve OpenVAS config", err)
+				return
+			}
+			configID = id
+		}
+
+		// Phase 3: create the OpenVAS target from the live hosts.
+		addrs := make([]string, 0, len(hosts))
+		for _, h := range hosts {
+			addrs = append(addrs, h.Address)
+		}
+		targetID, targetExisted, err := svc.CreateTarget(ctx, req.Name, strings.Join(addrs, ","), req.PortRange, req.PortListID)
+		if err != nil {
+			fail("create_target", openVASErrorStatus(err), "failed to create OpenVAS target", err)
+			return
+		}
+		resp.TargetID = targetID
+
+		// Phase 4: create the task, rolling back a new target on failure.
+		taskID, _, err := svc.CreateTask(ctx, req.Name, configID, targetID, "", "")
+		if err != nil {
+			if !targetExisted {
+				if delErr := svc.DeleteTarget(ctx, targetID); delErr != nil {
+					log.Printf("failed to roll back OpenVAS target %s: %v", targetID, delErr)
+					addWarning(ctx, "target %s was created but could not be rolled back", targetID)
+				} else {
+					resp.TargetID = ""
+				}
+			}
+			fail("create_task", openVASErrorStatus(err), "failed to create OpenVAS task", err)
+			return
+		}
+		resp.TaskID = taskID
+
+		// Phase 5: start the task.
+		raw, err := svc.StartTask(ctx, taskID)
+		if err != nil {
+			fail("start_task", openVASErrorStatus(err), "failed to start OpenVAS task", err)
+			return
+		}
+		reportID, err := parseStartTaskReportID(raw)
+		if err != nil {
+			fail("start_task", openVASErrorStatus(err), "failed to read OpenVAS report id", err)
+			return
+		}
+		resp.ReportID = reportID
+		tasks.track(taskID, reportID)
+
+		resp.Warnings = warns.list()
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("failed to encode recon response: %v", err)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// diffPort identifies an open port on a host in a scan diff.
+type diffPort struct {
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+// serviceChange is a port that is open in both scans but reports a
+// different service or version.
+type serviceChange struct {
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Before   string `json:"before"`
+	After    string `json:"after"`
+}
+
+// scanDiff is what changed between scan A (before) and scan B (after).
+type scanDiff struct {
+	A              string          `json:"a"`
+	B              string          `json:"b"`
+	NewlyOpen      []diffPort      `json:"newly_open"`
+	NewlyClosed    []diffPort      `json:"newly_closed"`
+	ServiceChanges []serviceChange `json:"service_changes"`
+	Warnings       []string        `json:"warnings,omitempty"`
+}
+
+// openPorts indexes the open ports of every host in raw nmap output by
+// "address port/protocol".
+func openPorts(rawOutput string) map[string]diffPort {
+	ports := make(map[string]diffPort)
+	for _, h := range parseHostReports(rawOutput) {
+		for _, p := range h.Ports {
+			if p.State != "open" {
+				continue
+			}
+			ports[fmt.Sprintf("%s %d/%s", h.Address, p.Port, p.Protocol)] = diffPort{
+				Address:  h.Address,
+				Port:     p.Port,
+				Protocol: p.Protocol,
+				Service:  p.Service,
+				Version:  p.Version,
+			}
+		}
+	}
+	return ports
+}
+
+// describeService renders a port's service and version for a
+// serviceChange.
+func describeService(p diffPort) string {
+	return strings.TrimSpace(p.Service + " " + p.Version)
+}
+
+// diffScans compares the open ports of two scans. A host missing from one
+// of them counts as having no open ports there.
+func diffScans(a, b scanHistoryEntry) scanDiff {
+	before, after := openPorts(a.RawOutput), openPorts(b.RawOutput)
+	diff := scanDiff{
+		A:              a.ID,
+		B:              b.ID,
+		NewlyOpen:      []diffPort{},
+		NewlyClosed:    []diffPort{},
+		ServiceChanges: []serviceChange{},
+	}
+
+	for key, p := range after {
+		old, ok := before[key]
+		if !ok {
+			diff.NewlyOpen = append(diff.NewlyOpen, p)
+			continue
+		}
+		if describeService(old) != describeService(p) {
+			diff.ServiceChanges = append(diff.ServiceChanges, serviceChange{
+				Address:  p.Address,
+				Port:     p.Port,
+				Protocol: p.Protocol,
+				Before:   describeService(old),
+				After:    describeService(p),
+			})
+		}
+	}
+	for key, p := range before {
+		if _, ok := after[key]; !ok {
+			diff.NewlyClosed = append(diff.NewlyClosed, p)
+		}
+	}
+
+	sortDiffPorts(diff.NewlyOpen)
+	sortDiffPorts(diff.NewlyClosed)
+	sort.Slice(diff.ServiceChanges, func(i, j int) bool {
+		x, y := diff.ServiceChanges[i], diff.ServiceChanges[j]
+		if x.Address != y.Address {
+			return x.Address < y.Address
+		}
+		return x.Port < y.Port
+	})
+	return diff
+}
+
+func sortDiffPorts(ports []diffPort) {
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Address != ports[j].Address {
+			return ports[i].Address < ports[j].Address
+		}
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
+		}
+		return ports[i].Protocol < ports[j].Protocol
+	})
+}
+
+// scanDiffHandler compares two stored scans: GET /scan-history/diff?a=ID&b=ID
+// reports the ports that opened, closed or changed service from a to b.
+func scanDiffHandler(history *scanHistory) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		idA := strings.TrimSpace(r.URL.Query().Get("a"))
+		idB := strings.TrimSpace(r.URL.Query().Get("b"))
+		if idA == "" || idB == "" {
+			writeJSONError(w, http.StatusBadRequest, "a and b are required")
+			return
+		}
+
+		var entries [2]scanHistoryEntry
+		for i, id := range []string{idA, idB} {
+			entry, ok, err := history.get(ctx, id)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "failed to load scan", err)
+				return
+			}
+			if !ok {
+				writeJSONError(w, http.StatusNotFound, fmt.Sprintf("scan %s not found", id))
+				return
+			}
+			entries[i] = entry
+		}
+		if entries[0].Target != entries[1].Target {
+			addWarning(ctx, "scans have different targets (%s vs %s)", entries[0].Target, entries[1].Target)
+		}
+		if entries[1].StartedAt.Before(entries[0].StartedAt) {
+			addWarning(ctx, "scan b ran before scan a; the diff is reversed in time")
+		}
+
+		diff := diffScans(entries[0], entries[1])
+		diff.Warnings = warns.list()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(diff); err != nil {
+			log.Printf("failed to encode scan diff response: %v", err)
+		}
+	})
+}
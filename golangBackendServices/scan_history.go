@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const scanHistorySchema = `
+CREATE TABLE IF NOT EXISTS scans (
+	id                TEXT PRIMARY KEY,
+	target            TEXT NOT NULL,
+	args              TEXT NOT NULL,
+	started_at        INTEGER NOT NULL,
+	finished_at       INTEGER NOT NULL,
+	exit_code         INTEGER NOT NULL,
+	error             TEXT NOT NULL DEFAULT '',
+	raw_output        TEXT NOT NULL DEFAULT '',
+	scanned_addresses TEXT NOT NULL DEFAULT '[]'
+);
+CREATE INDEX IF NOT EXISTS scans_started_at ON scans (started_at);
+`
+
+// Limits applied to GET /scan-history.
+const (
+	defaultScanHistoryLimit = 50
+	maxScanHistoryLimit     = 500
+)
+
+// scanHistoryEntry is a single persisted nmap scan.
+type scanHistoryEntry struct {
+	ID               string    `json:"id"`
+	Target           string    `json:"target"`
+	Args             []string  `json:"args"`
+	StartedAt        time.Time `json:"started_at"`
+	FinishedAt       time.Time `json:"finished_at"`
+	ExitCode         int       `json:"exit_code"`
+	Error            string    `json:"error,omitempty"`
+	RawOutput        string    `json:"raw_output,omitempty"`
+	ScannedAddresses []string  `json:"scanned_addresses,omitempty"`
+}
+
+// scanHistory persists finished scans to SQLite. Entries older than
+// retention are pruned periodically; a zero retention keeps everything.
+type scanHistory struct {
+	db        *sql.DB
+	retention time.Duration
+}
+
+// openScanHistory opens (creating if needed) the SQLite database at path.
+func openScanHistory(path string, retention time.Duration) (*scanHistory, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scan history %s: %w", path, err)
+	}
+	// SQLite only allows one writer at a time; a single connection avoids
+	// "database is locked" errors from concurrent scans.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(scanHistorySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create scan history schema: %w", err)
+	}
+	return &scanHistory{db: db, retention: retention}, nil
+}
+
+func (h *scanHistory) close() error {
+	return h.db.Close()
+}
+
+// record stores a finished scan and returns its history ID.
+func (h *scanHistory) record(ctx context.Context, args []string, startedAt time.Time, resp scanResponse) (string, error) {
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	addrs := resp.ScannedAddresses
+	if addrs == nil {
+		addrs = []string{}
+	}
+	addrsJSON, err := json.Marshal(addrs)
+	if err != nil {
+		return "", err
+	}
+
+	id := newJobID()
+	_, err = h.db.ExecContext(ctx,
+		`INSERT INTO scans (id, target, args, started_at, finished_at, exit_code, error, raw_output, scanned_addresses)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, resp.Target, string(argsJSON), startedAt.UnixMilli(), time.Now().UnixMilli(),
+		resp.ExitCode, resp.Error, resp.RawOutput, string(addrsJSON),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to record scan: %w", err)
+	}
+	return id, nil
+}
+
+// list returns the most recent scans, newest first. Raw output is omitted to
+// keep the listing small; fetch a single entry for it.
+func (h *scanHistory) list(ctx context.Context, limit int) ([]scanHistoryEntry, error) {
+	rows, err := h.db.QueryContext(ctx,
+		`SELECT id, target, args, started_at, finished_at, exit_code, error, '', scanned_addresses
+		 FROM scans ORDER BY started_at DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scan history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []scanHistoryEntry{}
+	for rows.Next() {
+		entry, err := scanHistoryRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// get returns a single scan including its raw output.
+func (h *scanHistory) get(ctx context.Context, id string) (scanHistoryEntry, bool, error) {
+	row := h.db.QueryRowContext(ctx,
+		`SELECT id, target, args, started_at, finished_at, exit_code, error, raw_output, scanned_addresses
+		 FROM scans WHERE id = ?`, id)
+	entry, err := scanHistoryRow(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return scanHistoryEntry{}, false, nil
+	}
+	if err != nil {
+		return scanHistoryEntry{}, false, err
+	}
+	return entry, true, nil
+}
+
+// scanHistoryRow decodes a row selected in the column order used by list and
+// get.
+func scanHistoryRow(row interface{ Scan(...any) error }) (scanHistoryEntry, error) {
+	var (
+		entry                 scanHistoryEntry
+		argsJSON, addrsJSON   string
+		startedAt, finishedAt int64
+	)
+	if err := row.Scan(&entry.ID, &entry.Target, &argsJSON, &startedAt, &finishedAt,
+		&entry.ExitCode, &entry.Error, &entry.RawOutput, &addrsJSON); err != nil {
+		return entry, err
+	}
+	entry.StartedAt = time.UnixMilli(startedAt).UTC()
+	entry.FinishedAt = time.UnixMilli(finishedAt).UTC()
+	if err := json.Unmarshal([]byte(argsJSON), &entry.Args); err != nil {
+		return entry, fmt.Errorf("failed to decode args of scan %s: %w", entry.ID, err)
+	}
+	if err := json.Unmarshal([]byte(addrsJSON), &entry.ScannedAddresses); err != nil {
+		return entry, fmt.Errorf("failed to decode addresses of scan %s: %w", entry.ID, err)
+	}
+	return entry, nil
+}
+
+// prune deletes scans older than the retention period.
+func (h *scanHistory) prune(ctx context.Context) (int64, error) {
+	if h.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-h.retention).UnixMilli()
+	res, err := h.db.ExecContext(ctx, `DELETE FROM scans WHERE started_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune scan history: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// runPrune periodically prunes old scans until ctx is cancelled.
+func (h *scanHistory) runPrune(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := h.prune(ctx); err != nil {
+			log.Printf("%v", err)
+		} else if n > 0 {
+			log.Printf("pruned %d scan(s) from history", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type scanHistoryResponse struct {
+	Scans []scanHistoryEntry `json:"scans"`
+}
+
+// scanHistoryListHandler lists recent scans, newest first. ?limit= caps the
+// number returned.
+func scanHistoryListHandler(history *scanHistory) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		limit := defaultScanHistoryLimit
+		if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxScanHistoryLimit {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxScanHistoryLimit))
+				return
+			}
+			limit = n
+		}
+
+		scans, err := history.list(r.Context(), limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to list scan history", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(scanHistoryResponse{Scans: scans}); err != nil {
+			log.Printf("failed to encode scan history response: %v", err)
+		}
+	})
+}
+
+// scanHistoryEntryHandler returns a single scan from /scan-history/{id}.
+func scanHistoryEntryHandler(history *scanHistory) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		id := strings.TrimSpace(r.PathValue("id"))
+		if id == "" {
+			writeJSONError(w, http.StatusBadRequest, "id is required")
+			return
+		}
+
+		entry, ok, err := history.get(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load scan", err)
+			return
+		}
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "scan not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entry); err != nil {
+			log.Printf("failed to encode scan history entry: %v", err)
+		}
+	})
+}
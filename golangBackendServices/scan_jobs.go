@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job states reported by the async scan status endpoint. A job is unknown
+// when the server restarted while it was pending or running, so its outcome
+// was lost.
+const (
+	jobStatusPending = "pending"
+	jobStatusRunning = "running"
+	jobStatusDone    = "done"
+	jobStatusFailed  = "failed"
+	jobStatusUnknown = "unknown"
+)
+
+// scanJob tracks a single asynchronous nmap scan.
+type scanJob struct {
+	ID         string        `json:"job_id"`
+	Status     string        `json:"status"`
+	Target     string        `json:"target"`
+	Error      string        `json:"error,omitempty"`
+	Result     *scanResponse `json:"result,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+// JobStore keeps track of async scan jobs. It must be safe for concurrent
+// use. memoryJobStore loses jobs on restart; sqliteJobStore persists them.
+type JobStore interface {
+	// create registers a new pending job for target and returns its ID.
+	create(ctx context.Context, target string) (string, error)
+	// setRunning marks a job as running.
+	setRunning(ctx context.Context, id string) error
+	// finish records the outcome of a job. A non-nil scanErr marks it as
+	// failed.
+	finish(ctx context.Context, id string, result scanResponse, scanErr error) error
+	// get returns a job including its result.
+	get(ctx context.Context, id string) (scanJob, bool, error)
+	// list returns all known jobs, newest first, without their results.
+	list(ctx context.Context) ([]scanJob, error)
+	// cleanup removes finished jobs older than the store's TTL.
+	cleanup(ctx context.Context) error
+}
+
+// memoryJobStore is an in-memory, mutex-guarded JobStore. Finished jobs are
+// dropped once they are older than ttl.
+type memoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*scanJob
+	ttl  time.Duration
+}
+
+func newMemoryJobStore(ttl time.Duration) *memoryJobStore {
+	return &memoryJobStore{
+		jobs: make(map[string]*scanJob),
+		ttl:  ttl,
+	}
+}
+
+func (s *memoryJobStore) create(_ context.Context, target string) (string, error) {
+	id := newJobID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[id] = &scanJob{
+		ID:        id,
+		Status:    jobStatusPending,
+		Target:    target,
+		CreatedAt: time.Now(),
+	}
+	return id, nil
+}
+
+func (s *memoryJobStore) setRunning(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		job.Status = jobStatusRunning
+	}
+	return nil
+}
+
+func (s *memoryJobStore) finish(_ context.Context, id string, result scanResponse, scanErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Result = &result
+	if scanErr != nil {
+		job.Status = jobStatusFailed
+		job.Error = scanErr.Error()
+		return nil
+	}
+	job.Status = jobStatusDone
+	return nil
+}
+
+// get returns a copy of the job so callers can encode it without holding the
+// lock.
+func (s *memoryJobStore) get(_ context.Context, id string) (scanJob, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return scanJob{}, false, nil
+	}
+	return *job, true, nil
+}
+
+func (s *memoryJobStore) list(_ context.Context) ([]scanJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]scanJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		j := *job
+		j.Result = nil
+		out = append(out, j)
+	}
+	sort.Slice(out, func(i, k int) bool {
+		return out[i].CreatedAt.After(out[k].CreatedAt)
+	})
+	return out, nil
+}
+
+func (s *memoryJobStore) cleanup(_ context.Context) error {
+	cutoff := time.Now().Add(-s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+	return nil
+}
+
+// runJobCleanup periodically expires old jobs until ctx is cancelled.
+func runJobCleanup(ctx context.Context, jobs JobStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := jobs.cleanup(ctx); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand should never fail; fall back to a timestamp so the job
+		// is still addressable.
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+type asyncScanResponse struct {
+	JobID    string   `json:"job_id"`
+	Status   string   `json:"status"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// scanOpenPortsAsyncHandler validates a scan request, starts nmap in a
+// background goroutine and immediately returns a job ID that can be polled
+// via scanStatusHandler.
+func scanOpenPortsAsyncHandler(baseCtx context.Context, jobs JobStore, scanner *nmapScanner) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		req, cmdArgs, ok := decodeScanRequest(ctx, w, r)
+		if !ok {
+			return
+		}
+		if req.DryRun {
+			writeNmapDryRun(w, req, cmdArgs, warns.list())
+			return
+		}
+
+		// Reserve the scan slot up front so callers learn immediately that the
+		// host is saturated instead of queuing a job that may never run.
+		if !scanner.limiter.tryAcquire() {
+			writeTooManyScans(w)
+			return
+		}
+
+		id, err := jobs.create(ctx, req.Target)
+		if err != nil {
+			scanner.limiter.release()
+			writeError(w, http.StatusInternalServerError, "failed to create scan job", err)
+			return
+		}
+
+		// The scan must outlive the HTTP request, so it runs on the server's
+		// base context instead of r.Context().
+		go func() {
+			defer scanner.limiter.release()
+			if err := jobs.setRunning(baseCtx, id); err != nil {
+				log.Printf("%v", err)
+			}
+			resp, err := scanner.run(contextWithWarnings(detachRequest(baseCtx, ctx), warns), req, cmdArgs)
+			if err != nil {
+				log.Printf("async nmap error for job %s target %s: %v", id, req.Target, err)
+			}
+			resp.Warnings = warns.list()
+			// Record the outcome even if the server is shutting down, so the
+			// job isn't reported as unknown after the restart.
+			if err := jobs.finish(context.WithoutCancel(baseCtx), id, resp, err); err != nil {
+				log.Printf("%v", err)
+			}
+		}()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(asyncScanResponse{
+			JobID:    id,
+			Status:   jobStatusPending,
+			Warnings: warns.list(),
+		}); err != nil {
+			log.Printf("failed to encode async scan response: %v", err)
+		}
+	})
+}
+
+// scanStatusHandler reports the state of an async scan job, including the
+// scan result once it has finished.
+func scanStatusHandler(jobs JobStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		id := strings.TrimSpace(r.URL.Query().Get("job_id"))
+		if id == "" {
+			writeJSONError(w, http.StatusBadRequest, "job_id is required")
+			return
+		}
+
+		job, ok, err := jobs.get(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load scan job", err)
+			return
+		}
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "job not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(job); err != nil {
+			log.Printf("failed to encode scan status response: %v", err)
+		}
+	})
+}
+
+type scanJobsResponse struct {
+	Jobs []scanJob `json:"jobs"`
+}
+
+// scanJobsHandler lists all tracked async scan jobs.
+func scanJobsHandler(jobs JobStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		list, err := jobs.list(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to list scan jobs", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(scanJobsResponse{Jobs: list}); err != nil {
+			log.Printf("failed to encode scan jobs response: %v", err)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const scanJobsSchema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id          TEXT PRIMARY KEY,
+	status      TEXT NOT NULL,
+	target      TEXT NOT NULL,
+	error       TEXT NOT NULL DEFAULT '',
+	result      TEXT NOT NULL DEFAULT '',
+	created_at  INTEGER NOT NULL,
+	finished_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS jobs_created_at ON jobs (created_at);
+`
+
+// sqliteJobStore is a JobStore backed by SQLite, so tracked jobs and their
+// results survive a restart. Finished jobs are deleted once they are older
+// than ttl.
+type sqliteJobStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// openSQLiteJobStore opens (creating if needed) the SQLite database at path.
+// Jobs that were pending or running when the server last stopped can never
+// finish, so they are marked unknown.
+func openSQLiteJobStore(path string, ttl time.Duration) (*sqliteJobStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scan job store %s: %w", path, err)
+	}
+	// See openScanHistory: one connection avoids "database is locked".
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(scanJobsSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create scan job schema: %w", err)
+	}
+
+	res, err := db.Exec(
+		`UPDATE jobs SET status = ?, error = ?, finished_at = ? WHERE status IN (?, ?)`,
+		jobStatusUnknown, "server restarted before the scan finished", time.Now().UnixMilli(),
+		jobStatusPending, jobStatusRunning,
+	)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to mark interrupted scan jobs: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("marked %d interrupted scan job(s) as %s", n, jobStatusUnknown)
+	}
+	return &sqliteJobStore{db: db, ttl: ttl}, nil
+}
+
+func (s *sqliteJobStore) close() error {
+	return s.db.Close()
+}
+
+func (s *sqliteJobStore) create(ctx context.Context, target string) (string, error) {
+	id := newJobID()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO jobs (id, status, target, created_at) VALUES (?, ?, ?, ?)`,
+		id, jobStatusPending, target, time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create scan job: %w", err)
+	}
+	return id, nil
+}
+
+func (s *sqliteJobStore) setRunning(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE jobs SET status = ? WHERE id = ?`, jobStatusRunning, id); err != nil {
+		return fmt.Errorf("failed to update scan job %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *sqliteJobStore) finish(ctx context.Context, id string, result scanResponse, scanErr error) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result of scan job %s: %w", id, err)
+	}
+	status, errText := jobStatusDone, ""
+	if scanErr != nil {
+		status, errText = jobStatusFailed, scanErr.Error()
+	}
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE jobs SET status = ?, error = ?, result = ?, finished_at = ? WHERE id = ?`,
+		status, errText, string(resultJSON), time.Now().UnixMilli(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish scan job %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *sqliteJobStore) get(ctx context.Context, id string) (scanJob, bool, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, status, target, error, result, created_at, finished_at FROM jobs WHERE id = ?`, id)
+	job, err := scanJobRow(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return scanJob{}, false, nil
+	}
+	if err != nil {
+		return scanJob{}, false, err
+	}
+	return job, true, nil
+}
+
+func (s *sqliteJobStore) list(ctx context.Context) ([]scanJob, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, status, target, error, '', created_at, finished_at FROM jobs ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scan jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []scanJob{}
+	for rows.Next() {
+		job, err := scanJobRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *sqliteJobStore) cleanup(ctx context.Context) error {
+	cutoff := time.Now().Add(-s.ttl).UnixMilli()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM jobs WHERE finished_at IS NOT NULL AND finished_at < ?`, cutoff); err != nil {
+		return fmt.Errorf("failed to clean up scan jobs: %w", err)
+	}
+	return nil
+}
+
+// scanJobRow decodes a row selected in the column order used by get and
+// list. An empty result column leaves Result nil.
+func scanJobRow(row interface{ Scan(...any) error }) (scanJob, error) {
+	var (
+		job        scanJob
+		resultJSON string
+		createdAt  int64
+		finishedAt sql.NullInt64
+	)
+	if err := row.Scan(&job.ID, &job.Status, &job.Target, &job.Error, &resultJSON, &createdAt, &finishedAt); err != nil {
+		return job, err
+	}
+	job.CreatedAt = time.UnixMilli(createdAt).UTC()
+	if finishedAt.Valid {
+		t := time.UnixMilli(finishedAt.Int64).UTC()
+		job.FinishedAt = &t
+	}
+	if resultJSON != "" {
+		var result scanResponse
+		if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+			return job, fmt.Errorf("failed to decode result of scan job %s: %w", job.ID, err)
+		}
+		job.Result = &result
+	}
+	return job, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// scanRetryAfterSeconds is the Retry-After hint returned when all scan slots
+// are busy.
+const scanRetryAfterSeconds = 10
+
+// scanLimiter caps the number of nmap processes running at once using a
+// buffered channel as a counting semaphore.
+type scanLimiter struct {
+	slots chan struct{}
+}
+
+func newScanLimiter(max int) *scanLimiter {
+	return &scanLimiter{slots: make(chan struct{}, max)}
+}
+
+// tryAcquire takes a scan slot without blocking. It returns false when the
+// limit has been reached.
+func (l *scanLimiter) tryAcquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a slot previously taken with tryAcquire.
+func (l *scanLimiter) release() {
+	<-l.slots
+}
+
+// writeTooManyScans rejects a request because every scan slot is in use.
+func writeTooManyScans(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(scanRetryAfterSeconds))
+	writeJSONError(w, http.StatusTooManyRequests, "too many concurrent scans, retry later")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestScanLimiter(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+	}{
+		{"single slot", 1},
+		{"two slots", 2},
+		{"default", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newScanLimiter(tt.limit)
+			for i := 0; i < tt.limit; i++ {
+				if !l.tryAcquire() {
+					t.Fatalf("acquire %d of %d failed", i+1, tt.limit)
+				}
+			}
+
+			if l.tryAcquire() {
+				t.Fatalf("acquire %d succeeded with %d scans running", tt.limit+1, tt.limit)
+			}
+			rec := httptest.NewRecorder()
+			writeTooManyScans(rec)
+			if rec.Code != http.StatusTooManyRequests {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+			}
+			if got := rec.Header().Get("Retry-After"); got != strconv.Itoa(scanRetryAfterSeconds) {
+				t.Errorf("Retry-After = %q, want %d", got, scanRetryAfterSeconds)
+			}
+
+			l.release()
+			if !l.tryAcquire() {
+				t.Fatal("acquire after release failed")
+			}
+			if l.tryAcquire() {
+				t.Fatal("acquire succeeded with the freed slot taken again")
+			}
+		})
+	}
+}
+
+func TestScanOpenPortsHandlerRejectsWhenFull(t *testing.T) {
+	scanner := &nmapScanner{limiter: newScanLimiter(2)}
+	scanner.limiter.tryAcquire()
+	scanner.limiter.tryAcquire()
+
+	req := httptest.NewRequest(http.MethodPost, "/scan-open-ports", strings.NewReader(`{"target":"127.0.0.1"}`))
+	rec := httptest.NewRecorder()
+	scanOpenPortsHandler(scanner).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusTooManyRequests, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header not set")
+	}
+}
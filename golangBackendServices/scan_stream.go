@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// streamStatsInterval is how often streamed scans ask nmap to print a
+// progress line.
+const streamStatsInterval = "5s"
+
+// lineWriter is an io.Writer that calls onLine for every complete line
+// written to it. A trailing partial line is held until the next write or
+// flush.
+type lineWriter struct {
+	onLine func(string)
+	buf    []byte
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		lw.onLine(strings.TrimRight(string(lw.buf[:i]), "\r"))
+		lw.buf = lw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush emits any remaining partial line.
+func (lw *lineWriter) flush() {
+	if len(lw.buf) > 0 {
+		lw.onLine(strings.TrimRight(string(lw.buf), "\r"))
+		lw.buf = nil
+	}
+}
+
+// sseWriter writes Server-Sent Events, flushing after each one so the client
+// sees them immediately.
+type sseWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+// event writes one event. Multi-line data is split into several data fields
+// as required by the SSE format.
+func (s *sseWriter) event(name, data string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", name)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(s.w, b.String()); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// scanStreamHandler runs nmap like scanOpenPortsHandler but streams its
+// stdout to the client as Server-Sent Events: one "output" event per line,
+// with progress printed every few seconds, then a final "done" event holding
+// the scan result (with raw_output left empty, as it has been streamed).
+// Disconnecting cancels the scan.
+func scanStreamHandler(scanner *nmapScanner) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, warns := withWarnings(r.Context())
+
+		req, cmdArgs, ok := decodeScanRequest(ctx, w, r)
+		if !ok {
+			return
+		}
+		cmdArgs = append([]string{"--stats-every", streamStatsInterval}, cmdArgs...)
+		if req.DryRun {
+			writeNmapDryRun(w, req, cmdArgs, warns.list())
+			return
+		}
+
+		if !scanner.limiter.tryAcquire() {
+			writeTooManyScans(w)
+			return
+		}
+		defer scanner.limiter.release()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		rc := http.NewResponseController(w)
+		sse := &sseWriter{w: w, rc: rc}
+		if err := rc.Flush(); err != nil {
+			log.Printf("failed to start scan stream: %v", err)
+			return
+		}
+
+		resp, err := scanner.runWithLines(ctx, req, cmdArgs, func(line string) {
+			// Write errors mean the client went away; the request context
+			// is then cancelled, which kills nmap.
+			_ = sse.event("output", line)
+		})
+		if err != nil {
+			log.Printf("nmap error for target %s: %v", req.Target, err)
+		}
+		if r.Context().Err() != nil {
+			return
+		}
+
+		resp.RawOutput = ""
+		resp.Warnings = warns.list()
+		body, err := json.Marshal(resp)
+		if err != nil {
+			log.Printf("failed to encode scan stream result: %v", err)
+			return
+		}
+		if err := sse.event("done", string(body)); err != nil {
+			log.Printf("failed to send scan stream result: %v", err)
+		}
+	})
+}
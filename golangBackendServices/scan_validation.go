@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// scanRequestError lists every problem found in a scan request, so callers
+// can fix them all at once instead of one per round-trip.
+type scanRequestError struct {
+	Problems []string
+}
+
+func (e *scanRequestError) Error() string {
+	return "invalid scan request: " + strings.Join(e.Problems, "; ")
+}
+
+// writeScanRequestError writes e as a 400 listing every problem.
+func writeScanRequestError(w http.ResponseWriter, e *scanRequestError) {
+	writeErrorResponse(w, http.StatusBadRequest, errorResponse{
+		Error:    "invalid scan request",
+		Problems: e.Problems,
+	})
+}
+
+// validateScanRequest checks a scan request for out-of-range values and
+// contradictory combinations, returning a *scanRequestError that lists all
+// of them. It also folds flags that are requested twice or implied by
+// another one (flag_sv and service_detection, anything covered by aggressive)
+// into a single setting, noting each one as a warning.
+func validateScanRequest(ctx context.Context, req scanRequest) (scanRequest, error) {
+	var problems []string
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if name := strings.ToLower(strings.TrimSpace(req.Profile)); name != "" {
+		if p, ok := scanProfiles[name]; ok {
+			req = applyScanProfile(req, p)
+		} else {
+			problem("unknown profile %q: must be one of %s", req.Profile, scanProfileNames())
+		}
+	}
+	if req.ScanType == "" {
+		req.ScanType = defaultScanType
+	}
+	if req.Timing != "" && !validTimings[req.Timing] {
+		problem("invalid timing %q: must be one of T0, T1, T2, T3, T4, T5", req.Timing)
+	}
+	if req.Ports != "" && req.TopPorts != 0 {
+		problem("ports and top_ports are mutually exclusive")
+	}
+	if req.TopPorts != 0 && (req.TopPorts < 1 || req.TopPorts > 65535) {
+		problem("top_ports must be between 1 and 65535")
+	}
+	if req.MaxRetries != nil && (*req.MaxRetries < 0 || *req.MaxRetries > 10) {
+		problem("max_retries must be between 0 and 10")
+	}
+	if t := strings.TrimSpace(req.HostTimeout); t != "" && !nmapDurationPattern.MatchString(t) {
+		problem("invalid host_timeout %q: must be a duration such as 500ms, 30s, 15m or 1h", t)
+	}
+	if req.MinRate < 0 || req.MaxRate < 0 {
+		problem("min_rate and max_rate must be positive")
+	}
+	if req.MinRate > 0 && req.MaxRate > 0 && req.MinRate > req.MaxRate {
+		problem("min_rate (%d) must not exceed max_rate (%d)", req.MinRate, req.MaxRate)
+	}
+	if iface := strings.TrimSpace(req.Interface); iface != "" {
+		names, err := hostInterfaceNames()
+		switch {
+		case err != nil:
+			problem("cannot list network interfaces to check %q: %v", iface, err)
+		case !slices.Contains(names, iface):
+			problem("unknown interface %q: must be one of %s", iface, strings.Join(names, ", "))
+		}
+	}
+	if sourceIP := strings.TrimSpace(req.SourceIP); sourceIP != "" {
+		if net.ParseIP(sourceIP) == nil {
+			problem("invalid source_ip %q: must be an IP address", sourceIP)
+		} else if strings.TrimSpace(req.Interface) == "" {
+			addWarning(ctx, "source_ip usually needs interface as well, or nmap may not be able to pick a route")
+		}
+	}
+	if req.StealthOptions != nil {
+		problems = append(problems, req.StealthOptions.problems()...)
+	}
+	req.VersionMode = strings.ToLower(strings.TrimSpace(req.VersionMode))
+	if req.VersionIntensity != nil && (*req.VersionIntensity < 0 || *req.VersionIntensity > 9) {
+		problem("version_intensity must be between 0 and 9")
+	}
+	if req.VersionMode != "" && req.VersionMode != "light" && req.VersionMode != "all" {
+		problem("invalid version_mode %q: must be light or all", req.VersionMode)
+	}
+	if req.VersionIntensity != nil && req.VersionMode != "" {
+		problem("version_intensity and version_mode are mutually exclusive")
+	}
+	if req.OutputFormat != "" && len(req.OutputFormats) > 0 {
+		problem("output_format and output_formats are mutually exclusive")
+	}
+
+	// Fold the flag_* aliases into their named options.
+	merge := func(named *bool, flag *bool, namedField, flagField string) {
+		if *named && *flag {
+			addWarning(ctx, "%s and %s both set; they are the same option", namedField, flagField)
+		}
+		*named = *named || *flag
+		*flag = false
+	}
+	merge(&req.ServiceDetection, &req.FlagSV, "service_detection", "flag_sv")
+	merge(&req.OSDetection, &req.FlagO, "os_detection", "flag_o")
+	merge(&req.Traceroute, &req.FlagTraceroute, "traceroute", "flag_traceroute")
+	merge(&req.Aggressive, &req.FlagA, "aggressive", "flag_a")
+
+	// -A already runs -sV -O -sC --traceroute.
+	if req.Aggressive {
+		var implied []string
+		if req.ServiceDetection {
+			implied = append(implied, "service_detection")
+			req.ServiceDetection = false
+		}
+		if req.OSDetection {
+			implied = append(implied, "os_detection")
+			req.OSDetection = false
+		}
+		if req.FlagSC {
+			implied = append(implied, "flag_sc")
+			req.FlagSC = false
+		}
+		if req.Traceroute {
+			implied = append(implied, "traceroute")
+			req.Traceroute = false
+		}
+		if len(implied) > 0 {
+			addWarning(ctx, "aggressive already enables %s", strings.Join(implied, ", "))
+		}
+	}
+
+	// The version options only tune -sV, which -A includes.
+	if (req.VersionIntensity != nil || req.VersionMode != "") && !req.ServiceDetection && !req.Aggressive {
+		addWarning(ctx, "version_intensity and version_mode only apply with service_detection; ignored")
+		req.VersionIntensity = nil
+		req.VersionMode = ""
+	}
+
+	// A ping scan (-sn) skips the port scan, so nothing that needs open
+	// ports can run.
+	if req.ScanType == "ping" {
+		if req.Ports != "" || req.TopPorts != 0 {
+			problem("scan_type ping does not scan ports; remove ports/top_ports")
+		}
+		if req.ServiceDetection {
+			problem("scan_type ping cannot be combined with service detection")
+		}
+		if req.OSDetection {
+			problem("scan_type ping cannot be combined with OS detection")
+		}
+		if req.Aggressive {
+			problem("scan_type ping cannot be combined with aggressive")
+		}
+	}
+
+	if len(problems) > 0 {
+		return req, &scanRequestError{Problems: problems}
+	}
+	return req, nil
+}
+
+// hostInterfaceNames lists the names of this host's network interfaces.
+func hostInterfaceNames() ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateScanRequest(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+	tests := []struct {
+		name string
+		req  scanRequest
+		// wantProblems are substrings of the expected problems, in order.
+		wantProblems []string
+	}{
+		{"minimal", scanRequest{Target: "10.0.0.1"}, nil},
+		{"profile", scanRequest{Target: "10.0.0.1", Profile: "Quick"}, nil},
+		{"profile ports replaced", scanRequest{Target: "10.0.0.1", Profile: "quick", Ports: "22"}, nil},
+		{"rates in order", scanRequest{Target: "10.0.0.1", MinRate: 100, MaxRate: 500}, nil},
+		{"version mode with service detection", scanRequest{Target: "10.0.0.1", ServiceDetection: true, VersionMode: " Light "}, nil},
+		{"ping scan", scanRequest{Target: "10.0.0.0/24", ScanType: "ping"}, nil},
+		{"host timeout", scanRequest{Target: "10.0.0.1", HostTimeout: "1.5m"}, nil},
+
+		{"unknown profile", scanRequest{Profile: "loud"}, []string{`unknown profile "loud"`}},
+		{"bad timing", scanRequest{Timing: "T9"}, []string{`invalid timing "T9"`}},
+		{"ports and top ports", scanRequest{Ports: "22", TopPorts: 10}, []string{"ports and top_ports are mutually exclusive"}},
+		{"top ports out of range", scanRequest{TopPorts: 70000}, []string{"top_ports must be between 1 and 65535"}},
+		{"negative top ports", scanRequest{TopPorts: -1}, []string{"top_ports must be between 1 and 65535"}},
+		{"max retries out of range", scanRequest{MaxRetries: intPtr(11)}, []string{"max_retries must be between 0 and 10"}},
+		{"bad host timeout", scanRequest{HostTimeout: "30 seconds"}, []string{`invalid host_timeout "30 seconds"`}},
+		{"negative rate", scanRequest{MinRate: -1}, []string{"min_rate and max_rate must be positive"}},
+		{"rates reversed", scanRequest{MinRate: 500, MaxRate: 100}, []string{"min_rate (500) must not exceed max_rate (100)"}},
+		{"unknown interface", scanRequest{Interface: "nosuchif0"}, []string{`unknown interface "nosuchif0"`}},
+		{"bad source ip", scanRequest{SourceIP: "10.0.0"}, []string{`invalid source_ip "10.0.0"`}},
+		{"version intensity out of range", scanRequest{ServiceDetection: true, VersionIntensity: intPtr(10)}, []string{"version_intensity must be between 0 and 9"}},
+		{"bad version mode", scanRequest{ServiceDetection: true, VersionMode: "heavy"}, []string{`invalid version_mode "heavy"`}},
+		{"intensity and mode", scanRequest{ServiceDetection: true, VersionIntensity: intPtr(3), VersionMode: "all"}, []string{"version_intensity and version_mode are mutually exclusive"}},
+		{"output format and formats", scanRequest{OutputFormat: "xml", OutputFormats: []string{"normal"}}, []string{"output_format and output_formats are mutually exclusive"}},
+		{"ping with ports", scanRequest{ScanType: "ping", Ports: "22"}, []string{"scan_type ping does not scan ports"}},
+		{"ping with flag_sv", scanRequest{ScanType: "ping", FlagSV: true}, []string{"scan_type ping cannot be combined with service detection"}},
+		{"ping with flag_a", scanRequest{ScanType: "ping", FlagA: true}, []string{"scan_type ping cannot be combined with aggressive"}},
+		{"ping profile with aggressive profile", scanRequest{ScanType: "ping", Profile: "thorough"}, []string{"scan_type ping cannot be combined with aggressive"}},
+		{
+			"every problem reported",
+			scanRequest{Timing: "fast", Ports: "22", TopPorts: 10, MinRate: 500, MaxRate: 100},
+			[]string{`invalid timing "fast"`, "ports and top_ports are mutually exclusive", "min_rate (500) must not exceed max_rate (100)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := withWarnings(context.Background())
+			_, err := validateScanRequest(ctx, tt.req)
+			if len(tt.wantProblems) == 0 {
+				if err != nil {
+					t.Fatalf("validateScanRequest: %v", err)
+				}
+				return
+			}
+
+			var reqErr *scanRequestError
+			if !errors.As(err, &reqErr) {
+				t.Fatalf("err = %v, want a *scanRequestError", err)
+			}
+			if len(reqErr.Problems) != len(tt.wantProblems) {
+				t.Fatalf("problems = %q, want %d", reqErr.Problems, len(tt.wantProblems))
+			}
+			for i, want := range tt.wantProblems {
+				if !strings.Contains(reqErr.Problems[i], want) {
+					t.Errorf("problem %d = %q, want %q", i, reqErr.Problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateScanRequestFoldsFlags(t *testing.T) {
+	ctx, w := withWarnings(context.Background())
+	got, err := validateScanRequest(ctx, scanRequest{
+		Target:      "10.0.0.1",
+		FlagA:       true,
+		FlagSV:      true,
+		OSDetection: true,
+		FlagSC:      true,
+		VersionMode: "all",
+	})
+	if err != nil {
+		t.Fatalf("validateScanRequest: %v", err)
+	}
+
+	if !got.Aggressive || got.FlagA {
+		t.Errorf("flag_a was not folded into aggressive: %+v", got)
+	}
+	if got.ServiceDetection || got.FlagSV || got.OSDetection || got.FlagSC {
+		t.Errorf("options implied by aggressive were kept: %+v", got)
+	}
+	if got.VersionMode != "all" {
+		t.Errorf("version_mode = %q, want it kept under aggressive", got.VersionMode)
+	}
+	if warnings := w.list(); len(warnings) != 1 || !strings.Contains(warnings[0], "aggressive already enables service_detection, os_detection, flag_sc") {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ErrTargetNotAllowed is returned when a scan target lies outside
+// ALLOWED_TARGETS.
+var ErrTargetNotAllowed = errors.New("target not allowed")
+
+// ErrTargetDenied is returned when a scan target matches a DENIED_TARGETS
+// rule.
+var ErrTargetDenied = errors.New("target denied")
+
+// targetAllowlist restricts which hosts nmap and OpenVAS may scan. It is
+// nil, allowing everything, unless ALLOWED_TARGETS is set.
+var targetAllowlist *cidrAllowlist
+
+// targetDenylist lists hosts that must never be scanned, whatever the
+// allowlist says. It is nil unless DENIED_TARGETS is set.
+var targetDenylist *denylist
+
+// checkTargetScope checks a single target against the denylist and then the
+// allowlist.
+func checkTargetScope(ctx context.Context, target string) error {
+	if err := targetDenylist.check(ctx, target); err != nil {
+		return err
+	}
+	return targetAllowlist.check(ctx, target)
+}
+
+// checkTargetsScope runs checkTargetScope on every target.
+func checkTargetsScope(ctx context.Context, targets []string) error {
+	for _, t := range targets {
+		if err := checkTargetScope(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTargetScopeError reports whether err is a denied or not-allowed target,
+// which is reported as 403.
+func isTargetScopeError(err error) bool {
+	return errors.Is(err, ErrTargetNotAllowed) || errors.Is(err, ErrTargetDenied)
+}
+
+// cidrAllowlist holds the networks scans are confined to. A target is
+// allowed only if every address it covers is inside one of them.
+type cidrAllowlist struct {
+	prefixes []netip.Prefix
+	// resolve lets hostname targets through when all of their addresses
+	// are allowed; otherwise hostnames can't be checked and are rejected.
+	resolve bool
+}
+
+// parseTargetAllowlist parses CIDRs and single IP addresses. It returns nil
+// when entries is empty.
+func parseTargetAllowlist(entries []string, resolve bool) (*cidrAllowlist, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	a := &cidrAllowlist{resolve: resolve}
+	for _, e := range entries {
+		p, err := parseCIDROrAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ALLOWED_TARGETS entry %q: must be a CIDR or IP address", e)
+		}
+		a.prefixes = append(a.prefixes, p)
+	}
+	return a, nil
+}
+
+// parseCIDROrAddr parses a CIDR, or a single address as a one-address
+// prefix.
+func parseCIDROrAddr(s string) (netip.Prefix, error) {
+	s = unbracketIPv6(strings.TrimSpace(s))
+	if addr, err := netip.ParseAddr(s); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return p.Masked(), nil
+}
+
+// contains reports whether every address from lo to hi is allowed. As each
+// prefix is contiguous, it is enough for one of them to hold both ends.
+func (a *cidrAllowlist) contains(lo, hi netip.Addr) bool {
+	for _, p := range a.prefixes {
+		if p.Contains(lo) && p.Contains(hi) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns an error wrapping ErrTargetNotAllowed unless target, in any
+// syntax validateTarget accepts or a GVM a.b.c.d-e.f.g.h range, is
+// entirely allowed.
+func (a *cidrAllowlist) check(ctx context.Context, target string) error {
+	if a == nil {
+		return nil
+	}
+
+	lo, hi, isAddr, err := targetAddressRange(target)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTargetNotAllowed, err)
+	}
+	if isAddr {
+		if !a.contains(lo, hi) {
+			return fmt.Errorf("%w: %s is outside ALLOWED_TARGETS", ErrTargetNotAllowed, target)
+		}
+		return nil
+	}
+
+	if !a.resolve {
+		return fmt.Errorf("%w: hostname %s can't be checked against ALLOWED_TARGETS; use an IP address or set ALLOWED_TARGETS_RESOLVE", ErrTargetNotAllowed, target)
+	}
+	addrs, err := resolveTarget(ctx, target)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTargetNotAllowed, err)
+	}
+	for _, addr := range addrs {
+		if !a.contains(addr, addr) {
+			return fmt.Errorf("%w: %s resolves to %s, which is outside ALLOWED_TARGETS", ErrTargetNotAllowed, target, addr)
+		}
+	}
+	return nil
+}
+
+// denyRule is one DENIED_TARGETS entry: a network or a hostname, kept as
+// written so errors can name it.
+type denyRule struct {
+	raw    string
+	prefix netip.Prefix
+	host   string
+}
+
+// denylist holds the DENIED_TARGETS rules. A target is denied if any
+// address it covers falls in a denied network, or if it is a denied
+// hostname. Hostname targets are also resolved, so a name pointing at a
+// denied address (say 169.254.169.254) is caught too.
+type denylist struct {
+	rules []denyRule
+}
+
+// parseTargetDenylist parses CIDRs, IP addresses and hostnames. It returns
+// nil when entries is empty.
+func parseTargetDenylist(entries []string) (*denylist, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	d := &denylist{}
+	for _, e := range entries {
+		rule := denyRule{raw: e}
+		if p, err := parseCIDROrAddr(e); err == nil {
+			rule.prefix = p
+		} else if hostnamePattern.MatchString(e) {
+			rule.host = normalizeHostname(e)
+		} else {
+			return nil, fmt.Errorf("invalid DENIED_TARGETS entry %q: must be a CIDR, IP address or hostname", e)
+		}
+		d.rules = append(d.rules, rule)
+	}
+	return d, nil
+}
+
+func normalizeHostname(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// overlaps reports whether any address from lo to hi is in p.
+func overlaps(p netip.Prefix, lo, hi netip.Addr) bool {
+	if p.Addr().BitLen() != lo.BitLen() {
+		return false
+	}
+	return lo.Compare(lastAddr(p)) <= 0 && p.Addr().Compare(hi) <= 0
+}
+
+// check returns an error wrapping ErrTargetDenied, naming the matching
+// rule, if target hits the denylist.
+func (d *denylist) check(ctx context.Context, target string) error {
+	if d == nil {
+		return nil
+	}
+
+	lo, hi, isAddr, err := targetAddressRange(target)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTargetDenied, err)
+	}
+	if isAddr {
+		for _, r := range d.rules {
+			if r.prefix.IsValid() && overlaps(r.prefix, lo, hi) {
+				return fmt.Errorf("%w: %s matches DENIED_TARGETS rule %s", ErrTargetDenied, target, r.raw)
+			}
+		}
+		return nil
+	}
+
+	name := normalizeHostname(target)
+	for _, r := range d.rules {
+		if r.host != "" && r.host == name {
+			return fmt.Errorf("%w: %s matches DENIED_TARGETS rule %s", ErrTargetDenied, target, r.raw)
+		}
+	}
+	// A name that doesn't resolve can't be scanned either, so only the
+	// addresses it does resolve to need checking.
+	addrs, err := resolveTarget(ctx, target)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		for _, r := range d.rules {
+			if r.prefix.IsValid() && r.prefix.Contains(addr) {
+				return fmt.Errorf("%w: %s resolves to %s, which matches DENIED_TARGETS rule %s", ErrTargetDenied, target, addr, r.raw)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveTarget looks up the addresses of a hostname target.
+func resolveTarget(ctx context.Context, host string) ([]netip.Addr, error) {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", strings.TrimSuffix(host, "."))
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s: %v", host, err)
+	}
+	for i, addr := range addrs {
+		addrs[i] = addr.Unmap()
+	}
+	return addrs, nil
+}
+
+// targetAddressRange returns the lowest and highest address covered by an
+// IP address, CIDR block, nmap octet range or GVM address range. isAddr is
+// false for hostnames.
+func targetAddressRange(target string) (lo, hi netip.Addr, isAddr bool, err error) {
+	target = unbracketIPv6(strings.TrimSpace(target))
+
+	if addr, err := netip.ParseAddr(target); err == nil {
+		addr = addr.Unmap()
+		return addr, addr, true, nil
+	}
+	if p, err := parseCIDROrAddr(target); err == nil {
+		return p.Addr(), lastAddr(p), true, nil
+	}
+	if from, to, ok := strings.Cut(target, "-"); ok {
+		a, errA := netip.ParseAddr(from)
+		b, errB := netip.ParseAddr(to)
+		if errA == nil && errB == nil {
+			a, b = a.Unmap(), b.Unmap()
+			if a.BitLen() != b.BitLen() || b.Less(a) {
+				return lo, hi, true, fmt.Errorf("invalid address range %s", target)
+			}
+			return a, b, true, nil
+		}
+	}
+	if octetRangePattern.MatchString(target) {
+		return octetRange(target)
+	}
+	return lo, hi, false, nil
+}
+
+// octetRange computes the bounds of an nmap octet range such as
+// 192.168.1-3.1,10-20 or 10.0.*.1, with an optional /prefix.
+func octetRange(target string) (lo, hi netip.Addr, isAddr bool, err error) {
+	spec, bitsText, hasBits := strings.Cut(target, "/")
+	var first, last [4]byte
+	for i, octet := range strings.Split(spec, ".") {
+		omin, omax := 255, 0
+		for _, part := range strings.Split(octet, ",") {
+			from, to := 0, 255
+			switch {
+			case part == "*":
+			case strings.Contains(part, "-"):
+				f, t, _ := strings.Cut(part, "-")
+				if f != "" {
+					if from, err = strconv.Atoi(f); err != nil {
+						return lo, hi, true, fmt.Errorf("invalid octet range %q in %s", part, target)
+					}
+				}
+				if t != "" {
+					if to, err = strconv.Atoi(t); err != nil {
+						return lo, hi, true, fmt.Errorf("invalid octet range %q in %s", part, target)
+					}
+				}
+			default:
+				if from, err = strconv.Atoi(part); err != nil {
+					return lo, hi, true, fmt.Errorf("invalid octet %q in %s", part, target)
+				}
+				to = from
+			}
+			if from > to || to > 255 {
+				return lo, hi, true, fmt.Errorf("invalid octet range %q in %s", part, target)
+			}
+			omin, omax = min(omin, from), max(omax, to)
+		}
+		first[i], last[i] = byte(omin), byte(omax)
+	}
+	lo, hi = netip.AddrFrom4(first), netip.AddrFrom4(last)
+
+	if hasBits {
+		bits, err := strconv.Atoi(bitsText)
+		if err != nil || bits > 32 {
+			return lo, hi, true, fmt.Errorf("invalid prefix length in %s", target)
+		}
+		lo = netip.PrefixFrom(lo, bits).Masked().Addr()
+		hi = lastAddr(netip.PrefixFrom(hi, bits))
+	}
+	return lo, hi, true, nil
+}
+
+// lastAddr returns the highest address in p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
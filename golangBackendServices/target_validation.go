@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	// hostnamePattern matches RFC 1123 hostnames such as scanme.nmap.org.
+	hostnamePattern = regexp.MustCompile(`^(?i:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?)(?:\.(?i:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?))*\.?$`)
+
+	// octetRangePattern matches nmap's IPv4 octet range syntax, e.g.
+	// 192.168.1.1-254, 10.0.*.1 or 10.0.0,1.1, optionally with a prefix.
+	octetRangePattern = regexp.MustCompile(`^[0-9*,\-]+\.[0-9*,\-]+\.[0-9*,\-]+\.[0-9*,\-]+(?:/[0-9]{1,2})?$`)
+)
+
+// unbracketIPv6 strips the URL-style brackets from an IPv6 target such as
+// "[2001:db8::1]" or "[2001:db8::]/64", which nmap does not accept. Other
+// targets are returned unchanged.
+func unbracketIPv6(target string) string {
+	if !strings.HasPrefix(target, "[") {
+		return target
+	}
+	end := strings.Index(target, "]")
+	if end < 0 {
+		return target
+	}
+	addr := target[1:end]
+	if ip := net.ParseIP(addr); ip == nil || ip.To4() != nil {
+		return target
+	}
+	return addr + target[end+1:]
+}
+
+// isIPv6Target reports whether target is an IPv6 address or CIDR block,
+// bracketed or not.
+func isIPv6Target(target string) bool {
+	target = unbracketIPv6(target)
+	if ip := net.ParseIP(target); ip != nil {
+		return ip.To4() == nil
+	}
+	if ip, _, err := net.ParseCIDR(target); err == nil {
+		return ip.To4() == nil
+	}
+	return false
+}
+
+// isIPv4Target reports whether target is an IPv4 address, CIDR block or
+// octet range.
+func isIPv4Target(target string) bool {
+	if ip := net.ParseIP(target); ip != nil {
+		return ip.To4() != nil
+	}
+	if ip, _, err := net.ParseCIDR(target); err == nil {
+		return ip.To4() != nil
+	}
+	return octetRangePattern.MatchString(target)
+}
+
+// validateTarget checks that a single scan target is an IP address, CIDR
+// block, nmap octet range or hostname. IPv6 addresses may be bracketed.
+// Anything else — in particular values starting with "-" that nmap would
+// parse as options — is rejected.
+func validateTarget(target string) error {
+	if target == "" {
+		return fmt.Errorf("target is empty")
+	}
+	if strings.HasPrefix(target, "-") {
+		return fmt.Errorf("invalid target %q: must not start with '-'", target)
+	}
+	if isIPv6Target(target) {
+		return nil
+	}
+
+	if net.ParseIP(target) != nil {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(target); err == nil {
+		return nil
+	}
+	if octetRangePattern.MatchString(target) {
+		return nil
+	}
+	if len(target) <= 253 && hostnamePattern.MatchString(target) {
+		return nil
+	}
+	return fmt.Errorf("invalid target %q: must be an IP address, CIDR, IP range or hostname", target)
+}
+
+// splitTargetList splits a comma, space or newline separated list of hosts.
+func splitTargetList(raw string) []string {
+	return strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+// validateExcludeList validates every entry of an --exclude list and
+// returns it in the comma-joined form nmap expects.
+func validateExcludeList(raw string) (string, error) {
+	entries := splitTargetList(raw)
+	for i, e := range entries {
+		if err := validateTarget(e); err != nil {
+			return "", fmt.Errorf("invalid exclude entry: %w", err)
+		}
+		entries[i] = unbracketIPv6(e)
+	}
+	return strings.Join(entries, ","), nil
+}
+
+// validateExcludeFile checks that an --excludefile exists on this host and
+// that every entry in it is a valid target. Lines starting with '#' are
+// comments, as in nmap.
+func validateExcludeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("exclude_file is not readable: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, e := range splitTargetList(line) {
+			if err := validateTarget(e); err != nil {
+				return fmt.Errorf("invalid entry in exclude_file: %w", err)
+			}
+		}
+	}
+	return nil
+}
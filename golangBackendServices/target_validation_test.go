@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateExcludeList(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"single host", "10.0.0.1", "10.0.0.1", false},
+		{"mixed separators", "10.0.0.1, 10.0.0.2\ngateway.lan", "10.0.0.1,10.0.0.2,gateway.lan", false},
+		{"cidr and range", "10.0.0.0/30 10.0.1.1-10", "10.0.0.0/30,10.0.1.1-10", false},
+		{"bracketed ipv6", "[2001:db8::1]", "2001:db8::1", false},
+		{"option", "-iL /etc/passwd", "", true},
+		{"shell metacharacters", "10.0.0.1;reboot", "", true},
+		{"url", "http://10.0.0.1/", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateExcludeList(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateExcludeList(%q) error = %v, want error: %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("validateExcludeList(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateExcludeFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"hosts and comments", "# lab gateways\n10.0.0.1\n10.0.1.1, 10.0.2.1 # routers\n\n", false},
+		{"bad entry", "10.0.0.1\n--script=exploit\n", true},
+		{"commented out bad entry", "10.0.0.1\n# --script=exploit\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := validateExcludeFile(path); (err != nil) != tt.wantErr {
+				t.Fatalf("validateExcludeFile error = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := validateExcludeFile(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("validateExcludeFile accepted a missing file")
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		wantErr bool
+	}{
+		{"10.0.0.1", false},
+		{"10.0.0.0/24", false},
+		{"192.168.1.1-254", false},
+		{"scanme.nmap.org", false},
+		{"2001:db8::1", false},
+		{"[2001:db8::1]", false},
+		{"2001:db8::/64", false},
+		{"[2001:db8::]/64", false},
+		{"::ffff:10.0.0.1", false},
+		{"", true},
+		{"-sS", true},
+		{"[2001:db8::1", true},
+		{"2001:db8::zz", true},
+		{"10.0.0.1;id", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if err := validateTarget(tt.target); (err != nil) != tt.wantErr {
+				t.Fatalf("validateTarget(%q) = %v, want error: %v", tt.target, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTargetAddressFamily(t *testing.T) {
+	tests := []struct {
+		target     string
+		unbracket  string
+		ipv6, ipv4 bool
+	}{
+		{"10.0.0.1", "10.0.0.1", false, true},
+		{"10.0.0.0/24", "10.0.0.0/24", false, true},
+		{"10.0.*.1", "10.0.*.1", false, true},
+		{"2001:db8::1", "2001:db8::1", true, false},
+		{"[2001:db8::1]", "2001:db8::1", true, false},
+		{"2001:db8::/64", "2001:db8::/64", true, false},
+		{"[2001:db8::]/64", "2001:db8::/64", true, false},
+		{"[10.0.0.1]", "[10.0.0.1]", false, false},
+		{"scanme.nmap.org", "scanme.nmap.org", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := unbracketIPv6(tt.target); got != tt.unbracket {
+				t.Errorf("unbracketIPv6 = %q, want %q", got, tt.unbracket)
+			}
+			if got := isIPv6Target(tt.target); got != tt.ipv6 {
+				t.Errorf("isIPv6Target = %v, want %v", got, tt.ipv6)
+			}
+			if got := isIPv4Target(tt.target); got != tt.ipv4 {
+				t.Errorf("isIPv4Target = %v, want %v", got, tt.ipv4)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// trackedTask is an OpenVAS task this backend started and still follows.
+type trackedTask struct {
+	TaskID    string    `json:"task_id"`
+	ReportID  string    `json:"report_id,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	// NotifiedAt is set once the completion webhook has been delivered for
+	// this run, so restarts don't notify twice.
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+}
+
+// taskRegistry remembers which OpenVAS tasks were started through this
+// backend. The tasks themselves keep running inside gvmd across restarts, so
+// the registry is written to disk on shutdown and reloaded on startup to let
+// the backend pick up where it left off.
+type taskRegistry struct {
+	mu    sync.Mutex
+	path  string
+	tasks map[string]trackedTask
+}
+
+func newTaskRegistry(path string) *taskRegistry {
+	return &taskRegistry{
+		path:  path,
+		tasks: make(map[string]trackedTask),
+	}
+}
+
+// track records a started task, replacing any previous entry for it. The
+// placeholder task of a dry run is ignored.
+func (r *taskRegistry) track(taskID, reportID string) {
+	if taskID == dryRunID {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[taskID] = trackedTask{
+		TaskID:    taskID,
+		ReportID:  reportID,
+		StartedAt: time.Now().UTC(),
+	}
+}
+
+// markNotified records that the completion webhook for a task was sent.
+func (r *taskRegistry) markNotified(taskID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tasks[taskID]
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	t.NotifiedAt = &now
+	r.tasks[taskID] = t
+}
+
+// untrack forgets a task, e.g. once it has been stopped.
+func (r *taskRegistry) untrack(taskID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tasks, taskID)
+}
+
+// list returns all tracked tasks, oldest first.
+func (r *taskRegistry) list() []trackedTask {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]trackedTask, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, k int) bool {
+		return out[i].StartedAt.Before(out[k].StartedAt)
+	})
+	return out
+}
+
+// load reads previously persisted tasks. A missing file is not an error.
+func (r *taskRegistry) load() error {
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read task registry %s: %w", r.path, err)
+	}
+
+	var tasks []trackedTask
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return fmt.Errorf("failed to parse task registry %s: %w", r.path, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range tasks {
+		if t.TaskID != "" {
+			r.tasks[t.TaskID] = t
+		}
+	}
+	return nil
+}
+
+// save writes all tracked tasks to disk. The file is replaced atomically so
+// a crash mid-write never leaves a truncated registry behind.
+func (r *taskRegistry) save() error {
+	data, err := json.MarshalIndent(r.list(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode task registry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), ".openvas-tasks-*")
+	if err != nil {
+		return fmt.Errorf("failed to write task registry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write task registry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write task registry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write task registry: %w", err)
+	}
+	return nil
+}
+
+type trackedTasksResponse struct {
+	Tasks []trackedTask `json:"tasks"`
+}
+
+// trackedTasksHandler lists the OpenVAS tasks this backend has started.
+func trackedTasksHandler(tasks *taskRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(trackedTasksResponse{Tasks: tasks.list()}); err != nil {
+			log.Printf("failed to encode tracked tasks response: %v", err)
+		}
+	})
+}
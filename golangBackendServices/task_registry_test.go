@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTaskRegistryPersistsAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openvas_tasks.json")
+
+	r := newTaskRegistry(path)
+	r.track("daba56c8-73ec-11df-a475-002264764cea", "11111111-2222-3333-4444-555555555555")
+	r.track("8715c877-47a0-438d-98a3-27c7a6ab2196", "")
+	r.markNotified("8715c877-47a0-438d-98a3-27c7a6ab2196")
+	r.track("d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663", "")
+	r.untrack("d21f6c81-2b88-4ac1-b7b4-a2a9f2ad4663")
+	if err := r.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	reloaded := newTaskRegistry(path)
+	if err := reloaded.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	got := make(map[string]trackedTask)
+	for _, task := range reloaded.list() {
+		got[task.TaskID] = task
+	}
+	if len(got) != 2 {
+		t.Fatalf("reloaded %d tasks, want 2: %+v", len(got), got)
+	}
+	first := got["daba56c8-73ec-11df-a475-002264764cea"]
+	if first.ReportID != "11111111-2222-3333-4444-555555555555" || first.StartedAt.IsZero() {
+		t.Errorf("first task reloaded as %+v", first)
+	}
+	if first.NotifiedAt != nil {
+		t.Error("first task reloaded as notified")
+	}
+	if second := got["8715c877-47a0-438d-98a3-27c7a6ab2196"]; second.NotifiedAt == nil {
+		t.Error("notified_at was not persisted")
+	}
+}
+
+func TestTaskRegistryLoad(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content *string
+		want    int
+		wantErr bool
+	}{
+		{"missing file", nil, 0, false},
+		{"empty list", stringPtr("[]"), 0, false},
+		{"entry without task id", stringPtr(`[{"task_id":""},{"task_id":"daba56c8-73ec-11df-a475-002264764cea"}]`), 1, false},
+		{"corrupt", stringPtr(`[{"task_id":`), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if tt.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := newTaskRegistry(path)
+			err := r.load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load error = %v, want error: %v", err, tt.wantErr)
+			}
+			if n := len(r.list()); n != tt.want {
+				t.Errorf("loaded %d tasks, want %d", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskRegistryIgnoresDryRunTask(t *testing.T) {
+	r := newTaskRegistry(filepath.Join(t.TempDir(), "openvas_tasks.json"))
+	r.track(dryRunID, dryRunID)
+	if n := len(r.list()); n != 0 {
+		t.Fatalf("tracked %d tasks after a dry run, want 0", n)
+	}
+}
+
+func stringPtr(s string) *string { return &s }
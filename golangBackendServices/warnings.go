@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// warnings collects non-fatal issues hit while serving a request: downgraded
+// scan options, skipped targets, unparsable output and so on. It travels in
+// the request context so the scanner and OpenVAS code paths can record
+// problems without threading an extra parameter through every call.
+type warnings struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+type warningsKey struct{}
+
+// withWarnings returns a context carrying a fresh warnings collector.
+func withWarnings(ctx context.Context) (context.Context, *warnings) {
+	w := &warnings{}
+	return contextWithWarnings(ctx, w), w
+}
+
+// contextWithWarnings attaches an existing collector to ctx, e.g. to keep
+// collecting after work moves to a background goroutine.
+func contextWithWarnings(ctx context.Context, w *warnings) context.Context {
+	return context.WithValue(ctx, warningsKey{}, w)
+}
+
+// addWarning records a warning on the collector carried by ctx. It is a
+// no-op when ctx has no collector.
+func addWarning(ctx context.Context, format string, args ...interface{}) {
+	w, ok := ctx.Value(warningsKey{}).(*warnings)
+	if !ok || w == nil {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, existing := range w.msgs {
+		if existing == msg {
+			return
+		}
+	}
+	w.msgs = append(w.msgs, msg)
+}
+
+// list returns a copy of the collected warnings, or nil when there are none
+// so that omitempty keeps responses clean.
+func (w *warnings) list() []string {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.msgs) == 0 {
+		return nil
+	}
+	return append([]string(nil), w.msgs...)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestAddWarning(t *testing.T) {
+	ctx, w := withWarnings(context.Background())
+	if got := w.list(); got != nil {
+		t.Fatalf("fresh collector lists %q, want nil", got)
+	}
+
+	addWarning(ctx, "feed %s is stale", "NVT")
+	addWarning(ctx, "scan could not be saved to history")
+	addWarning(ctx, "feed %s is stale", "NVT")
+
+	want := []string{"feed NVT is stale", "scan could not be saved to history"}
+	got := w.list()
+	if !slices.Equal(got, want) {
+		t.Fatalf("warnings = %q, want %q", got, want)
+	}
+	got[0] = "changed"
+	if w.list()[0] != want[0] {
+		t.Error("list does not return a copy")
+	}
+}
+
+func TestAddWarningWithoutCollector(t *testing.T) {
+	// Must not panic; code paths outside a request have no collector.
+	addWarning(context.Background(), "ignored")
+
+	var w *warnings
+	if got := w.list(); got != nil {
+		t.Errorf("nil collector lists %q, want nil", got)
+	}
+}
+
+func TestAddWarningConcurrent(t *testing.T) {
+	ctx, w := withWarnings(context.Background())
+	detached := contextWithWarnings(context.Background(), w)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); addWarning(ctx, "from the request") }()
+		go func() { defer wg.Done(); addWarning(detached, "from the background") }()
+	}
+	wg.Wait()
+
+	if got := w.list(); len(got) != 2 {
+		t.Errorf("warnings = %q, want one of each", got)
+	}
+}
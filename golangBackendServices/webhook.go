@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// webhookSignatureHeader carries the hex HMAC-SHA256 of the request body,
+// keyed with WEBHOOK_SECRET, in the form "sha256=<hex>".
+const webhookSignatureHeader = "X-Signature-256"
+
+// taskCompletionPayload is POSTed to the webhook when a tracked OpenVAS task
+// finishes.
+type taskCompletionPayload struct {
+	TaskID          string                 `json:"task_id"`
+	ReportID        string                 `json:"report_id"`
+	Status          string                 `json:"status"`
+	SeveritySummary *ReportSeveritySummary `json:"severity_summary,omitempty"`
+}
+
+// webhookNotifier delivers JSON payloads to a single URL, retrying failed
+// deliveries with exponential backoff.
+type webhookNotifier struct {
+	url         string
+	secret      string
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+}
+
+func newWebhookNotifier(url, secret string, maxAttempts int) *webhookNotifier {
+	return &webhookNotifier{
+		url:         url,
+		secret:      secret,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: maxAttempts,
+		backoff:     time.Second,
+	}
+}
+
+// signWebhookPayload returns the value of the signature header for body.
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// send POSTs payload to the webhook. Any non-2xx response or transport error
+// is retried until maxAttempts is reached or ctx is cancelled.
+func (n *webhookNotifier) send(ctx context.Context, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	delay := n.backoff
+	var lastErr error
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		if lastErr = n.post(ctx, body); lastErr == nil {
+			return nil
+		}
+		if attempt == n.maxAttempts {
+			break
+		}
+		log.Printf("webhook delivery attempt %d/%d failed: %v; retrying in %s", attempt, n.maxAttempts, lastErr, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("webhook delivery failed after %d attempt(s): %w", n.maxAttempts, lastErr)
+}
+
+func (n *webhookNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// watchTasks polls the tracked OpenVAS tasks every interval and notifies the
+// webhook once for each task that reaches Done.
+func watchTasks(ctx context.Context, svc *OpenVASService, tasks *taskRegistry, notifier *webhookNotifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, t := range tasks.list() {
+			if t.NotifiedAt != nil {
+				continue
+			}
+			if err := notifyIfDone(ctx, svc, tasks, notifier, t); err != nil {
+				log.Printf("task %s completion notification: %v", t.TaskID, err)
+			}
+		}
+	}
+}
+
+// notifyIfDone checks a single task and delivers its completion webhook if
+// it has finished.
+func notifyIfDone(ctx context.Context, svc *OpenVASService, tasks *taskRegistry, notifier *webhookNotifier, t trackedTask) error {
+	progress, err := svc.GetTaskProgress(ctx, t.TaskID)
+	if err != nil {
+		return err
+	}
+	if progress.Status != "Done" {
+		return nil
+	}
+
+	payload := taskCompletionPayload{
+		TaskID:   t.TaskID,
+		ReportID: progress.ReportID,
+		Status:   progress.Status,
+	}
+	if payload.ReportID == "" {
+		payload.ReportID = t.ReportID
+	}
+	if payload.ReportID != "" {
+		summary, err := svc.SummarizeReport(ctx, payload.ReportID, defaultReportSummaryTop)
+		if err != nil {
+			// Still notify; the receiver can fetch the report itself.
+			log.Printf("failed to summarize report %s for webhook: %v", payload.ReportID, err)
+		} else {
+			payload.SeveritySummary = &summary
+		}
+	}
+
+	if err := notifier.send(ctx, payload); err != nil {
+		return err
+	}
+	tasks.markNotified(t.TaskID)
+	if err := tasks.save(); err != nil {
+		log.Printf("failed to save task registry: %v", err)
+	}
+	return nil
+}